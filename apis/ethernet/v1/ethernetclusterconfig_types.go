@@ -17,23 +17,144 @@ type DeviceSelector struct {
 	PCIAddress string `json:"pciAddress,omitempty"`
 }
 
+type MaintenanceWindow struct {
+	// Cron expression (minute hour day-of-month month day-of-week) of window starts, e.g. "0 22 * * 6"
+	// +kubebuilder:validation:MinLength=9
+	Schedule string `json:"schedule"`
+	// Duration of the window, e.g. "4h"
+	Duration metav1.Duration `json:"duration"`
+	// IANA time zone of the schedule, e.g. "Europe/Warsaw". UTC is used if not set
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
 type DeviceConfig struct {
-	// Path to .zip DDP package to be applied
+	// Path to .zip DDP package to be applied. Either HTTP(S) URL or OCI artifact reference
+	// in oci://registry/repository[:tag][@digest] format
 	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\.\-\/]+
 	DDPURL string `json:"ddpURL,omitempty"`
-	// SHA-1 checksum of .zip DDP package
-	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{40}$`
+	// Checksum of .zip DDP package in <algorithm>:<hex> format, where algorithm is one of sha1, sha256 or sha512.
+	// Checksum without algorithm prefix is treated as SHA-1
+	// +kubebuilder:validation:Pattern=`^((sha1:)?[a-fA-F0-9]{40}|sha256:[a-fA-F0-9]{64}|sha512:[a-fA-F0-9]{128})$`
 	DDPChecksum string `json:"ddpChecksum,omitempty"`
+	// Path to detached signature of .zip DDP package
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\.\-\/]+
+	DDPSignatureURL string `json:"ddpSignatureURL,omitempty"`
+	// How the DDP package is loaded by the device. Reboot (default) reboots the node, DriverReload rebinds
+	// ice driver to physical functions of the adapter without reboot
+	// +kubebuilder:validation:Enum=Reboot;DriverReload
+	DDPApplyStrategy string `json:"ddpApplyStrategy,omitempty"`
+	// Remove DDP package previously applied to the device, so that it loads the default package of the OS.
+	// DDPURL is ignored when set
+	DDPRevert bool `json:"ddpRevert,omitempty"`
 
-	// Path to .tar.gz Firmware (NVMUpdate package) to be applied
+	// Path to .tar.gz Firmware (NVMUpdate package) to be applied, or to raw .bin NVM image with DevlinkFlash
+	// backend. Either HTTP(S) URL or OCI artifact reference in oci://registry/repository[:tag][@digest] format
 	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\.\-\/]+
 	FWURL string `json:"fwURL,omitempty"`
-	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{40}$`
-	// SHA-1 checksum of .tar.gz Firmware
+	// +kubebuilder:validation:Pattern=`^((sha1:)?[a-fA-F0-9]{40}|sha256:[a-fA-F0-9]{64}|sha512:[a-fA-F0-9]{128})$`
+	// Checksum of .tar.gz Firmware in <algorithm>:<hex> format, where algorithm is one of sha1, sha256 or sha512.
+	// Checksum without algorithm prefix is treated as SHA-1
 	FWChecksum string `json:"fwChecksum,omitempty"`
+	// Path to detached signature of .tar.gz Firmware
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\.\-\/]+
+	FWSignatureURL string `json:"fwSignatureURL,omitempty"`
 	// Additional arguments for NVMUpdate utility 
 	// e.g. "./nvmupdate64e -u -m 40a6b79ee660 -c ./nvmupdate.cfg -o update.xml -l <fwUpdateParam>"
 	FWUpdateParam string `json:"fwUpdateParam,omitempty"`
+	// How the firmware is applied. NVMUpdate (default) runs NVMUpdate utility from the package, DevlinkFlash
+	// flashes the NVM image with devlink, which requires the driver of the device to support flash update
+	// +kubebuilder:validation:Enum=NVMUpdate;DevlinkFlash
+	FWUpdateBackend string `json:"fwUpdateBackend,omitempty"`
+	// Component of the device flashed by DevlinkFlash backend, e.g. fw.mgmt or fw.undi.
+	// Whole NVM image is flashed if not set
+	FWFlashComponent string `json:"fwFlashComponent,omitempty"`
+	// Sections of the device NVM which DevlinkFlash backend overwrites with contents of the image instead of
+	// preserving them. Settings are device configuration, Identifiers are MAC addresses and serial numbers.
+	// Drivers may require Settings to be overwritten together with Identifiers
+	// +kubebuilder:validation:items:Enum=Settings;Identifiers
+	FWFlashOverwrite []string `json:"fwFlashOverwrite,omitempty"`
+	// Name of Secret containing public key used to verify detached signatures of FW and DDP packages.
+	// The key is read from "publicKey" field and can be either OpenPGP key or PEM encoded ECDSA/RSA key
+	SignatureKeySecret string `json:"signatureKeySecret,omitempty"`
+	// Name of kubernetes.io/dockerconfigjson Secret used to authenticate to registry when FW or DDP package
+	// is pulled as OCI artifact
+	PullSecret string `json:"pullSecret,omitempty"`
+	// Apply the update even if resulting combination of driver, firmware and DDP versions
+	// is not listed in the compatibility map of supported devices
+	SkipCompatibilityCheck bool `json:"skipCompatibilityCheck,omitempty"`
+	// Leave the node cordoned if firmware or DDP version reported by the device after the post-update reboot
+	// does not match the version which was applied
+	KeepCordonedOnVersionMismatch bool `json:"keepCordonedOnVersionMismatch,omitempty"`
+	// Devlink parameters of the device, e.g. enable_roce, applied and verified by the daemon
+	DevlinkParams []DevlinkParam `json:"devlinkParams,omitempty"`
+	// Ethtool settings of network interfaces of the device, applied without node drain and re-applied
+	// whenever the daemon observes that they differ, e.g. after driver reload or node reboot
+	PortSettings *PortSettings `json:"portSettings,omitempty"`
+}
+
+// DevlinkParam is a value of devlink parameter of the device in the given configuration mode,
+// as set by devlink dev param set
+type DevlinkParam struct {
+	// Name of the parameter, e.g. enable_roce or msix_vec_per_pf_max
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Value of the parameter, true or false for boolean parameters
+	Value string `json:"value"`
+	// Configuration mode of the value. Runtime values are applied right away without node drain, driverinit
+	// values after devlink reload of the device and permanent values are stored in the NVM and applied
+	// after node reboot
+	// +kubebuilder:validation:Enum=runtime;driverinit;permanent
+	CMode string `json:"cmode"`
+}
+
+// PortSettings are ethtool settings of network interfaces of the device. Settings which are not set
+// are left as configured by the driver
+type PortSettings struct {
+	// Size of RX ring of each queue, as set by ethtool -G rx
+	// +kubebuilder:validation:Minimum=1
+	RxRingSize *int `json:"rxRingSize,omitempty"`
+	// Size of TX ring of each queue, as set by ethtool -G tx
+	// +kubebuilder:validation:Minimum=1
+	TxRingSize *int `json:"txRingSize,omitempty"`
+	// Number of combined RX/TX channels, as set by ethtool -L combined
+	// +kubebuilder:validation:Minimum=1
+	CombinedChannels *int `json:"combinedChannels,omitempty"`
+	// Interrupt coalescing, as set by ethtool -C
+	Coalescing *CoalescingSettings `json:"coalescing,omitempty"`
+	// Forward error correction mode, as set by ethtool --set-fec encoding
+	// +kubebuilder:validation:Enum=Auto;Off;RS;BaseR
+	FECMode string `json:"fecMode,omitempty"`
+	// Link autonegotiation, as set by ethtool -s autoneg
+	Autoneg *bool `json:"autoneg,omitempty"`
+	// Link speed in Mb/s forced with full duplex, as set by ethtool -s speed. Requires autoneg to be disabled
+	// +kubebuilder:validation:Minimum=1
+	Speed *int `json:"speed,omitempty"`
+	// Pause frames, as set by ethtool -A
+	Pause *PauseSettings `json:"pause,omitempty"`
+	// Driver private flags by name, e.g. fw-lldp-agent of ice driver, as set by ethtool --set-priv-flags
+	PrivateFlags map[string]bool `json:"privateFlags,omitempty"`
+}
+
+type CoalescingSettings struct {
+	// Adaptive RX interrupt moderation
+	AdaptiveRx *bool `json:"adaptiveRx,omitempty"`
+	// Adaptive TX interrupt moderation
+	AdaptiveTx *bool `json:"adaptiveTx,omitempty"`
+	// Microseconds to delay RX interrupt after a packet arrives
+	// +kubebuilder:validation:Minimum=0
+	RxUsecs *int `json:"rxUsecs,omitempty"`
+	// Microseconds to delay TX interrupt after a packet is sent
+	// +kubebuilder:validation:Minimum=0
+	TxUsecs *int `json:"txUsecs,omitempty"`
+}
+
+type PauseSettings struct {
+	// Autonegotiation of pause frames
+	Autoneg *bool `json:"autoneg,omitempty"`
+	// Pause RX
+	Rx *bool `json:"rx,omitempty"`
+	// Pause TX
+	Tx *bool `json:"tx,omitempty"`
 }
 
 // EthernetClusterConfigSpec defines the desired state of EthernetClusterConfig
@@ -51,10 +172,47 @@ type EthernetClusterConfigSpec struct {
 	// Higher priority policies can override lower ones.
 	//If several ClusterConfigs have same Priority, then operator will apply ClusterConfig with highest CreationTimestamp (newest one)
 	Priority int `json:"priority,omitempty"`
+
+	// When true, selected devices are not updated. Instead the plan of the update is computed on the nodes
+	// and published in the status
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// When true, DDP package applied to selected devices is reverted to the default one once this config is deleted.
+	// Deletion of the config is blocked by a finalizer until the revert succeeds
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	RevertDDPOnDelete bool `json:"revertDDPOnDelete,omitempty"`
+
+	// When set, changes of the configuration are propagated to the nodes, and devices are updated,
+	// only inside the maintenance window
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// How the node is rebooted to complete the update. Immediate (default) reboots the node right away,
+	// Deferred waits for approval annotation on EthernetNodeConfig, External waits until the node is rebooted
+	// by external orchestrator
+	// +kubebuilder:validation:Enum=Immediate;Deferred;External
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	RebootStrategy string `json:"rebootStrategy,omitempty"`
+}
+
+type MaintenanceStatus struct {
+	// Start of the next maintenance window
+	NextWindowStart *metav1.Time `json:"nextWindowStart,omitempty"`
+	// Nodes with changes of the configuration deferred until the maintenance window
+	DeferredNodes []string `json:"deferredNodes,omitempty"`
+	// Error in the definition of the maintenance window, changes are deferred until it is fixed
+	Error string `json:"error,omitempty"`
 }
 
 // EthernetClusterConfigStatus defines the observed state of EthernetClusterConfig
 type EthernetClusterConfigStatus struct {
+	// Update plan of selected nodes and devices, set only if DryRun is enabled
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	Plan []NodeUpdatePlan `json:"plan,omitempty"`
+	// State of the maintenance window, set only if MaintenanceWindow is configured
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
 }
 
 //+kubebuilder:object:root=true
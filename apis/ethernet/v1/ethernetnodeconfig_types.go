@@ -13,6 +13,14 @@ type DeviceNodeConfig struct {
 	PCIAddress string `json:"PCIAddress"`
 	// Configuration which will be applied to this device
 	DeviceConfig DeviceConfig `json:"deviceConfig"`
+	// Compute update plan for this device instead of updating it
+	DryRun bool `json:"dryRun,omitempty"`
+	// Window in which the device can be updated, the update is deferred if node drain and reboot
+	// cannot be completed before the window closes
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// How the node is rebooted to complete the update of this device
+	// +kubebuilder:validation:Enum=Immediate;Deferred;External
+	RebootStrategy string `json:"rebootStrategy,omitempty"`
 }
 
 // EthernetNodeConfigSpec defines the desired state of EthernetNodeConfig
@@ -28,6 +36,12 @@ type EthernetNodeConfigSpec struct {
 type FirmwareInfo struct {
 	MAC     string `json:"MAC"`
 	Version string `json:"version"`
+	// Running version of the management firmware (devlink fw.mgmt)
+	MgmtVersion string `json:"mgmtVersion,omitempty"`
+	// Running version of the UNDI/Option ROM (devlink fw.undi)
+	UNDIVersion string `json:"undiVersion,omitempty"`
+	// Running version of the netlist (devlink fw.netlist)
+	NetlistVersion string `json:"netlistVersion,omitempty"`
 }
 
 type DDPInfo struct {
@@ -53,6 +67,203 @@ type Device struct {
 	Firmware FirmwareInfo `json:"firmware"`
 	// DDPInfo contains information about loaded DDP profile
 	DDP DDPInfo `json:"DDP"`
+	// Network interfaces of the device, empty if the device is not bound to a kernel network driver
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+	// NUMA node the device is attached to, not set if the platform doesn't report it
+	NUMANode *int `json:"numaNode,omitempty"`
+	// PCIe link of the device
+	PCIeLink *PCIeLinkInfo `json:"pcieLink,omitempty"`
+	// SR-IOV capabilities of the device, not set if the device doesn't support SR-IOV
+	SRIOV *SRIOVInfo `json:"sriov,omitempty"`
+	// Serial number of the board
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// True if the device is bound to a driver used by DPDK (vfio-pci, igb_uio or uio_pci_generic)
+	DPDKBound bool `json:"dpdkBound,omitempty"`
+	// Values of devlink parameters of the device in each configuration mode supported by the parameter
+	DevlinkParams []DevlinkParam `json:"devlinkParams,omitempty"`
+}
+
+type NetworkInterface struct {
+	// Name of the network interface
+	Name string `json:"name"`
+	// Operational state of the link, e.g. up or down
+	LinkState string `json:"linkState,omitempty"`
+	// Speed of the link in Mb/s, not set if the link is down
+	Speed int `json:"speed,omitempty"`
+}
+
+type PCIeLinkInfo struct {
+	// Negotiated link speed, e.g. 16.0 GT/s PCIe
+	Speed string `json:"speed,omitempty"`
+	// Negotiated link width (number of lanes)
+	Width int `json:"width,omitempty"`
+	// Maximum link speed supported by the device
+	MaxSpeed string `json:"maxSpeed,omitempty"`
+	// Maximum link width supported by the device
+	MaxWidth int `json:"maxWidth,omitempty"`
+}
+
+type SRIOVInfo struct {
+	// Maximum number of VFs supported by the device
+	TotalVFs int `json:"totalVFs"`
+	// Number of VFs currently enabled
+	NumVFs int `json:"numVFs"`
+}
+
+type FirmwareRollback struct {
+	// PciAddress of device
+	PCIAddress string `json:"PCIAddress"`
+	// Outcome of restoring the NVM image saved before the failed firmware update
+	// +kubebuilder:validation:Enum=Succeeded;Failed;Unavailable
+	Result string `json:"result"`
+	// Contains details about the firmware update failure and the rollback
+	Message string `json:"message,omitempty"`
+	// Time when the rollback finished
+	Time metav1.Time `json:"time"`
+}
+
+type FlashedImage struct {
+	// PciAddress of device
+	PCIAddress string `json:"PCIAddress"`
+	// SHA-256 checksum of the raw NVM image flashed with devlink flash, in sha256:<hex> format
+	Checksum string `json:"checksum"`
+	// Firmware version (EETrack ID) which the device reported as stored in its NVM after the flash
+	Version string `json:"version"`
+	// Time when the flash finished
+	Time metav1.Time `json:"time"`
+}
+
+type DeviceUpdateStatus struct {
+	// PciAddress of device
+	PCIAddress string `json:"PCIAddress"`
+	// Current phase of the device update, UpToDate if the device already ran requested versions
+	// +kubebuilder:validation:Enum=Pending;Downloading;Extracting;Flashing;CopyingDDP;RevertingDDP;ReloadingDriver;SettingParams;Rebooting;Succeeded;UpToDate;Failed
+	Phase string `json:"phase"`
+	// Firmware version (EETrack ID) provided by the requested NVM Update package
+	TargetFirmwareVersion string `json:"targetFirmwareVersion,omitempty"`
+	// Firmware version currently reported by the device
+	ObservedFirmwareVersion string `json:"observedFirmwareVersion,omitempty"`
+	// DDP profile version provided by the requested DDP package
+	TargetDDPVersion string `json:"targetDDPVersion,omitempty"`
+	// DDP profile version currently loaded by the device
+	ObservedDDPVersion string `json:"observedDDPVersion,omitempty"`
+	// Error which caused the last update of the device to fail
+	LastError string `json:"lastError,omitempty"`
+	// Result of the last firmware update reported by NVM Update utility
+	FirmwareUpdate *FirmwareUpdateSummary `json:"firmwareUpdate,omitempty"`
+	// Logs of the last update of the device
+	Logs *OperationLogReference `json:"logs,omitempty"`
+	// Time when the last update of the device started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Time when the device entered current phase
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Time when the last update of the device succeeded or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+type FirmwareModuleResult struct {
+	// Type of the module, e.g. NVM, Netlist, PXE or EFI
+	Type string `json:"type"`
+	// Version of the module before the update
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	// Version of the module provided by the package
+	Version string `json:"version,omitempty"`
+	// Result of the module update, e.g. Success
+	Result string `json:"result,omitempty"`
+	// Status code of the module update, 0 on success
+	StatusID int `json:"statusID"`
+	// Status message of the module update
+	Message string `json:"message,omitempty"`
+}
+
+type FirmwareUpdateSummary struct {
+	// Exit code of NVM Update utility
+	ExitCode int `json:"exitCode"`
+	// Human readable description of the exit code
+	ExitReason string `json:"exitReason,omitempty"`
+	// Results of updated modules
+	Modules []FirmwareModuleResult `json:"modules,omitempty"`
+	// True if NVM Update utility requested reboot to finish the update
+	RebootRequired bool `json:"rebootRequired,omitempty"`
+	// True if NVM Update utility requested power cycle to finish the update
+	PowerCycleRequired bool `json:"powerCycleRequired,omitempty"`
+}
+
+type OperationLogReference struct {
+	// Storage of the logs
+	// +kubebuilder:validation:Enum=ConfigMap;Secret;S3
+	Storage string `json:"storage"`
+	// Name of ConfigMap or Secret in the namespace of the daemon, or URL of S3 prefix the logs were uploaded under
+	Location string `json:"location"`
+	// Keys of the logs in ConfigMap or Secret, names of objects under S3 prefix
+	Entries []string `json:"entries,omitempty"`
+	// True if any log was truncated to the size limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type DeviceUpdatePlan struct {
+	// PciAddress of device
+	PCIAddress string `json:"PCIAddress"`
+	// True if NVM Update package would flash the device
+	FirmwareUpdate bool `json:"firmwareUpdate"`
+	// Firmware version currently reported by the device
+	CurrentFirmwareVersion string `json:"currentFirmwareVersion,omitempty"`
+	// Firmware version (EETrack ID) provided by the requested NVM Update package
+	TargetFirmwareVersion string `json:"targetFirmwareVersion,omitempty"`
+	// True if DDP package would be copied for the device
+	DDPUpdate bool `json:"ddpUpdate"`
+	// DDP profile version currently loaded by the device
+	CurrentDDPVersion string `json:"currentDDPVersion,omitempty"`
+	// DDP profile version provided by the requested DDP package
+	TargetDDPVersion string `json:"targetDDPVersion,omitempty"`
+	// True if DDP package applied to the device would be removed to restore the default one
+	DDPRevert bool `json:"ddpRevert,omitempty"`
+	// Devlink parameters which would be changed to the requested values
+	DevlinkParams []DevlinkParam `json:"devlinkParams,omitempty"`
+	// True if node reboot would be needed to complete the update of the device
+	RebootRequired bool `json:"rebootRequired"`
+	// Error which prevented computing the plan, e.g. failed package download
+	Error string `json:"error,omitempty"`
+}
+
+type NodeUpdatePlan struct {
+	// Name of the node, set in EthernetClusterConfig status only
+	NodeName string `json:"nodeName,omitempty"`
+	// Plans of devices configured with dry run
+	Devices []DeviceUpdatePlan `json:"devices,omitempty"`
+	// True if node would be drained before the update
+	DrainRequired bool `json:"drainRequired"`
+	// True if node would be rebooted after the update
+	RebootRequired bool `json:"rebootRequired"`
+	// False until plans of all devices are computed for the current configuration
+	Complete bool `json:"complete"`
+	// Generation of EthernetNodeConfig the plan was computed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+type RebootStatus struct {
+	// Reboot strategy applied to complete the last update
+	Strategy string `json:"strategy"`
+	// True while the update waits for the node reboot
+	Pending bool `json:"pending"`
+	// Time when the update was staged and reboot became required
+	RequiredSince *metav1.Time `json:"requiredSince,omitempty"`
+	// Boot ID of the node when the update was staged, used to detect the reboot
+	BootID string `json:"bootID,omitempty"`
+}
+
+type PortSettingsStatus struct {
+	// PciAddress of device
+	PCIAddress string `json:"PCIAddress"`
+	// Network interface of the device, not set if the device has no network interface
+	Interface string `json:"interface,omitempty"`
+	// Requested settings which differ from ones observed on the interface after they were applied,
+	// e.g. "rxRingSize: requested 4096, observed 2048"
+	Drift []string `json:"drift,omitempty"`
+	// Time when settings were last applied to the interface
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+	// Error which prevented observing or applying the settings
+	Error string `json:"error,omitempty"`
 }
 
 // EthernetNodeConfigStatus defines the observed state of EthernetNodeConfig
@@ -63,6 +274,24 @@ type EthernetNodeConfigStatus struct {
 	// Contains list of supported CLV cards and details about them
 	//+operator-sdk:csv:customresourcedefinitions:type=status
 	Devices []Device `json:"devices,omitempty"`
+	// Contains outcome of the last firmware rollback performed for each device
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	FirmwareRollbacks []FirmwareRollback `json:"firmwareRollbacks,omitempty"`
+	// Contains the last raw NVM image flashed to each device, so the image is not flashed again
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	FlashedImages []FlashedImage `json:"flashedImages,omitempty"`
+	// Contains update status of each configured device
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	DeviceUpdates []DeviceUpdateStatus `json:"deviceUpdates,omitempty"`
+	// Contains update plan of devices configured with dry run
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	UpdatePlan *NodeUpdatePlan `json:"updatePlan,omitempty"`
+	// Contains state of the node reboot completing the last update
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	Reboot *RebootStatus `json:"reboot,omitempty"`
+	// Contains state of port settings of each network interface of configured devices
+	//+operator-sdk:csv:customresourcedefinitions:type=status
+	PortSettings []PortSettingsStatus `json:"portSettings,omitempty"`
 }
 
 //+kubebuilder:object:root=true
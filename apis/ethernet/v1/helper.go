@@ -15,3 +15,27 @@ func (ds DeviceSelector) Matches(d Device) bool {
 	}
 	return true
 }
+
+// SetRequirements derives node drain and reboot from plans of the devices. Drain is performed
+// before any update unless it is skipped for the node
+func (p *NodeUpdatePlan) SetRequirements(drainSkip bool) {
+	p.DrainRequired, p.RebootRequired = false, false
+	for _, d := range p.Devices {
+		if d.FirmwareUpdate || d.DDPUpdate || d.DDPRevert {
+			p.DrainRequired = !drainSkip
+		}
+		for _, param := range d.DevlinkParams {
+			if !param.IsRuntime() {
+				p.DrainRequired = !drainSkip
+			}
+		}
+		if d.RebootRequired {
+			p.RebootRequired = true
+		}
+	}
+}
+
+// IsRuntime is true for values applied by the driver right away, without devlink reload or node reboot
+func (p DevlinkParam) IsRuntime() bool {
+	return p.CMode == "runtime"
+}
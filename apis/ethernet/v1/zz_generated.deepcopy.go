@@ -13,6 +13,41 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoalescingSettings) DeepCopyInto(out *CoalescingSettings) {
+	*out = *in
+	if in.AdaptiveRx != nil {
+		in, out := &in.AdaptiveRx, &out.AdaptiveRx
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AdaptiveTx != nil {
+		in, out := &in.AdaptiveTx, &out.AdaptiveTx
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RxUsecs != nil {
+		in, out := &in.RxUsecs, &out.RxUsecs
+		*out = new(int)
+		**out = **in
+	}
+	if in.TxUsecs != nil {
+		in, out := &in.TxUsecs, &out.TxUsecs
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoalescingSettings.
+func (in *CoalescingSettings) DeepCopy() *CoalescingSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(CoalescingSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DDPInfo) DeepCopyInto(out *DDPInfo) {
 	*out = *in
@@ -33,6 +68,31 @@ func (in *Device) DeepCopyInto(out *Device) {
 	*out = *in
 	out.Firmware = in.Firmware
 	out.DDP = in.DDP
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]NetworkInterface, len(*in))
+		copy(*out, *in)
+	}
+	if in.NUMANode != nil {
+		in, out := &in.NUMANode, &out.NUMANode
+		*out = new(int)
+		**out = **in
+	}
+	if in.PCIeLink != nil {
+		in, out := &in.PCIeLink, &out.PCIeLink
+		*out = new(PCIeLinkInfo)
+		**out = **in
+	}
+	if in.SRIOV != nil {
+		in, out := &in.SRIOV, &out.SRIOV
+		*out = new(SRIOVInfo)
+		**out = **in
+	}
+	if in.DevlinkParams != nil {
+		in, out := &in.DevlinkParams, &out.DevlinkParams
+		*out = make([]DevlinkParam, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Device.
@@ -48,6 +108,21 @@ func (in *Device) DeepCopy() *Device {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceConfig) DeepCopyInto(out *DeviceConfig) {
 	*out = *in
+	if in.FWFlashOverwrite != nil {
+		in, out := &in.FWFlashOverwrite, &out.FWFlashOverwrite
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DevlinkParams != nil {
+		in, out := &in.DevlinkParams, &out.DevlinkParams
+		*out = make([]DevlinkParam, len(*in))
+		copy(*out, *in)
+	}
+	if in.PortSettings != nil {
+		in, out := &in.PortSettings, &out.PortSettings
+		*out = new(PortSettings)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfig.
@@ -63,7 +138,12 @@ func (in *DeviceConfig) DeepCopy() *DeviceConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceNodeConfig) DeepCopyInto(out *DeviceNodeConfig) {
 	*out = *in
-	out.DeviceConfig = in.DeviceConfig
+	in.DeviceConfig.DeepCopyInto(&out.DeviceConfig)
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceNodeConfig.
@@ -91,13 +171,82 @@ func (in *DeviceSelector) DeepCopy() *DeviceSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceUpdatePlan) DeepCopyInto(out *DeviceUpdatePlan) {
+	*out = *in
+	if in.DevlinkParams != nil {
+		in, out := &in.DevlinkParams, &out.DevlinkParams
+		*out = make([]DevlinkParam, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceUpdatePlan.
+func (in *DeviceUpdatePlan) DeepCopy() *DeviceUpdatePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceUpdatePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceUpdateStatus) DeepCopyInto(out *DeviceUpdateStatus) {
+	*out = *in
+	if in.FirmwareUpdate != nil {
+		in, out := &in.FirmwareUpdate, &out.FirmwareUpdate
+		*out = new(FirmwareUpdateSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Logs != nil {
+		in, out := &in.Logs, &out.Logs
+		*out = new(OperationLogReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceUpdateStatus.
+func (in *DeviceUpdateStatus) DeepCopy() *DeviceUpdateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceUpdateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevlinkParam) DeepCopyInto(out *DevlinkParam) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevlinkParam.
+func (in *DevlinkParam) DeepCopy() *DevlinkParam {
+	if in == nil {
+		return nil
+	}
+	out := new(DevlinkParam)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EthernetClusterConfig) DeepCopyInto(out *EthernetClusterConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EthernetClusterConfig.
@@ -161,7 +310,12 @@ func (in *EthernetClusterConfigSpec) DeepCopyInto(out *EthernetClusterConfigSpec
 		}
 	}
 	out.DeviceSelector = in.DeviceSelector
-	out.DeviceConfig = in.DeviceConfig
+	in.DeviceConfig.DeepCopyInto(&out.DeviceConfig)
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EthernetClusterConfigSpec.
@@ -177,6 +331,18 @@ func (in *EthernetClusterConfigSpec) DeepCopy() *EthernetClusterConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EthernetClusterConfigStatus) DeepCopyInto(out *EthernetClusterConfigStatus) {
 	*out = *in
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = make([]NodeUpdatePlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EthernetClusterConfigStatus.
@@ -254,7 +420,9 @@ func (in *EthernetNodeConfigSpec) DeepCopyInto(out *EthernetNodeConfigSpec) {
 	if in.Config != nil {
 		in, out := &in.Config, &out.Config
 		*out = make([]DeviceNodeConfig, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -281,7 +449,47 @@ func (in *EthernetNodeConfigStatus) DeepCopyInto(out *EthernetNodeConfigStatus)
 	if in.Devices != nil {
 		in, out := &in.Devices, &out.Devices
 		*out = make([]Device, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FirmwareRollbacks != nil {
+		in, out := &in.FirmwareRollbacks, &out.FirmwareRollbacks
+		*out = make([]FirmwareRollback, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FlashedImages != nil {
+		in, out := &in.FlashedImages, &out.FlashedImages
+		*out = make([]FlashedImage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeviceUpdates != nil {
+		in, out := &in.DeviceUpdates, &out.DeviceUpdates
+		*out = make([]DeviceUpdateStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UpdatePlan != nil {
+		in, out := &in.UpdatePlan, &out.UpdatePlan
+		*out = new(NodeUpdatePlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reboot != nil {
+		in, out := &in.Reboot, &out.Reboot
+		*out = new(RebootStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PortSettings != nil {
+		in, out := &in.PortSettings, &out.PortSettings
+		*out = make([]PortSettingsStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -309,3 +517,327 @@ func (in *FirmwareInfo) DeepCopy() *FirmwareInfo {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareModuleResult) DeepCopyInto(out *FirmwareModuleResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareModuleResult.
+func (in *FirmwareModuleResult) DeepCopy() *FirmwareModuleResult {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareModuleResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareRollback) DeepCopyInto(out *FirmwareRollback) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareRollback.
+func (in *FirmwareRollback) DeepCopy() *FirmwareRollback {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareRollback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareUpdateSummary) DeepCopyInto(out *FirmwareUpdateSummary) {
+	*out = *in
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]FirmwareModuleResult, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareUpdateSummary.
+func (in *FirmwareUpdateSummary) DeepCopy() *FirmwareUpdateSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareUpdateSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlashedImage) DeepCopyInto(out *FlashedImage) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlashedImage.
+func (in *FlashedImage) DeepCopy() *FlashedImage {
+	if in == nil {
+		return nil
+	}
+	out := new(FlashedImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceStatus) DeepCopyInto(out *MaintenanceStatus) {
+	*out = *in
+	if in.NextWindowStart != nil {
+		in, out := &in.NextWindowStart, &out.NextWindowStart
+		*out = (*in).DeepCopy()
+	}
+	if in.DeferredNodes != nil {
+		in, out := &in.DeferredNodes, &out.DeferredNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceStatus.
+func (in *MaintenanceStatus) DeepCopy() *MaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkInterface) DeepCopyInto(out *NetworkInterface) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterface.
+func (in *NetworkInterface) DeepCopy() *NetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeUpdatePlan) DeepCopyInto(out *NodeUpdatePlan) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]DeviceUpdatePlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeUpdatePlan.
+func (in *NodeUpdatePlan) DeepCopy() *NodeUpdatePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeUpdatePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationLogReference) DeepCopyInto(out *OperationLogReference) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationLogReference.
+func (in *OperationLogReference) DeepCopy() *OperationLogReference {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationLogReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PCIeLinkInfo) DeepCopyInto(out *PCIeLinkInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PCIeLinkInfo.
+func (in *PCIeLinkInfo) DeepCopy() *PCIeLinkInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(PCIeLinkInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PauseSettings) DeepCopyInto(out *PauseSettings) {
+	*out = *in
+	if in.Autoneg != nil {
+		in, out := &in.Autoneg, &out.Autoneg
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Rx != nil {
+		in, out := &in.Rx, &out.Rx
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Tx != nil {
+		in, out := &in.Tx, &out.Tx
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PauseSettings.
+func (in *PauseSettings) DeepCopy() *PauseSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(PauseSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortSettings) DeepCopyInto(out *PortSettings) {
+	*out = *in
+	if in.RxRingSize != nil {
+		in, out := &in.RxRingSize, &out.RxRingSize
+		*out = new(int)
+		**out = **in
+	}
+	if in.TxRingSize != nil {
+		in, out := &in.TxRingSize, &out.TxRingSize
+		*out = new(int)
+		**out = **in
+	}
+	if in.CombinedChannels != nil {
+		in, out := &in.CombinedChannels, &out.CombinedChannels
+		*out = new(int)
+		**out = **in
+	}
+	if in.Coalescing != nil {
+		in, out := &in.Coalescing, &out.Coalescing
+		*out = new(CoalescingSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoneg != nil {
+		in, out := &in.Autoneg, &out.Autoneg
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Speed != nil {
+		in, out := &in.Speed, &out.Speed
+		*out = new(int)
+		**out = **in
+	}
+	if in.Pause != nil {
+		in, out := &in.Pause, &out.Pause
+		*out = new(PauseSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrivateFlags != nil {
+		in, out := &in.PrivateFlags, &out.PrivateFlags
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortSettings.
+func (in *PortSettings) DeepCopy() *PortSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(PortSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortSettingsStatus) DeepCopyInto(out *PortSettingsStatus) {
+	*out = *in
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortSettingsStatus.
+func (in *PortSettingsStatus) DeepCopy() *PortSettingsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PortSettingsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebootStatus) DeepCopyInto(out *RebootStatus) {
+	*out = *in
+	if in.RequiredSince != nil {
+		in, out := &in.RequiredSince, &out.RequiredSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebootStatus.
+func (in *RebootStatus) DeepCopy() *RebootStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RebootStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SRIOVInfo) DeepCopyInto(out *SRIOVInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SRIOVInfo.
+func (in *SRIOVInfo) DeepCopy() *SRIOVInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(SRIOVInfo)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -33,6 +33,8 @@ func init() {
 }
 
 func main() {
+	var metricsAddr string
+	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to.")
 	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
@@ -66,7 +68,7 @@ func main() {
 
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme:             scheme,
-		MetricsBindAddress: "0",
+		MetricsBindAddress: metricsAddr,
 		LeaderElection:     false,
 		Namespace:          ns,
 	})
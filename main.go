@@ -56,7 +56,7 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
-	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", "127.0.0.1:8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
@@ -78,6 +78,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	// storage of operation logs is also used in daemon assets
+	if err := utils.SetOsEnvIfNotSet(fwddp_manager.OperationLogStorageEnvVarName, "ConfigMap", setupLog); err != nil {
+		setupLog.Error(err, "unable to set operation log storage")
+		os.Exit(1)
+	}
+	// expected update duration is also used in daemon assets
+	if err := utils.SetOsEnvIfNotSet(fwddp_manager.MaintenanceUpdateDurationEnvVarName, "3600", setupLog); err != nil {
+		setupLog.Error(err, "unable to set maintenance update duration")
+		os.Exit(1)
+	}
+
 	runningInK8s := isRunningInPod()
 	var adHocClient client.Client
 	if adHocClient, err = client.New(restConfig, client.Options{Scheme: scheme}); err != nil {
@@ -6,14 +6,13 @@ package drainhelper
 import (
 	"context"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection"
@@ -26,8 +25,22 @@ const (
 	drainHelperTimeoutDefault    = int64(90)
 	leaseDurationEnvVarName      = "LEASE_DURATION_SECONDS"
 	leaseDurationDefault         = int64(600)
+
+	// DrainHeldAnnotation is set on the node cordoned and drained by Run until it is uncordoned by Run or Uncordon.
+	// Node left drained after Run, e.g. until reboot completing the update, holds the drain and other nodes are
+	// not drained while it is cordoned
+	DrainHeldAnnotation = "ethernet.intel.com/drain-held"
 )
 
+// DrainHeldError is returned by Run if the node was not drained, because other node holds the drain
+type DrainHeldError struct {
+	Node string
+}
+
+func (e *DrainHeldError) Error() string {
+	return fmt.Sprintf("node %v is drained until its update completes, drain postponed", e.Node)
+}
+
 type DrainHelper struct {
 	log       logr.Logger
 	clientSet *clientset.Clientset
@@ -38,29 +51,11 @@ type DrainHelper struct {
 	leaderElectionConfig leaderelection.LeaderElectionConfig
 }
 
-func getOsVarOrUseDefault(log logr.Logger, varName string, defVal int64) int64 {
-	retValStr := os.Getenv(varName)
-
-	if retValStr == "" {
-		log.Info("env variable not found - using default value", "variable", varName, "default", defVal)
-		return defVal
-	}
-
-	val, err := strconv.ParseInt(retValStr, 10, 64)
-
-	if err != nil {
-		log.Error(err, "failed to parse env variable to int64 - using default value", "variable", varName, "value", retValStr, "default", defVal)
-		return defVal
-	}
-
-	return val
-}
-
 func NewDrainHelper(log logr.Logger, cs *clientset.Clientset, nodeName, namespace string) *DrainHelper {
-	drainTimeout := getOsVarOrUseDefault(log, drainHelperTimeoutEnvVarName, drainHelperTimeoutDefault)
+	drainTimeout := utils.GetOsVarOrUseDefault(log, drainHelperTimeoutEnvVarName, drainHelperTimeoutDefault)
 	log.Info("drain settings", "timeout seconds", drainTimeout)
 
-	leaseDur := getOsVarOrUseDefault(log, leaseDurationEnvVarName, leaseDurationDefault)
+	leaseDur := utils.GetOsVarOrUseDefault(log, leaseDurationEnvVarName, leaseDurationDefault)
 	log.Info("lease settings", "duration seconds", leaseDur)
 
 	lock := &resourcelock.LeaseLock{
@@ -114,7 +109,9 @@ func NewDrainHelper(log logr.Logger, cs *clientset.Clientset, nodeName, namespac
 // f is a function that takes a context and returns a bool.
 // It should return true if uncordon should be performed(Only applicable if drain is set to true).
 // If `f` returns false, the uncordon does not take place. This is useful in 2-step scenario like fwddp-daemon where
-// reboot must be performed without loosing the leadership and without the uncordon.
+// reboot must be performed without loosing the leadership and without the uncordon. The lease is released once Run
+// returns, but the node keeps holding the drain until Uncordon is called, so other nodes are not drained meanwhile.
+// If other node holds the drain, f is not run and DrainHeldError is returned.
 func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 	defer func() {
 		// Following mitigation is needed because of the bug in the leader election's release functionality
@@ -165,6 +162,12 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 			}
 
 			if drain {
+				if err := dh.checkDrainHeld(ctx); err != nil {
+					dh.log.Info("drain postponed", "reason", err.Error())
+					innerErr = err
+					return
+				}
+
 				dh.log.Info("cordoning & draining node")
 				if err := dh.cordonAndDrain(ctx); err != nil {
 					dh.log.Error(err, "cordonAndDrain failed")
@@ -206,6 +209,35 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 	return innerErr
 }
 
+// checkDrainHeld returns DrainHeldError if other node holds the drain and is still cordoned
+func (dh *DrainHelper) checkDrainHeld(ctx context.Context) error {
+	nodes, err := dh.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		dh.log.Error(err, "failed to list nodes")
+		return err
+	}
+	for _, node := range nodes.Items {
+		if _, held := node.Annotations[DrainHeldAnnotation]; held && node.Name != dh.nodeName && node.Spec.Unschedulable {
+			return &DrainHeldError{Node: node.Name}
+		}
+	}
+	return nil
+}
+
+// setDrainHeld adds or removes DrainHeldAnnotation of the node
+func (dh *DrainHelper) setDrainHeld(ctx context.Context, held bool) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, DrainHeldAnnotation)
+	if held {
+		patch = fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, DrainHeldAnnotation)
+	}
+	_, err := dh.clientSet.CoreV1().Nodes().Patch(ctx, dh.nodeName, types.MergePatchType, []byte(patch),
+		metav1.PatchOptions{})
+	if err != nil {
+		dh.log.Error(err, "failed to update drain held annotation of the node", "held", held)
+	}
+	return err
+}
+
 func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
 	node, nodeGetErr := dh.clientSet.CoreV1().Nodes().Get(ctx, dh.nodeName, metav1.GetOptions{})
 	if nodeGetErr != nil {
@@ -213,6 +245,11 @@ func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
 		return nodeGetErr
 	}
 
+	// node holds the drain before it's cordoned, so it's never left cordoned without holding it
+	if err := dh.setDrainHeld(ctx, true); err != nil {
+		return err
+	}
+
 	var e error
 	backoff := wait.Backoff{Steps: 5, Duration: 15 * time.Second, Factor: 2}
 	f := func() (bool, error) {
@@ -245,6 +282,7 @@ func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
 	return nil
 }
 
+// Uncordon marks the node schedulable and releases the drain held by the node
 func (dh *DrainHelper) Uncordon(ctx context.Context) error {
 	node, err := dh.clientSet.CoreV1().Nodes().Get(ctx, dh.nodeName, metav1.GetOptions{})
 	if err != nil {
@@ -275,5 +313,5 @@ func (dh *DrainHelper) Uncordon(ctx context.Context) error {
 	}
 	dh.log.Info("node uncordoned")
 
-	return nil
+	return dh.setDrainHeld(ctx, false)
 }
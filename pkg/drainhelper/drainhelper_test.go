@@ -5,6 +5,7 @@ package drainhelper
 
 import (
 	"context"
+	"errors"
 	"os"
 	"strconv"
 	"time"
@@ -17,6 +18,7 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("DrainHelper Tests", func() {
@@ -235,6 +237,55 @@ var _ = Describe("DrainHelper Tests", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		var _ = It("Hold the drain until the node is uncordoned", func() {
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "dummy"}}
+			Expect(k8sClient.Create(context.Background(), node)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(context.TODO(), node)).To(Succeed()) }()
+
+			cset, err := clientset.NewForConfig(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(os.Setenv("LEASE_DURATION_SECONDS", "16")).To(Succeed())
+			dh := NewDrainHelper(log, cset, "dummy", "default")
+			other := NewDrainHelper(log, cset, "other", "default")
+
+			Expect(dh.Run(func(c context.Context) bool { return false }, true)).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+			Expect(node.Spec.Unschedulable).To(BeTrue())
+			Expect(node.Annotations).To(HaveKey(DrainHeldAnnotation))
+
+			called := false
+			err = other.Run(func(c context.Context) bool { called = true; return true }, true)
+			var heldErr *DrainHeldError
+			Expect(errors.As(err, &heldErr)).To(BeTrue())
+			Expect(heldErr.Node).To(Equal("dummy"))
+			Expect(called).To(BeFalse())
+
+			// nodes not drained by the operator don't hold the drain
+			Expect(other.Run(func(c context.Context) bool { called = true; return true }, false)).To(Succeed())
+			Expect(called).To(BeTrue())
+
+			Expect(dh.Uncordon(context.Background())).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+			Expect(node.Spec.Unschedulable).To(BeFalse())
+			Expect(node.Annotations).ToNot(HaveKey(DrainHeldAnnotation))
+			Expect(other.checkDrainHeld(context.Background())).To(Succeed())
+		})
+
+		var _ = It("Ignore drain held by node uncordoned by the administrator", func() {
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{
+				Name:        "dummy",
+				Annotations: map[string]string{DrainHeldAnnotation: "true"},
+			}}
+			Expect(k8sClient.Create(context.Background(), node)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(context.TODO(), node)).To(Succeed()) }()
+
+			cset, err := clientset.NewForConfig(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(NewDrainHelper(log, cset, "other", "default").checkDrainHeld(context.Background())).To(Succeed())
+		})
+
 		var _ = It("Create and run simple DrainHelper with drain false", func() {
 			var err error
 			node := &corev1.Node{
@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+)
+
+const (
+	artifactCacheSizeEnvVarName = "ARTIFACT_CACHE_SIZE_MB"
+	artifactCacheSizeDefault    = int64(4096)
+
+	// cacheEntryMarker is created once package is fetched, verified and extracted. It contains package file name
+	// and its checksum, modification time of the marker is used to track last use of the entry
+	cacheEntryMarker = ".complete"
+)
+
+var (
+	// artifactCacheFolder is kept on persistent host storage outside of artifactsFolder, so cached packages survive
+	// artifacts cleanup and node reboot. It must be accessible by the daemon only
+	artifactCacheFolder = "/var/lib/intel-ethernet-operator/artifactcache"
+
+	cacheKeyRegex = regexp.MustCompile(`^(sha1|sha256|sha512)-[a-f0-9]+$`)
+)
+
+// artifactCache keeps fetched FW/DDP packages in folders keyed by package checksum, so identical packages are
+// downloaded once per node and reused across devices and reconciles. Cached package is verified against its checksum
+// on every use and extracted again, extracted files are never trusted. Least recently used entries are evicted once
+// total size of the cache exceeds the limit. Packages without checksum are not cached.
+type artifactCache struct {
+	log     logr.Logger
+	maxSize int64
+	// entries used in current update pass, never evicted
+	inUse map[string]bool
+}
+
+func newArtifactCache(log logr.Logger) *artifactCache {
+	sizeMB := utils.GetOsVarOrUseDefault(log, artifactCacheSizeEnvVarName, artifactCacheSizeDefault)
+	log.Info("artifact cache settings", "size MB", sizeMB)
+
+	return &artifactCache{
+		log:     log.WithName("artifactCache"),
+		maxSize: sizeMB * 1024 * 1024,
+		inUse:   map[string]bool{},
+	}
+}
+
+// cacheKey returns name of the cache entry for the package. Checksum is preferred, OCI artifacts without checksum
+// are identified by digest from the reference. Empty key is returned if package can't be identified by content
+func cacheKey(url, checksum string) string {
+	if checksum == "" && utils.IsOCIReference(url) {
+		if ref, err := utils.ParseOCIReference(url); err == nil {
+			checksum = ref.Digest
+		}
+	}
+	if checksum == "" {
+		return ""
+	}
+	if !strings.Contains(checksum, ":") {
+		checksum = "sha1:" + checksum
+	}
+
+	key := strings.Replace(strings.ToLower(checksum), ":", "-", 1)
+	if !cacheKeyRegex.MatchString(key) {
+		return ""
+	}
+	return key
+}
+
+// acquire returns folder in which the package should be prepared. If the package is already cached and its checksum
+// is still valid, path of the package file is returned and cached is set to true. Any other content of the folder is
+// removed, so the package has to be extracted again
+func (c *artifactCache) acquire(pciAddr, url, checksum string) (folder, pkgPath string, cached bool, err error) {
+	key := cacheKey(url, checksum)
+	if key != "" && c.maxSize > 0 {
+		if err := c.checkCacheFolder(); err != nil {
+			c.log.Error(err, "artifact cache folder can't be used, package won't be cached", "folder", artifactCacheFolder)
+			key = ""
+		}
+	}
+	if key == "" || c.maxSize <= 0 {
+		folder = filepath.Join(artifactsFolder, pciAddr)
+		return folder, "", false, utils.CreateFolder(folder, c.log)
+	}
+
+	c.inUse[key] = true
+	folder = filepath.Join(artifactCacheFolder, key)
+
+	pkgPath, err = c.verifyEntry(folder, checksum)
+	if err == nil {
+		c.log.V(4).Info("Using cached package", "entry", key, "device", pciAddr)
+		return folder, pkgPath, true, nil
+	}
+	if !os.IsNotExist(err) {
+		c.log.Error(err, "discarding cache entry", "entry", key)
+	}
+
+	// entry without marker is a leftover of interrupted or failed preparation
+	if err := os.RemoveAll(folder); err != nil {
+		return "", "", false, err
+	}
+	return folder, "", false, os.Mkdir(folder, 0700)
+}
+
+// checkCacheFolder creates artifactCacheFolder if needed and makes sure it's a directory owned by the daemon user
+// and not accessible by anyone else. Existing entries are dropped if folder was accessible by other users
+func (c *artifactCache) checkCacheFolder() error {
+	if err := os.MkdirAll(artifactCacheFolder, 0700); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(artifactCacheFolder)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%v is not a directory", artifactCacheFolder)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("%v is not owned by uid %v", artifactCacheFolder, os.Geteuid())
+	}
+	if info.Mode().Perm() == 0700 {
+		return nil
+	}
+
+	c.log.Info("Restricting access to artifact cache folder, existing entries are dropped", "mode", info.Mode().Perm())
+	if err := os.Chmod(artifactCacheFolder, 0700); err != nil {
+		return err
+	}
+	dirs, err := os.ReadDir(artifactCacheFolder)
+	if err != nil {
+		return err
+	}
+	for _, d := range dirs {
+		if err := os.RemoveAll(filepath.Join(artifactCacheFolder, d.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyEntry checks cached package against checksum recorded in the marker and the one requested in the config,
+// removes everything else from the entry folder and marks the entry as used. Path of the package is returned
+func (c *artifactCache) verifyEntry(folder, checksum string) (string, error) {
+	markerPath := filepath.Join(folder, cacheEntryMarker)
+	marker, err := os.ReadFile(markerPath)
+	if err != nil {
+		return "", err
+	}
+
+	pkgName, pkgChecksum, found := strings.Cut(string(marker), "\n")
+	if !found || pkgName == "" || pkgName != filepath.Base(pkgName) || pkgName == cacheEntryMarker {
+		return "", fmt.Errorf("invalid cache entry marker")
+	}
+	pkgPath := filepath.Join(folder, pkgName)
+
+	for _, expected := range []string{pkgChecksum, checksum} {
+		if expected == "" {
+			continue
+		}
+		match, err := utils.VerifyChecksum(pkgPath, expected)
+		if err != nil {
+			return "", err
+		}
+		if !match {
+			return "", fmt.Errorf("checksum mismatch of cached package %v", pkgName)
+		}
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.Name() == pkgName || e.Name() == cacheEntryMarker {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(folder, e.Name())); err != nil {
+			return "", err
+		}
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(markerPath, now, now); err != nil {
+		c.log.Error(err, "failed to update last use of cache entry", "entry", filepath.Base(folder))
+	}
+	return pkgPath, nil
+}
+
+// store records checksum of package prepared in folder, marks the entry as complete and evicts least recently used entries if needed.
+// Folders outside of the cache are ignored
+func (c *artifactCache) store(folder, pkgPath string) error {
+	if filepath.Dir(folder) != filepath.Clean(artifactCacheFolder) {
+		return nil
+	}
+
+	checksum, err := utils.FileChecksum(pkgPath)
+	if err != nil {
+		return err
+	}
+	marker := filepath.Base(pkgPath) + "\n" + checksum
+	err = os.WriteFile(filepath.Join(folder, cacheEntryMarker), []byte(marker), 0600)
+	if err != nil {
+		return err
+	}
+
+	c.evict()
+	return nil
+}
+
+// release should be called before each update pass, it allows entries used by previous pass to be evicted
+func (c *artifactCache) release() {
+	c.inUse = map[string]bool{}
+}
+
+type cacheEntry struct {
+	key      string
+	size     int64
+	lastUsed time.Time
+	complete bool
+}
+
+func (c *artifactCache) evict() {
+	entries, err := c.entries()
+	if err != nil {
+		c.log.Error(err, "failed to list cache entries")
+		return
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	// incomplete entries go first, then least recently used ones
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].complete != entries[j].complete {
+			return !entries[i].complete
+		}
+		return entries[i].lastUsed.Before(entries[j].lastUsed)
+	})
+
+	for _, e := range entries {
+		if total <= c.maxSize && e.complete {
+			break
+		}
+		if c.inUse[e.key] {
+			continue
+		}
+		c.log.V(4).Info("Evicting cache entry", "entry", e.key, "size", e.size)
+		if err := os.RemoveAll(filepath.Join(artifactCacheFolder, e.key)); err != nil {
+			c.log.Error(err, "failed to evict cache entry", "entry", e.key)
+			continue
+		}
+		total -= e.size
+	}
+
+	if total > c.maxSize {
+		c.log.Info("Artifact cache exceeds size limit, entries in use can't be evicted", "size", total, "limit", c.maxSize)
+	}
+}
+
+func (c *artifactCache) entries() ([]cacheEntry, error) {
+	dirs, err := os.ReadDir(artifactCacheFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []cacheEntry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		e := cacheEntry{key: d.Name()}
+		path := filepath.Join(artifactCacheFolder, d.Name())
+
+		if info, err := os.Stat(filepath.Join(path, cacheEntryMarker)); err == nil {
+			e.complete = true
+			e.lastUsed = info.ModTime()
+		}
+
+		err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				e.size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
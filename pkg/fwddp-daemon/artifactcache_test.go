@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ArtifactCache", func() {
+	var cache *artifactCache
+	sha1Checksum := strings.Repeat("a", 40)
+	sha256Checksum := "sha256:" + strings.Repeat("b", 64)
+
+	checksumOf := func(content []byte) string {
+		sum := sha256.Sum256(content)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	fillEntry := func(url string, content []byte) string {
+		folder, pkgPath, cached, err := cache.acquire("0000:00:00.1", url, checksumOf(content))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(pkgPath).To(BeEmpty())
+
+		pkgPath = filepath.Join(folder, "package.tar.gz")
+		Expect(os.WriteFile(pkgPath, content, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(folder, "extracted"), []byte("extracted"), 0644)).To(Succeed())
+		Expect(cache.store(folder, pkgPath)).To(Succeed())
+		return folder
+	}
+
+	BeforeEach(func() {
+		artifactsFolder = "./workdir/nvmupdate/"
+		artifactCacheFolder = "./workdir/artifactcache/"
+		cache = &artifactCache{log: log, maxSize: 1024, inUse: map[string]bool{}}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll("./workdir")).To(Succeed())
+	})
+
+	var _ = It("will derive cache key from checksum or OCI digest", func() {
+		Expect(cacheKey("http://fw", sha1Checksum)).To(Equal("sha1-" + sha1Checksum))
+		Expect(cacheKey("http://fw", "SHA256:"+strings.Repeat("B", 64))).To(Equal("sha256-" + strings.Repeat("b", 64)))
+		Expect(cacheKey("oci://registry/fw:v1@"+sha256Checksum, "")).To(Equal("sha256-" + strings.Repeat("b", 64)))
+		Expect(cacheKey("oci://registry/fw:v1", "")).To(BeEmpty())
+		Expect(cacheKey("http://fw", "")).To(BeEmpty())
+		Expect(cacheKey("http://fw", "sha256:../../etc")).To(BeEmpty())
+	})
+
+	var _ = It("will use per-device folder for packages without checksum", func() {
+		folder, _, cached, err := cache.acquire("0000:00:00.1", "http://fw", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(folder).To(Equal(filepath.Join(artifactsFolder, "0000:00:00.1")))
+		Expect(cache.store(folder, filepath.Join(folder, "package.tar.gz"))).To(Succeed())
+		Expect(filepath.Join(folder, cacheEntryMarker)).ToNot(BeAnExistingFile())
+	})
+
+	var _ = It("will reuse stored package and remove extracted files", func() {
+		content := []byte("package")
+		folder := fillEntry("http://fw", content)
+
+		cachedFolder, pkgPath, cached, err := cache.acquire("0000:00:00.2", "http://other/fw", checksumOf(content))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeTrue())
+		Expect(cachedFolder).To(Equal(folder))
+		Expect(pkgPath).To(Equal(filepath.Join(folder, "package.tar.gz")))
+		Expect(pkgPath).To(BeARegularFile())
+		Expect(filepath.Join(folder, "extracted")).ToNot(BeAnExistingFile())
+	})
+
+	var _ = It("will discard entry with modified package", func() {
+		content := []byte("package")
+		folder := fillEntry("http://fw", content)
+		Expect(os.WriteFile(filepath.Join(folder, "package.tar.gz"), []byte("modified"), 0644)).To(Succeed())
+
+		_, _, cached, err := cache.acquire("0000:00:00.1", "http://fw", checksumOf(content))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(filepath.Join(folder, "package.tar.gz")).ToNot(BeAnExistingFile())
+	})
+
+	var _ = It("will discard entry if package doesn't match checksum recorded in marker", func() {
+		folder, _, _, err := cache.acquire("0000:00:00.1", "oci://registry/fw:v1@"+sha256Checksum, "")
+		Expect(err).ToNot(HaveOccurred())
+		pkgPath := filepath.Join(folder, "package.tar.gz")
+		Expect(os.WriteFile(pkgPath, []byte("package"), 0644)).To(Succeed())
+		Expect(cache.store(folder, pkgPath)).To(Succeed())
+		Expect(os.WriteFile(pkgPath, []byte("modified"), 0644)).To(Succeed())
+
+		_, _, cached, err := cache.acquire("0000:00:00.1", "oci://registry/fw:v1@"+sha256Checksum, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(filepath.Join(folder, "package.tar.gz")).ToNot(BeAnExistingFile())
+	})
+
+	var _ = It("will restrict access to cache folder and drop existing entries", func() {
+		folder := fillEntry("http://fw", []byte("package"))
+		Expect(os.Chmod(artifactCacheFolder, 0777)).To(Succeed())
+
+		_, _, cached, err := cache.acquire("0000:00:00.1", "http://fw", checksumOf([]byte("package")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(filepath.Join(folder, "package.tar.gz")).ToNot(BeAnExistingFile())
+
+		info, err := os.Stat(artifactCacheFolder)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+	})
+
+	var _ = It("will not use cache folder owned by other user", func() {
+		if os.Geteuid() != 0 {
+			Skip("changing folder owner requires root")
+		}
+		Expect(os.MkdirAll(artifactCacheFolder, 0700)).To(Succeed())
+		Expect(os.Chown(artifactCacheFolder, 1000, 1000)).To(Succeed())
+
+		folder, _, cached, err := cache.acquire("0000:00:00.1", "http://fw", sha1Checksum)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(folder).To(Equal(filepath.Join(artifactsFolder, "0000:00:00.1")))
+	})
+
+	var _ = It("will discard incomplete entry", func() {
+		folder, _, _, err := cache.acquire("0000:00:00.1", "http://fw", sha1Checksum)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(folder, "partial"), []byte("partial"), 0644)).To(Succeed())
+
+		_, _, cached, err := cache.acquire("0000:00:00.1", "http://fw", sha1Checksum)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(filepath.Join(folder, "partial")).ToNot(BeAnExistingFile())
+	})
+
+	var _ = It("will evict least recently used entries exceeding size limit", func() {
+		oldFolder := fillEntry("http://fw", make([]byte, 600))
+		past := time.Now().Add(-time.Hour)
+		Expect(os.Chtimes(filepath.Join(oldFolder, cacheEntryMarker), past, past)).To(Succeed())
+
+		cache.release()
+		newFolder := fillEntry("http://ddp", make([]byte, 601))
+
+		Expect(oldFolder).ToNot(BeADirectory())
+		Expect(newFolder).To(BeADirectory())
+	})
+
+	var _ = It("will not evict entries used in current pass", func() {
+		firstFolder := fillEntry("http://fw", make([]byte, 600))
+		secondFolder := fillEntry("http://ddp", make([]byte, 601))
+
+		Expect(firstFolder).To(BeADirectory())
+		Expect(secondFolder).To(BeADirectory())
+	})
+})
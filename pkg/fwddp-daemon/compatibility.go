@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+const compatibilityWildcard = "*"
+
+// incompatibleUpdateError is returned if the device would run combination of driver, firmware and DDP
+// versions which is not listed in the compatibility map, or if a target version constrained by the map is unknown
+type incompatibleUpdateError struct {
+	pciAddr  string
+	driver   string
+	firmware string
+	ddp      string
+	// unknownTarget names the artifact whose target version is unknown
+	unknownTarget string
+}
+
+func (e *incompatibleUpdateError) Error() string {
+	if e.unknownTarget != "" {
+		return fmt.Sprintf("update of device %v refused: target %v version is unknown and can't be checked against "+
+			"the compatibility map, set skipCompatibilityCheck to apply it anyway", e.pciAddr, e.unknownTarget)
+	}
+	return fmt.Sprintf("update of device %v refused: driver %v, firmware %v and DDP %v are not a compatible combination, "+
+		"set skipCompatibilityCheck to override", e.pciAddr, e.driver, e.firmware, e.ddp)
+}
+
+// verifyCompatibility checks versions which the device would run after applying artifacts against
+// the compatibility map. The check is skipped if requested in the device config or if nothing would be applied
+func (r *NodeConfigReconciler) verifyCompatibility(config ethernetv1.DeviceNodeConfig, inv []ethernetv1.Device,
+	artifacts deviceUpdateArtifacts) error {
+	log := r.log.WithName("verifyCompatibility").WithValues("device", config.PCIAddress)
+
+	if artifacts.fwPath == "" && artifacts.ddpPath == "" {
+		return nil
+	}
+	if config.DeviceConfig.SkipCompatibilityCheck {
+		log.Info("Compatibility check skipped")
+		return nil
+	}
+
+	for _, device := range inv {
+		if device.PCIAddress == config.PCIAddress {
+			return checkCompatibility(device, artifacts, *compatibilityMap)
+		}
+	}
+	log.V(2).Info("Device not found in inventory, compatibility not checked")
+	return nil
+}
+
+// checkCompatibility returns error unless any entry of compatibility map for the device model accepts the driver
+// currently loaded together with firmware (EETrack ID) and DDP versions the device would run after the update.
+// If an entry of the model constrains a target version which is unknown, e.g. of a raw NVM image flashed with
+// devlink, the update is refused as unverifiable. Models without entries are not checked
+func checkCompatibility(device ethernetv1.Device, artifacts deviceUpdateArtifacts, cmpMap CompatibilityMap) error {
+	driver := strings.TrimSpace(device.Driver + " " + device.DriverVersion)
+
+	firmware, fwUnknown := observedEETrack(device.Firmware.Version), false
+	if artifacts.fwPath != "" {
+		firmware, fwUnknown = artifacts.targetFWVersion, artifacts.targetFWVersion == ""
+	}
+	ddp, ddpUnknown := device.DDP.Version, false
+	if artifacts.ddpPath != "" {
+		ddp, ddpUnknown = artifacts.targetDDPVersion, artifacts.targetDDPVersion == ""
+	}
+
+	modelFound := false
+	var unknownTarget string
+	for _, entry := range cmpMap {
+		if !strings.EqualFold(entry.VendorID, device.VendorID) || !strings.EqualFold(entry.DeviceID, device.DeviceID) {
+			continue
+		}
+		modelFound = true
+		if !isWildcard(entry.Driver) && entry.Driver != device.DriverVersion &&
+			entry.Driver != device.Driver+"-"+device.DriverVersion {
+			continue
+		}
+		if !isWildcard(entry.Firmware) && fwUnknown {
+			unknownTarget = "firmware"
+			continue
+		}
+		if !isWildcard(entry.Firmware) && (firmware == "" || !strings.EqualFold(entry.Firmware, firmware)) {
+			continue
+		}
+		if !ddpAllowed(entry.DDP, ddp) {
+			if ddpUnknown {
+				unknownTarget = "DDP"
+			}
+			continue
+		}
+		return nil
+	}
+	if !modelFound {
+		return nil
+	}
+
+	unknown := func(v string) string {
+		if v == "" {
+			return "<unknown>"
+		}
+		return v
+	}
+	return &incompatibleUpdateError{
+		pciAddr:       device.PCIAddress,
+		driver:        unknown(driver),
+		firmware:      unknown(firmware),
+		ddp:           unknown(ddp),
+		unknownTarget: unknownTarget,
+	}
+}
+
+func ddpAllowed(allowed []string, version string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == compatibilityWildcard || (version != "" && a == version) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWildcard(v string) bool { return v == "" || v == compatibilityWildcard }
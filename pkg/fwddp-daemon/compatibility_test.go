@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("checkCompatibility", func() {
+	cmpMap := CompatibilityMap{
+		"E810-CQDA2": {
+			SupportedDevice: utils.SupportedDevice{VendorID: "8086", Class: "02", SubClass: "00", DeviceID: "1592"},
+			Driver:          "ice-1.9.11",
+			Firmware:        "0x8001778b",
+			DDP:             []string{"1.3.30.0", "1.3.35.0"},
+		},
+		"E810-CQDA2-NEXT": {
+			SupportedDevice: utils.SupportedDevice{VendorID: "8086", Class: "02", SubClass: "00", DeviceID: "1592"},
+			Driver:          "1.11.14",
+			Firmware:        "0x8001b9ad",
+			DDP:             []string{"*"},
+		},
+		"E810-XXVDA4": {
+			SupportedDevice: utils.SupportedDevice{VendorID: "8086", Class: "02", SubClass: "00", DeviceID: "1593"},
+		},
+	}
+
+	var device ethernetv1.Device
+	BeforeEach(func() {
+		device = ethernetv1.Device{
+			PCIAddress:    "0000:18:00.0",
+			VendorID:      "8086",
+			DeviceID:      "1592",
+			Driver:        "ice",
+			DriverVersion: "1.9.11",
+			Firmware:      ethernetv1.FirmwareInfo{Version: "4.01 0x8001778b 1.3246.0"},
+			DDP:           ethernetv1.DDPInfo{Version: "1.3.30.0"},
+		}
+	})
+
+	var _ = It("will accept DDP version allowed for current driver and firmware", func() {
+		artifacts := deviceUpdateArtifacts{ddpPath: "ice.pkg", targetDDPVersion: "1.3.35.0"}
+		Expect(checkCompatibility(device, artifacts, cmpMap)).To(Succeed())
+	})
+
+	var _ = It("will refuse DDP version not allowed for current driver", func() {
+		artifacts := deviceUpdateArtifacts{ddpPath: "ice.pkg", targetDDPVersion: "1.3.36.0"}
+		err := checkCompatibility(device, artifacts, cmpMap)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("driver ice 1.9.11, firmware 0x8001778b and DDP 1.3.36.0"))
+	})
+
+	var _ = It("will refuse DDP update if target version is unknown", func() {
+		artifacts := deviceUpdateArtifacts{ddpPath: "ice.pkg"}
+		err := checkCompatibility(device, artifacts, cmpMap)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("target DDP version is unknown"))
+		Expect(err.Error()).To(ContainSubstring("set skipCompatibilityCheck"))
+	})
+
+	var _ = It("will refuse firmware update if target version is unknown and entries constrain it", func() {
+		artifacts := deviceUpdateArtifacts{fwPath: "E810.bin"}
+		err := checkCompatibility(device, artifacts, cmpMap)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("target firmware version is unknown"))
+		Expect(err.Error()).To(ContainSubstring("set skipCompatibilityCheck"))
+	})
+
+	var _ = It("will match current firmware by EETrack ID", func() {
+		artifacts := deviceUpdateArtifacts{ddpPath: "ice.pkg", targetDDPVersion: "1.3.35.0"}
+		device.Firmware.Version = "4.01 0x8001778c 1.3246.0"
+		err := checkCompatibility(device, artifacts, cmpMap)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("firmware 0x8001778c"))
+
+		// NVM version reported by ethtool is not an EETrack ID
+		nvmVersionEntry := cmpMap["E810-CQDA2"]
+		nvmVersionEntry.Firmware = "4.01"
+		device.Firmware.Version = "4.01 0x8001778b 1.3246.0"
+		Expect(checkCompatibility(device, artifacts, CompatibilityMap{"E810-CQDA2": nvmVersionEntry})).ToNot(Succeed())
+	})
+
+	var _ = It("will match target firmware by EETrack ID", func() {
+		device.DriverVersion = "1.11.14"
+		artifacts := deviceUpdateArtifacts{fwPath: "E810", targetFWVersion: "0x8001b9ad"}
+		Expect(checkCompatibility(device, artifacts, cmpMap)).To(Succeed())
+
+		artifacts.targetFWVersion = "0x8001b9ae"
+		Expect(checkCompatibility(device, artifacts, cmpMap)).ToNot(Succeed())
+	})
+
+	var _ = It("will accept any versions if entry does not constrain them", func() {
+		device.DeviceID = "1593"
+		artifacts := deviceUpdateArtifacts{fwPath: "E810", ddpPath: "ice.pkg"}
+		Expect(checkCompatibility(device, artifacts, cmpMap)).To(Succeed())
+	})
+
+	var _ = It("will not check device model without entries", func() {
+		device.DeviceID = "159b"
+		artifacts := deviceUpdateArtifacts{ddpPath: "ice.pkg", targetDDPVersion: "1.3.36.0"}
+		Expect(checkCompatibility(device, artifacts, cmpMap)).To(Succeed())
+	})
+})
@@ -6,9 +6,11 @@ package daemon
 import (
 	"context"
 	"crypto/x509"
+	"errors"
 	"net/http"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 
 	"os"
@@ -20,6 +22,8 @@ import (
 	"github.com/go-logr/logr"
 	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -40,7 +44,7 @@ var (
 	getInventory = GetInventory
 	execCmd      = utils.ExecCmd
 
-	downloadFile     = utils.DownloadFile
+	downloadFile     = utils.DownloadFileWithOptions
 	untarFile        = utils.Untar
 	unpackDDPArchive = utils.UnpackDDPArchive
 
@@ -66,12 +70,27 @@ const (
 	UpdateFailed           UpdateConditionReason = "Failed"
 	UpdateNotRequested     UpdateConditionReason = "NotRequested"
 	UpdateSucceeded        UpdateConditionReason = "Succeeded"
+	UpdateDryRun           UpdateConditionReason = "DryRun"
+	UpdateIncompatible     UpdateConditionReason = "Incompatible"
+	UpdateVersionMismatch  UpdateConditionReason = "VersionMismatch"
+	UpdateDeferred         UpdateConditionReason = "Deferred"
+	UpdateRebootPending    UpdateConditionReason = "RebootPending"
+	UpdateRebootRequired   UpdateConditionReason = "RebootRequired"
 )
 
 type deviceUpdateArtifacts struct {
-	fwPath        string
-	ddpPath       string
-	fwUpdateParam string
+	fwPath           string
+	ddpPath          string
+	targetFWVersion  string
+	targetDDPVersion string
+	// device config the firmware is applied with, it selects the firmware backend
+	fwConfig ethernetv1.DeviceConfig
+	// requested values of devlink params which differ from values of the device
+	devlinkParams []devlinkParamChange
+	// load DDP by ice driver reload instead of node reboot
+	ddpReload bool
+	// remove DDP package applied to the device
+	ddpRevert bool
 }
 type deviceUpdateQueue map[string]deviceUpdateArtifacts
 
@@ -82,6 +101,15 @@ type NodeConfigReconciler struct {
 	nodeNameRef types.NamespacedName
 	ddpUpdater  *ddpUpdater
 	fwUpdater   *fwUpdater
+	// expected duration of node drain, update and reboot, checked against maintenance windows
+	updateDuration time.Duration
+	// store of device update logs, nil if logs are not kept
+	logStore     operationLogStore
+	logSizeLimit int
+	// recorder of update milestones, nil if events are not recorded
+	recorder record.EventRecorder
+	// interval of periodic inventory refresh, 0 if inventory is refreshed only on kernel uevents
+	inventoryRefreshInterval time.Duration
 }
 
 func LoadConfig() error {
@@ -149,6 +177,31 @@ func NewNodeConfigReconciler(c client.Client, clientSet *clientset.Clientset, lo
 		}
 	}
 
+	downloadOptions := newDownloadOptions(log)
+
+	fetcher := &packageFetcher{
+		log:                 log,
+		httpClient:          httpClient,
+		secrets:             clientSet.CoreV1().Secrets(ns),
+		downloadOptions:     downloadOptions,
+		plainHTTPRegistries: newPlainHTTPRegistries(log),
+	}
+
+	cache := newArtifactCache(log)
+	ddpReloadTimeout := utils.GetOsVarOrUseDefault(log, ddpReloadTimeoutEnvVarName, ddpReloadTimeoutDefault)
+	devlinkFlashTimeout := utils.GetOsVarOrUseDefault(log, devlinkFlashTimeoutEnvVarName, devlinkFlashTimeoutDefault)
+	updateDuration := utils.GetOsVarOrUseDefault(log, maintenanceUpdateDurationEnvVarName, maintenanceUpdateDurationDefault)
+	logSizeLimit := utils.GetOsVarOrUseDefault(log, operationLogSizeLimitEnvVarName, operationLogSizeLimitDefault)
+	inventoryRefreshInterval := utils.GetOsVarOrUseDefault(log, inventoryRefreshIntervalEnvVarName,
+		inventoryRefreshIntervalDefault)
+
+	verifier := &packageVerifier{
+		log:             log,
+		httpClient:      httpClient,
+		secrets:         clientSet.CoreV1().Secrets(ns),
+		downloadOptions: downloadOptions,
+	}
+
 	return &NodeConfigReconciler{
 		Client:      c,
 		log:         log,
@@ -158,13 +211,26 @@ func NewNodeConfigReconciler(c client.Client, clientSet *clientset.Clientset, lo
 			Name:      nodeName,
 		},
 		ddpUpdater: &ddpUpdater{
-			log:        log,
-			httpClient: httpClient,
+			log:           log,
+			httpClient:    httpClient,
+			fetcher:       fetcher,
+			cache:         cache,
+			verifier:      verifier,
+			reloadTimeout: time.Duration(ddpReloadTimeout) * time.Second,
 		},
 		fwUpdater: &fwUpdater{
-			log:        log,
-			httpClient: httpClient,
+			log:          log,
+			httpClient:   httpClient,
+			fetcher:      fetcher,
+			cache:        cache,
+			verifier:     verifier,
+			flashTimeout: time.Duration(devlinkFlashTimeout) * time.Second,
 		},
+		updateDuration: time.Duration(updateDuration) * time.Second,
+		logStore:       newOperationLogStore(log, clientSet.CoreV1(), ns, httpClient),
+		logSizeLimit:   int(logSizeLimit) * 1024,
+
+		inventoryRefreshInterval: time.Duration(inventoryRefreshInterval) * time.Second,
 	}, nil
 }
 
@@ -184,6 +250,15 @@ func getTlsCert(log logr.Logger) *x509.Certificate {
 }
 
 func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor("fwddp-daemon")
+	err := mgr.Add(&inventoryRefresher{
+		log:      r.log.WithName("inventoryRefresher"),
+		interval: r.inventoryRefreshInterval,
+		refresh:  r.refreshInventory,
+	})
+	if err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&ethernetv1.EthernetNodeConfig{}).
 		WithEventFilter(
@@ -192,7 +267,11 @@ func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					requiredName: r.nodeNameRef.Name,
 					log:          r.log,
 				},
-				predicate.GenerationChangedPredicate{},
+				predicate.Or(
+					predicate.GenerationChangedPredicate{},
+					// deferred reboot is approved with annotation
+					predicate.AnnotationChangedPredicate{},
+				),
 			),
 		).
 		Complete(r)
@@ -212,7 +291,56 @@ func (r *NodeConfigReconciler) updateCondition(nc *ethernetv1.EthernetNodeConfig
 	}
 }
 
+// updateConditionMessage replaces message of the update condition if it still has the reason, keeping the rest
+// of the status
+func (r *NodeConfigReconciler) updateConditionMessage(nc *ethernetv1.EthernetNodeConfig, reason UpdateConditionReason,
+	msg string) error {
+	return r.modifyStatus(nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		condition := meta.FindStatusCondition(nodeStatus.Conditions, UpdateCondition)
+		if condition != nil && condition.Reason == string(reason) {
+			condition.Message = msg
+		}
+	})
+}
+
 func (r *NodeConfigReconciler) updateStatus(nc *ethernetv1.EthernetNodeConfig, c []metav1.Condition) error {
+	return r.modifyStatus(nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		nodeStatus.Conditions = nil
+		for _, condition := range c {
+			meta.SetStatusCondition(&nodeStatus.Conditions, condition)
+		}
+	})
+}
+
+// recordRollback stores outcome of the firmware rollback, replacing previous one reported for the same device
+func (r *NodeConfigReconciler) recordRollback(nc *ethernetv1.EthernetNodeConfig, rollback ethernetv1.FirmwareRollback) {
+	log := r.log.WithName("recordRollback")
+
+	err := r.modifyStatus(nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		for i := range nodeStatus.FirmwareRollbacks {
+			if nodeStatus.FirmwareRollbacks[i].PCIAddress == rollback.PCIAddress {
+				nodeStatus.FirmwareRollbacks[i] = rollback
+				return
+			}
+		}
+		nodeStatus.FirmwareRollbacks = append(nodeStatus.FirmwareRollbacks, rollback)
+	})
+	if err != nil {
+		log.Error(err, "failed to record firmware rollback", "device", rollback.PCIAddress)
+	}
+}
+
+// modifyStatus applies modify to the current status of EthernetNodeConfig, keeping the inventory reported before
+func (r *NodeConfigReconciler) modifyStatus(nc *ethernetv1.EthernetNodeConfig, modify func(*ethernetv1.EthernetNodeConfigStatus)) error {
+	return r.modifyStatusWithInventory(nc, nil, modify)
+}
+
+// modifyStatusWithInventory applies modify to the current status of EthernetNodeConfig and replaces its inventory
+// with inv, unless inv is nil. Inventory is taken by callers once per reconcile or refresh pass, not for every
+// status write. Update status of devices which are no longer configured is dropped. Status is written only if
+// it changed
+func (r *NodeConfigReconciler) modifyStatusWithInventory(nc *ethernetv1.EthernetNodeConfig, inv []ethernetv1.Device,
+	modify func(*ethernetv1.EthernetNodeConfigStatus)) error {
 	log := r.log.WithName("updateStatus")
 
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
@@ -220,27 +348,49 @@ func (r *NodeConfigReconciler) updateStatus(nc *ethernetv1.EthernetNodeConfig, c
 			return err
 		}
 
-		inv, err := getInventory(log)
-		if err != nil {
-			log.Error(err, "failed to obtain inventory for the node")
-			return err
+		nodeStatus := nc.Status.DeepCopy()
+		if inv != nil {
+			nodeStatus.Devices = inv
 		}
-		nodeStatus := ethernetv1.EthernetNodeConfigStatus{Devices: inv}
 
-		for _, condition := range c {
-			meta.SetStatusCondition(&nodeStatus.Conditions, condition)
-		}
+		modify(nodeStatus)
+		pruneDeviceUpdates(nodeStatus, nc.Spec.Config)
+		refreshObservedVersions(nodeStatus)
 
-		nc.Status = nodeStatus
+		if equality.Semantic.DeepEqual(&nc.Status, nodeStatus) {
+			log.V(4).Info("EthernetNodeConfig status unchanged")
+			return nil
+		}
+		nc.Status = *nodeStatus
 		if err := r.Status().Update(context.Background(), nc); err != nil {
 			log.Error(err, "failed to update EthernetNodeConfig status")
 			return err
 		}
+		observeNodeStatus(&nc.Status)
 
 		return nil
 	})
 }
 
+// reportInventory replaces inventory in the status of EthernetNodeConfig with inv
+func (r *NodeConfigReconciler) reportInventory(nc *ethernetv1.EthernetNodeConfig, inv []ethernetv1.Device) {
+	err := r.modifyStatusWithInventory(nc, inv, func(*ethernetv1.EthernetNodeConfigStatus) {})
+	if err != nil {
+		r.log.Error(err, "failed to update inventory of EthernetNodeConfig")
+	}
+}
+
+// refreshStatusInventory takes inventory of the node again once devices were changed by the update and reports it
+func (r *NodeConfigReconciler) refreshStatusInventory(nc *ethernetv1.EthernetNodeConfig) ([]ethernetv1.Device, error) {
+	inv, err := getInventory(r.log)
+	if err != nil {
+		r.log.Error(err, "failed to obtain inventory for the node")
+		return nil, err
+	}
+	r.reportInventory(nc, inv)
+	return inv, nil
+}
+
 func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.log.WithName("Reconcile").WithValues("namespace", req.Namespace, "name", req.Name)
 	nodeConfig := &ethernetv1.EthernetNodeConfig{}
@@ -254,43 +404,137 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		log.Error(err, "Get() failed")
 		return requeueNowWithError(err)
 	}
+	observeNodeStatus(&nodeConfig.Status)
 
 	condition := meta.FindStatusCondition(nodeConfig.Status.Conditions, UpdateCondition)
 	if condition != nil && condition.Reason == string(UpdatePostUpdateReboot) {
 		log.V(4).Info("Post-update node reboot completed, finishing update...")
-
-		err := r.drainHelper.Uncordon(context.TODO())
+		return r.finishUpdateAfterReboot(nodeConfig)
+	}
+	if condition != nil && (condition.Reason == string(UpdateRebootPending) ||
+		condition.Reason == string(UpdateRebootRequired)) {
+		rebooted, err := isRebooted(nodeConfig)
 		if err != nil {
-			log.Error(err, "failed to uncordon node")
+			log.Error(err, "failed to check node reboot")
 			return requeueLater()
 		}
-
-		r.updateCondition(nodeConfig, metav1.ConditionTrue, UpdateSucceeded, "Updated successfully")
-		log.V(2).Info("Reconciled")
+		if rebooted {
+			log.V(4).Info("Node rebooted, finishing update...")
+			return r.finishUpdateAfterReboot(nodeConfig)
+		}
+		if condition.Reason == string(UpdateRebootPending) && isRebootApproved(nodeConfig) {
+			log.Info("Node reboot approved")
+			r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdatePostUpdateReboot, "Post-update node reboot")
+			r.nodeEvent(nodeConfig, corev1.EventTypeNormal, EventRebootIssued, "Approved post-update node reboot issued")
+			if err := r.rebootNode(); err != nil {
+				log.Error(err, "failed to reboot node")
+				updateFailures.WithLabelValues(failureReasonRebootFailed).Inc()
+				r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateRebootPending, "Approved node reboot failed: "+err.Error())
+			}
+			return doNotRequeue()
+		}
+		log.V(4).Info("Waiting for node reboot")
 		return doNotRequeue()
 	}
 
+	inv, err := getInventory(log)
+	if err != nil {
+		log.Error(err, "failed to obtain inventory for the node")
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateFailed, err.Error())
+		return requeueLater()
+	}
+	r.reportInventory(nodeConfig, inv)
+
 	if len(nodeConfig.Spec.Config) == 0 || r.allDeviceConfigsEmpty(nodeConfig.Spec.Config) {
 		log.V(4).Info("Nothing to do")
+		r.publishUpdatePlan(nodeConfig, nil)
+		r.syncPortSettingsAfterUpdate(nodeConfig)
 		r.updateCondition(nodeConfig, metav1.ConditionTrue, UpdateNotRequested, "Inventory up to date")
 		return doNotRequeue()
 	}
 
+	if deferred := r.checkMaintenanceWindows(nodeConfig, deferredUpdateQueue(nodeConfig)); deferred != nil {
+		log.V(2).Info("Update still deferred, packages not prepared")
+		return r.deferUpdate(nodeConfig, deferred)
+	}
+
 	r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateInProgress, "Update started")
+	r.startDeviceUpdates(nodeConfig)
 
-	updateQueue, err := r.prepareUpdateQueue(nodeConfig)
+	updateQueue, plans, err := r.prepareUpdateQueue(nodeConfig, inv)
 	if err != nil {
+		var incompatibleErr *incompatibleUpdateError
+		if errors.As(err, &incompatibleErr) {
+			updateFailures.WithLabelValues(failureReasonIncompatible).Inc()
+			r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateIncompatible, err.Error())
+			return doNotRequeue()
+		}
+		updateFailures.WithLabelValues(failureReasonPrepare).Inc()
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateFailed, err.Error())
+		return requeueLater()
+	}
+	r.publishUpdatePlan(nodeConfig, plans)
+
+	if len(plans) == len(nodeConfig.Spec.Config) {
+		log.V(2).Info("All devices configured with dry run, update plan computed")
+		if err := os.RemoveAll(artifactsFolder); err != nil {
+			log.Info("Error deleting artifacts folder", "error", err)
+		}
+		r.syncPortSettingsAfterUpdate(nodeConfig)
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateDryRun, "Update plan computed, no update performed")
+		return doNotRequeue()
+	}
+
+	if len(updateQueue) == 0 {
+		log.V(2).Info("All devices already run requested versions, skipping node drain")
+		if err := os.RemoveAll(artifactsFolder); err != nil {
+			log.Info("Error deleting artifacts folder", "error", err)
+		}
+		r.syncPortSettingsAfterUpdate(nodeConfig)
+		r.updateCondition(nodeConfig, metav1.ConditionTrue, UpdateSucceeded, "Devices already up to date")
+		return doNotRequeue()
+	}
+
+	if deferred := r.checkMaintenanceWindows(nodeConfig, updateQueue); deferred != nil {
+		return r.deferUpdate(nodeConfig, deferred)
+	}
+
+	if err := r.applyRuntimeDevlinkParams(nodeConfig, updateQueue); err != nil {
 		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateFailed, err.Error())
 		return requeueLater()
 	}
+	if len(updateQueue) == 0 {
+		log.V(2).Info("Only runtime devlink params set, skipping node drain")
+		if err := os.RemoveAll(artifactsFolder); err != nil {
+			log.Info("Error deleting artifacts folder", "error", err)
+		}
+		r.syncPortSettingsAfterUpdate(nodeConfig)
+		r.updateCondition(nodeConfig, metav1.ConditionTrue, UpdateSucceeded, "Updated successfully")
+		return doNotRequeue()
+	}
 
 	rebootRequired, err := r.configureNode(updateQueue, nodeConfig)
+	var heldErr *dh.DrainHeldError
+	if errors.As(err, &heldErr) {
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateDeferred, err.Error())
+		return requeueLater()
+	}
 	if err != nil {
 		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateFailed, err.Error())
 		return requeueLater()
 	}
 
 	if !rebootRequired {
+		// saved NVM images are kept for the next check if inventory is not available
+		if inv, err := r.refreshStatusInventory(nodeConfig); err == nil {
+			if err := r.checkFlashedDevices(nodeConfig, inv); err != nil {
+				log.Error(err, "Post-update health check failed")
+				updateFailures.WithLabelValues(failureReasonFlashFailed).Inc()
+				r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateFailed, err.Error())
+				return doNotRequeue()
+			}
+		}
+		r.syncPortSettingsAfterUpdate(nodeConfig)
 		r.updateCondition(nodeConfig, metav1.ConditionTrue, UpdateSucceeded, "Updated successfully")
 		log.V(2).Info("Reconciled")
 	}
@@ -298,28 +542,82 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return doNotRequeue()
 }
 
+// finishUpdateAfterReboot verifies health and versions reported by devices updated before the reboot, restoring
+// saved NVM image of unhealthy ones, and uncordons the node
+func (r *NodeConfigReconciler) finishUpdateAfterReboot(nodeConfig *ethernetv1.EthernetNodeConfig) (ctrl.Result, error) {
+	log := r.log.WithName("finishUpdateAfterReboot")
+
+	inv, err := getInventory(log)
+	if err != nil {
+		log.Error(err, "failed to obtain inventory for the node")
+		return requeueNowWithError(err)
+	}
+	keepCordoned, mismatchErr := r.verifyDeviceVersions(nodeConfig, inv)
+	if keepCordoned {
+		log.Info("Versions reported after reboot do not match applied ones, node is left cordoned")
+	} else if err := r.drainHelper.Uncordon(context.TODO()); err != nil {
+		log.Error(err, "failed to uncordon node")
+		return requeueLater()
+	} else {
+		r.nodeEvent(nodeConfig, corev1.EventTypeNormal, EventNodeUncordoned, "Node uncordoned after post-update reboot")
+	}
+	r.finishReboot(nodeConfig)
+
+	if err := r.checkFlashedDevices(nodeConfig, inv); err != nil {
+		log.Error(err, "Post-update health check failed")
+		updateFailures.WithLabelValues(failureReasonFlashFailed).Inc()
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateFailed, err.Error())
+		return doNotRequeue()
+	}
+	if mismatchErr != nil {
+		log.Error(mismatchErr, "Post-update verification failed")
+		updateFailures.WithLabelValues(failureReasonVersionMismatch).Inc()
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateVersionMismatch, mismatchErr.Error())
+		return doNotRequeue()
+	}
+	if err := r.reapplyDevlinkParams(nodeConfig); err != nil {
+		log.Error(err, "Failed to set devlink params after reboot")
+		updateFailures.WithLabelValues(failureReasonDevlinkParamsFailed).Inc()
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateFailed, err.Error())
+		return requeueLater()
+	}
+	r.syncPortSettingsAfterUpdate(nodeConfig)
+	r.updateCondition(nodeConfig, metav1.ConditionTrue, UpdateSucceeded, "Updated successfully")
+	log.V(2).Info("Reconciled")
+	return doNotRequeue()
+}
+
 func (r *NodeConfigReconciler) configureNode(updateQueue deviceUpdateQueue, nodeConfig *ethernetv1.EthernetNodeConfig) (bool, error) {
 	//func start
 	var nodeActionErr error
 	var rebootRequired bool
 
+	drain := !nodeConfig.Spec.DrainSkip
+	drainStart := timeNow()
 	drainFunc := func(ctx context.Context) bool {
-		fwReboot := false
-		ddpReboot := false
-
+		if drain {
+			drainDuration.Observe(timeNow().Sub(drainStart).Seconds())
+			r.nodeEvent(nodeConfig, corev1.EventTypeNormal, EventNodeDrained, "Node cordoned and drained")
+		}
 		for pciAddr, artifacts := range updateQueue {
-			fwReboot, nodeActionErr = r.fwUpdater.handleFWUpdate(pciAddr, artifacts.fwPath, artifacts.fwUpdateParam)
-			if nodeActionErr != nil {
-				return true
-			}
-
-			ddpReboot, nodeActionErr = r.ddpUpdater.handleDDPUpdate(pciAddr, artifacts.ddpPath)
+			updateAttempts.Inc()
+			deviceStatus := r.deviceStatus(nodeConfig, pciAddr)
+
+			// logs are stored before artifacts, which hold logs of NVM Update utility, are removed
+			opLog := r.newOperationLog()
+			var deviceReboot bool
+			deviceReboot, nodeActionErr = r.updateDevice(nodeConfig, pciAddr, artifacts, deviceStatus, opLog)
+			r.storeOperationLog(nodeConfig, pciAddr, opLog, deviceStatus)
 			if nodeActionErr != nil {
+				deviceStatus.fail(nodeActionErr)
 				return true
 			}
 
-			if fwReboot || ddpReboot {
+			if deviceReboot {
 				rebootRequired = true
+				deviceStatus.setPhase(DevicePhaseRebooting)
+			} else {
+				deviceStatus.setPhase(DevicePhaseSucceeded)
 			}
 		}
 
@@ -329,72 +627,236 @@ func (r *NodeConfigReconciler) configureNode(updateQueue deviceUpdateQueue, node
 		}
 
 		if rebootRequired {
-			r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdatePostUpdateReboot, "Post-update node reboot")
-			nodeActionErr = r.rebootNode()
+			nodeActionErr = r.requestReboot(nodeConfig, rebootStrategy(nodeConfig, updateQueue))
+			if nodeActionErr != nil {
+				updateFailures.WithLabelValues(failureReasonRebootFailed).Inc()
+			}
 			return false
 		}
 
 		return true
 	}
 	//func end
-	drainErr := r.drainHelper.Run(drainFunc, !nodeConfig.Spec.DrainSkip)
+	if drain {
+		r.nodeEvent(nodeConfig, corev1.EventTypeNormal, EventNodeDraining, "Draining node to update %v device(s)",
+			len(updateQueue))
+	}
+	drainErr := r.drainHelper.Run(drainFunc, drain)
 
+	var heldErr *dh.DrainHeldError
+	if errors.As(drainErr, &heldErr) {
+		r.log.Info("Node drain postponed", "reason", drainErr.Error())
+		return false, drainErr
+	}
 	if drainErr != nil {
 		r.log.Error(drainErr, "Error during node draining")
+		r.nodeEvent(nodeConfig, corev1.EventTypeWarning, EventDrainFailed, "Node drain failed: %v", drainErr)
+		updateFailures.WithLabelValues(failureReasonDrainFailed).Inc()
+		r.finishDeviceUpdates(nodeConfig, DevicePhaseFailed, drainErr, DevicePhasePending)
 		return false, drainErr
 	}
 	if nodeActionErr != nil {
 		r.log.Error(nodeActionErr, "Error during node FW/DDP update")
+		// reboot failure leaves devices waiting for reboot
+		r.finishDeviceUpdates(nodeConfig, DevicePhaseFailed, nodeActionErr, DevicePhaseRebooting)
 		return false, nodeActionErr
 	}
+	if drain && !rebootRequired {
+		r.nodeEvent(nodeConfig, corev1.EventTypeNormal, EventNodeUncordoned, "Node uncordoned after update")
+	}
 
 	return rebootRequired, nil
 }
 
-func (r *NodeConfigReconciler) prepareUpdateQueue(nodeConfig *ethernetv1.EthernetNodeConfig) (deviceUpdateQueue, error) {
-	inv, err := getInventory(r.log)
+// updateDevice applies firmware and DDP package to the device, recording logs in opLog. True is returned if node
+// reboot is required to complete the update
+func (r *NodeConfigReconciler) updateDevice(nodeConfig *ethernetv1.EthernetNodeConfig, pciAddr string,
+	artifacts deviceUpdateArtifacts, deviceStatus *deviceStatusReporter, opLog *operationLog) (bool, error) {
+	fwUpdater := r.fwUpdater.withOperationLog(opLog)
+	ddpUpdater := r.ddpUpdater.withOperationLog(opLog)
+
+	if artifacts.fwPath != "" {
+		deviceStatus.setPhase(DevicePhaseFlashing)
+		deviceStatus.event(corev1.EventTypeNormal, EventFlashStarted, "Flashing firmware %v",
+			artifacts.targetFWVersion)
+	}
+	fwReboot, rollback, err := fwUpdater.handleFWUpdate(pciAddr, artifacts.fwPath, artifacts.fwConfig, deviceStatus)
+	if rollback != nil {
+		r.recordRollback(nodeConfig, *rollback)
+	}
 	if err != nil {
-		return deviceUpdateQueue{}, err
+		deviceStatus.event(corev1.EventTypeWarning, EventFlashFailed, "Firmware update failed: %v", err)
+		updateFailures.WithLabelValues(failureReasonFlashFailed).Inc()
+		return false, err
+	}
+	if artifacts.fwPath != "" {
+		deviceStatus.event(corev1.EventTypeNormal, EventFlashCompleted, "Firmware %v flashed, reboot required: %v",
+			artifacts.targetFWVersion, fwReboot)
 	}
 
-	err = os.RemoveAll(artifactsFolder)
+	// reboot required by firmware update loads the DDP package anyway
+	ddpReload := artifacts.ddpReload && !fwReboot
+	var ddpReboot bool
+	if artifacts.ddpRevert {
+		deviceStatus.setPhase(DevicePhaseRevertingDDP)
+		ddpReboot, err = ddpUpdater.handleDDPRevert(pciAddr, ddpReload, deviceStatus)
+	} else {
+		if artifacts.ddpPath != "" {
+			deviceStatus.setPhase(DevicePhaseCopyingDDP)
+		}
+		ddpReboot, err = ddpUpdater.handleDDPUpdate(pciAddr, artifacts.ddpPath, ddpReload,
+			artifacts.targetDDPVersion, deviceStatus)
+	}
+	if err != nil {
+		deviceStatus.event(corev1.EventTypeWarning, EventDDPFailed, "DDP update failed: %v", err)
+		updateFailures.WithLabelValues(failureReasonDDPFailed).Inc()
+		return false, err
+	}
+	if artifacts.ddpRevert {
+		deviceStatus.event(corev1.EventTypeNormal, EventDDPReverted, "DDP package reverted, reboot required: %v",
+			ddpReboot)
+	} else if artifacts.ddpPath != "" {
+		deviceStatus.event(corev1.EventTypeNormal, EventDDPCopied, "DDP package %v copied, reboot required: %v",
+			artifacts.targetDDPVersion, ddpReboot)
+	}
+
+	// DDP load by driver reload resets devlink params, they are set afterwards
+	paramsReboot, err := r.setDevlinkParams(pciAddr, artifacts.devlinkParams, fwReboot || ddpReboot, deviceStatus)
+	if err != nil {
+		return false, err
+	}
+	return fwReboot || ddpReboot || paramsReboot, nil
+}
+
+// prepareUpdateQueue prepares artifacts of devices to be updated, comparing them with inventory inv. Devices
+// configured with dry run are not queued, their update plans are returned instead
+func (r *NodeConfigReconciler) prepareUpdateQueue(nodeConfig *ethernetv1.EthernetNodeConfig,
+	inv []ethernetv1.Device) (deviceUpdateQueue, []ethernetv1.DeviceUpdatePlan, error) {
+	err := os.RemoveAll(artifactsFolder)
 	if err != nil {
 		r.log.Error(err, "Failed to prepare firmware")
-		return deviceUpdateQueue{}, err
+		return deviceUpdateQueue{}, nil, err
 	}
+	r.fwUpdater.cache.release()
 
 	updateQueue := make(deviceUpdateQueue)
+	var plans []ethernetv1.DeviceUpdatePlan
 	for _, deviceConfig := range nodeConfig.Spec.Config {
-		artifacts, err := r.prepareArtifacts(deviceConfig, inv)
+		if deviceConfig.DryRun {
+			plans = append(plans, r.planDeviceUpdate(deviceConfig, inv, nodeConfig.Status.FlashedImages))
+			continue
+		}
+
+		deviceStatus := r.deviceStatus(nodeConfig, deviceConfig.PCIAddress)
+		artifacts, err := r.prepareArtifacts(deviceConfig, inv, nodeConfig.Status.FlashedImages, deviceStatus)
 		if err != nil {
 			r.log.Error(err, "Failed to prepare artifacts for", "device", deviceConfig.PCIAddress)
-			return deviceUpdateQueue{}, err
+			deviceStatus.fail(err)
+			return deviceUpdateQueue{}, nil, err
+		}
+		if err := r.verifyCompatibility(deviceConfig, inv, artifacts); err != nil {
+			r.log.Error(err, "Incompatible update", "device", deviceConfig.PCIAddress)
+			deviceStatus.fail(err)
+			return deviceUpdateQueue{}, nil, err
+		}
+		if artifacts.fwPath == "" && artifacts.ddpPath == "" && !artifacts.ddpRevert &&
+			len(artifacts.devlinkParams) == 0 {
+			r.log.V(2).Info("Device is up to date, skipping update", "device", deviceConfig.PCIAddress)
+			deviceStatus.setPhase(DevicePhaseUpToDate)
+			continue
 		}
 		updateQueue[deviceConfig.PCIAddress] = artifacts
 	}
-	return updateQueue, nil
+	return updateQueue, plans, nil
 }
 
-func (r *NodeConfigReconciler) prepareArtifacts(config ethernetv1.DeviceNodeConfig, inv []ethernetv1.Device) (deviceUpdateArtifacts, error) {
+func (r *NodeConfigReconciler) prepareArtifacts(config ethernetv1.DeviceNodeConfig, inv []ethernetv1.Device,
+	flashed []ethernetv1.FlashedImage, deviceStatus *deviceStatusReporter) (deviceUpdateArtifacts, error) {
 	log := r.log.WithName("prepare")
 
-	fwPath, err := r.fwUpdater.prepareFirmware(config)
+	fwPath, err := r.fwUpdater.prepareFirmware(config, deviceStatus)
 	if err != nil {
 		log.Error(err, "Failed to prepare firmware")
 		return deviceUpdateArtifacts{}, err
 	}
-	fwUpdateParam := config.DeviceConfig.FWUpdateParam
-	if fwUpdateParam != "" {
+	if config.DeviceConfig.FWUpdateParam != "" {
 		log.V(4).Info("Found NVM Update parameter", "parameter", config.DeviceConfig.FWUpdateParam)
 	}
 
-	ddpPath, err := r.ddpUpdater.prepareDDP(config)
-	if err != nil {
-		log.Error(err, "Failed to prepare DDP")
-		return deviceUpdateArtifacts{}, err
+	artifacts := deviceUpdateArtifacts{fwPath: fwPath, fwConfig: config.DeviceConfig,
+		ddpReload: config.DeviceConfig.DDPApplyStrategy == DDPApplyStrategyDriverReload}
+
+	if config.DeviceConfig.DDPRevert {
+		// revert is needed only if any DDP package was applied to the device
+		artifacts.ddpRevert, err = r.ddpUpdater.isDDPApplied(config.PCIAddress)
+		if err != nil {
+			log.Error(err, "Failed to check DDP package applied to the device")
+			return deviceUpdateArtifacts{}, err
+		}
+	} else {
+		artifacts.ddpPath, err = r.ddpUpdater.prepareDDP(config, deviceStatus)
+		if err != nil {
+			log.Error(err, "Failed to prepare DDP")
+			return deviceUpdateArtifacts{}, err
+		}
+	}
+
+	if fwPath != "" {
+		artifacts.targetFWVersion, err = r.fwUpdater.backend(config.DeviceConfig.FWUpdateBackend).targetVersion(fwPath,
+			config.PCIAddress)
+		if errors.Is(err, errNVMImageVersionUnknown) {
+			// raw NVM image doesn't carry its version, it is known if the same image was flashed before
+			artifacts.targetFWVersion, err = flashedImageVersion(flashed, config.PCIAddress, fwPath)
+		}
+		if err != nil {
+			log.V(2).Info("Unable to determine target firmware version", "device", config.PCIAddress, "error", err)
+		}
+	}
+	if artifacts.ddpPath != "" {
+		artifacts.targetDDPVersion, err = ddpTargetVersion(artifacts.ddpPath)
+		if err != nil {
+			log.V(2).Info("Unable to determine target DDP version", "device", config.PCIAddress, "error", err)
+		}
+	}
+	deviceStatus.setTargetVersions(artifacts.targetFWVersion, artifacts.targetDDPVersion)
+	skipCompliantArtifacts(&artifacts, config.PCIAddress, inv, log)
+
+	if len(config.DeviceConfig.DevlinkParams) != 0 {
+		params, err := newDeviceInfoProvider(log).devlinkParams(config.PCIAddress)
+		if err != nil {
+			log.Error(err, "Failed to get devlink params of the device")
+			return deviceUpdateArtifacts{}, err
+		}
+		artifacts.devlinkParams, err = pendingDevlinkParams(config.DeviceConfig.DevlinkParams, params)
+		if err != nil {
+			log.Error(err, "Invalid devlink params")
+			return deviceUpdateArtifacts{}, err
+		}
 	}
 
-	return deviceUpdateArtifacts{fwPath, ddpPath, fwUpdateParam}, nil
+	return artifacts, nil
+}
+
+// skipCompliantArtifacts drops firmware and DDP packages which provide versions already running on the device.
+// Artifacts with unknown target version are always applied
+func skipCompliantArtifacts(artifacts *deviceUpdateArtifacts, pciAddr string, inv []ethernetv1.Device, log logr.Logger) {
+	for _, device := range inv {
+		if device.PCIAddress != pciAddr {
+			continue
+		}
+
+		if artifacts.fwPath != "" && artifacts.targetFWVersion != "" &&
+			observedEETrack(device.Firmware.Version) == artifacts.targetFWVersion {
+			log.V(2).Info("Device already runs requested firmware", "device", pciAddr, "version", artifacts.targetFWVersion)
+			artifacts.fwPath = ""
+		}
+		if artifacts.ddpPath != "" && artifacts.targetDDPVersion != "" &&
+			device.DDP.Version == artifacts.targetDDPVersion {
+			log.V(2).Info("Device already runs requested DDP profile", "device", pciAddr, "version", artifacts.targetDDPVersion)
+			artifacts.ddpPath = ""
+		}
+		return
+	}
 }
 
 func (r *NodeConfigReconciler) CreateEmptyNodeConfigIfNeeded(c client.Client) error {
@@ -447,7 +909,7 @@ func (r *NodeConfigReconciler) rebootNode() error {
 
 func (r *NodeConfigReconciler) allDeviceConfigsEmpty(deviceNodeConfigs []ethernetv1.DeviceNodeConfig) bool {
 	for _, config := range deviceNodeConfigs {
-		if config.DeviceConfig != (ethernetv1.DeviceConfig{}) {
+		if !equality.Semantic.DeepEqual(config.DeviceConfig, ethernetv1.DeviceConfig{}) {
 			return false
 		}
 	}
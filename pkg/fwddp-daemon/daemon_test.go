@@ -10,15 +10,20 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	gerrors "errors"
 
 	"github.com/go-logr/logr"
 	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	dh "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/drainhelper"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -97,6 +102,42 @@ func initReconciler(toBeInitialized *NodeConfigReconciler, nodeName, namespace s
 
 var data = TestData{}
 
+// writeUpdateResult writes update.xml reporting rebootRequired as the NVM Update utility run by cmd would
+func writeUpdateResult(cmd *exec.Cmd, rebootRequired int) error {
+	if !isNvmupdateUpdate(cmd) {
+		return nil
+	}
+	result := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+		<DeviceUpdate lang="en">
+			<RebootRequired> %v </RebootRequired>
+		</DeviceUpdate>`, rebootRequired)
+	return os.WriteFile(updateResultPath(cmd.Dir), []byte(result), 0644)
+}
+
+// writeNvmupdateTool places NVM Update utility and its config in dir, as found in unpacked firmware package
+func writeNvmupdateTool(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, nvmupdate64e), []byte("#!/bin/sh\n"), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(nvmupdate64eCfgPath(dir), []byte("CONFIG VERSION: 1.20.0\n"), 0600)
+}
+
+// writeBackupImage writes NVM image as the NVM Update utility run by cmd with -b option would
+func writeBackupImage(cmd *exec.Cmd) error {
+	if !strings.Contains(strings.Join(cmd.Args, " "), " -b ") {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(cmd.Dir, "6805CAC8B4A0"+backupImageExt), []byte("nvm image"), 0600)
+}
+
+// isNvmupdateUpdate returns true if cmd is a firmware update run, not NVM backup or restore
+func isNvmupdateUpdate(cmd *exec.Cmd) bool {
+	return cmd.Args[1] == "-u" && !strings.Contains(strings.Join(cmd.Args, " "), " -a ")
+}
+
 var _ = Describe("DaemonTests", func() {
 	reconciler := new(NodeConfigReconciler)
 	var _ = BeforeEach(func() {
@@ -106,7 +147,7 @@ var _ = Describe("DaemonTests", func() {
 		getInventory = func(_ logr.Logger) ([]ethernetv1.Device, error) {
 			return data.Inventory, nil
 		}
-		downloadFile = func(path, url, checksum string, client *http.Client) error {
+		downloadFile = func(path, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
 			return nil
 		}
 		untarFile = func(srcPath string, dstPath string, log logr.Logger) error {
@@ -120,6 +161,14 @@ var _ = Describe("DaemonTests", func() {
 		}
 
 		artifactsFolder = "./workdir/nvmupdate/"
+		nvmBackupFolder = "./workdir/nvmbackup/"
+		artifactCacheFolder = "./workdir/artifactcache/"
+		Expect(os.RemoveAll(artifactCacheFolder)).To(Succeed())
+		readDeviceSerialNumber = func(string) (uint64, error) {
+			return 0xb49691ffffaf6d68, nil
+		}
+		verifyDetachedSignature = utils.VerifyDetachedSignature
+		pullOCIArtifact = utils.PullOCIArtifact
 	})
 
 	var _ = Context("Reconciler", func() {
@@ -201,6 +250,77 @@ var _ = Describe("DaemonTests", func() {
 			Expect(nodeConfigs.Items[0].Status.Devices[0]).To(Equal(data.Inventory[0]))
 		})
 
+		var _ = It("will refresh inventory only if it changed", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config = []ethernetv1.DeviceNodeConfig{}
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			Expect(reconciler.refreshInventory(context.TODO())).To(Succeed())
+			nodeConfig := &ethernetv1.EthernetNodeConfig{}
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+			Expect(nodeConfig.Status.Devices).To(Equal(data.Inventory))
+			resourceVersion := nodeConfig.ResourceVersion
+
+			Expect(reconciler.refreshInventory(context.TODO())).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+			Expect(nodeConfig.ResourceVersion).To(Equal(resourceVersion))
+
+			data.Inventory[0].Firmware.Version = "NewFWVersion"
+			Expect(reconciler.refreshInventory(context.TODO())).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+			Expect(nodeConfig.ResourceVersion).ToNot(Equal(resourceVersion))
+			Expect(nodeConfig.Status.Devices[0].Firmware.Version).To(Equal("NewFWVersion"))
+		})
+
+		var _ = It("will only report drift of port settings on inventory refresh and re-apply them on reconcile", func() {
+			origDevicesPath, origConfigurator := pciDevicesPath, newPortConfigurator
+			defer func() {
+				pciDevicesPath, newPortConfigurator = origDevicesPath, origConfigurator
+			}()
+			pciDevicesPath = GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(pciDevicesPath, "0000:00:00.1", "net", "ens785f0"), 0755)).To(Succeed())
+			conf := &fakePortConfigurator{settings: map[string]*ethernetv1.PortSettings{
+				"ens785f0": {RxRingSize: intValue(2048), PrivateFlags: map[string]bool{}},
+			}}
+			newPortConfigurator = func() (portConfigurator, error) { return conf, nil }
+
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			data.NodeConfig.Spec.Config = []ethernetv1.DeviceNodeConfig{{
+				PCIAddress:   "0000:00:00.1",
+				DeviceConfig: ethernetv1.DeviceConfig{PortSettings: &ethernetv1.PortSettings{RxRingSize: intValue(4096)}},
+			}}
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			Expect(reconciler.refreshInventory(context.TODO())).To(Succeed())
+			Expect(conf.applied).To(BeEmpty())
+			nodeConfig := &ethernetv1.EthernetNodeConfig{}
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+			Expect(nodeConfig.Status.PortSettings).To(HaveLen(1))
+			Expect(nodeConfig.Status.PortSettings[0].PCIAddress).To(Equal("0000:00:00.1"))
+			Expect(nodeConfig.Status.PortSettings[0].Interface).To(Equal("ens785f0"))
+			Expect(nodeConfig.Status.PortSettings[0].Drift).To(Equal([]string{"rxRingSize: requested 4096, observed 2048"}))
+			Expect(nodeConfig.Status.PortSettings[0].LastApplied).To(BeNil())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conf.applied).To(Equal([]string{"ens785f0"}))
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+			Expect(nodeConfig.Status.PortSettings[0].Drift).To(BeEmpty())
+			lastApplied := nodeConfig.Status.PortSettings[0].LastApplied
+			Expect(lastApplied).ToNot(BeNil())
+
+			// driver reload resets the settings
+			conf.settings["ens785f0"].RxRingSize = intValue(2048)
+			Expect(reconciler.refreshInventory(context.TODO())).To(Succeed())
+			Expect(conf.applied).To(HaveLen(1))
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+			Expect(nodeConfig.Status.PortSettings[0].Drift).To(Equal([]string{"rxRingSize: requested 4096, observed 2048"}))
+			Expect(nodeConfig.Status.PortSettings[0].LastApplied).To(Equal(lastApplied))
+		})
+
 		var _ = It("will update condition to Inventory up to date if Spec.Config is empty", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 
@@ -254,7 +374,7 @@ var _ = Describe("DaemonTests", func() {
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
 			downloadErr := gerrors.New("unable to download")
-			downloadFile = func(path, url, checksum string, client *http.Client) error {
+			downloadFile = func(path, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
 				return downloadErr
 			}
 
@@ -275,6 +395,65 @@ var _ = Describe("DaemonTests", func() {
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(downloadErr.Error()))
 		})
 
+		var _ = It("will take inventory once per reconcile, not for every status write", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+			inventoryCalls := 0
+			getInventory = func(_ logr.Logger) ([]ethernetv1.Device, error) {
+				inventoryCalls++
+				return data.Inventory, nil
+			}
+			downloadFile = func(path, url, checksum string, client *http.Client, opts utils.DownloadOptions) error {
+				return gerrors.New("unable to download")
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inventoryCalls).To(Equal(1))
+
+			nodeConfig := &ethernetv1.EthernetNodeConfig{}
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+			Expect(nodeConfig.Status.Devices).To(Equal(data.Inventory))
+			Expect(nodeConfig.Status.DeviceUpdates).To(HaveLen(1))
+			Expect(nodeConfig.Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseFailed))
+		})
+
+		var _ = It("will report download progress in update condition", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			var progressMessage string
+			downloadErr := gerrors.New("unable to download")
+			downloadFile = func(path, url, checksum string, client *http.Client, opts utils.DownloadOptions) error {
+				Expect(opts.Progress).ToNot(BeNil())
+				// progress is published asynchronously, only the latest value is written
+				opts.Progress(256, 2048)
+				opts.Progress(512, 2048)
+
+				Eventually(func() string {
+					nodeConfig := &ethernetv1.EthernetNodeConfig{}
+					Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), nodeConfig)).To(Succeed())
+					Expect(nodeConfig.Status.Conditions).To(HaveLen(1))
+					Expect(nodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateInProgress)))
+					progressMessage = nodeConfig.Status.Conditions[0].Message
+					return progressMessage
+				}, "5s", "100ms").Should(HaveSuffix("512/2048 bytes (25%)"))
+				return downloadErr
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progressMessage).To(Equal("Downloading testfwurl for device 0000:00:00.1: 512/2048 bytes (25%)"))
+		})
+
 		var _ = It("will call for reboot, expects ok and reboot", func() {
 			defer os.RemoveAll(artifactsFolder)
 
@@ -283,7 +462,7 @@ var _ = Describe("DaemonTests", func() {
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			downloadFile = func(localpath, url, checksum string, client *http.Client) error {
+			downloadFile = func(localpath, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
 
 				updateDir := path.Join(artifactsFolder, data.Inventory[0].PCIAddress)
 				updatePath := updateResultPath(updateDir)
@@ -345,27 +524,12 @@ var _ = Describe("DaemonTests", func() {
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			downloadFile = func(localpath, url, checksum string, client *http.Client) error {
-
-				updateDir := path.Join(artifactsFolder, data.Inventory[0].PCIAddress)
-				updatePath := updateResultPath(updateDir)
-
-				err := os.MkdirAll(updateDir, 0777)
-				Expect(err).ToNot(HaveOccurred())
-
-				tmpfile, err := os.OpenFile(updatePath, os.O_RDWR|os.O_CREATE, 0777)
-				Expect(err).ToNot(HaveOccurred())
-				defer tmpfile.Close()
-
-				nvmupdateOutput := `<?xml version="1.0" encoding="UTF-8"?>
-						   <DeviceUpdate lang="en">
-						           <RebootRequired> 0 </RebootRequired>
-						   </DeviceUpdate>`
-
-				_, err = tmpfile.Write([]byte(nvmupdateOutput))
-				Expect(err).ToNot(HaveOccurred())
+			downloadFile = func(localpath, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
+				return os.MkdirAll(path.Join(artifactsFolder, data.Inventory[0].PCIAddress), 0777)
+			}
 
-				return nil
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				return writeUpdateResult(cmd, 0)
 			}
 
 			wasRebootCalled := false
@@ -406,7 +570,7 @@ var _ = Describe("DaemonTests", func() {
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			downloadFile = func(localpath, url, checksum string, client *http.Client) error {
+			downloadFile = func(localpath, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
 
 				updateDir := path.Join(artifactsFolder, data.Inventory[0].PCIAddress)
 				updatePath := updateResultPath(updateDir)
@@ -489,14 +653,30 @@ var _ = Describe("DaemonTests", func() {
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(untarErr.Error()))
 		})
 
-		var _ = It("will update condition to UpdateFailed if firmware updater binary fails", func() {
+		var _ = It("will update condition to UpdateFailed if firmware signature verification fails", func() {
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWSignatureURL = "http://testfwurl.sig"
+			data.NodeConfig.Spec.Config[0].DeviceConfig.SignatureKeySecret = "signature-key"
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
+			secret := &core.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "signature-key", Namespace: data.NodeConfig.Namespace},
+				Data:       map[string][]byte{signatureKeySecretField: []byte("publickey")},
+			}
+			Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(context.TODO(), secret)).To(Succeed()) }()
+
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
-				return fmt.Errorf("FAILING NVME UPDATE BY PURPOSE")
+			untarCalled := false
+			untarFile = func(srcPath string, dstPath string, log logr.Logger) error {
+				untarCalled = true
+				return nil
+			}
+			verifyDetachedSignature = func(path, signaturePath string, publicKey []byte) error {
+				Expect(signaturePath).To(Equal(path + signatureFileSuffix))
+				Expect(publicKey).To(Equal([]byte("publickey")))
+				return gerrors.New("invalid signature")
 			}
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
@@ -506,6 +686,7 @@ var _ = Describe("DaemonTests", func() {
 				Name:      data.NodeConfig.Name,
 			}})
 			Expect(err).ToNot(HaveOccurred())
+			Expect(untarCalled).To(BeFalse())
 
 			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
@@ -513,19 +694,37 @@ var _ = Describe("DaemonTests", func() {
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(SatisfyAny(
-				ContainSubstring("FAILING NVME UPDATE BY PURPOSE")))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("invalid signature"))
 		})
 
-		var _ = It("will update condition to UpdateFailed if firmware update fails", func() {
+		var _ = It("will pull firmware as OCI artifact using pull secret", func() {
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = "oci://registry.local:5000/fw/e810:v1"
+			data.NodeConfig.Spec.Config[0].DeviceConfig.PullSecret = "registry-pull-secret"
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
+			secret := &core.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry-pull-secret", Namespace: data.NodeConfig.Namespace},
+				Type:       core.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					core.DockerConfigJsonKey: []byte(`{"auths": {"registry.local:5000": {"username": "user", "password": "pass"}}}`),
+				},
+			}
+			Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(context.TODO(), secret)).To(Succeed()) }()
+
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			fwErr := gerrors.New("unable to update firmware")
-			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
-				return fwErr
+			pullErr := gerrors.New("unable to pull")
+			downloadFile = func(path, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
+				Fail("HTTP download should not be used for OCI reference")
+				return nil
+			}
+			pullOCIArtifact = func(dstDir, ref, checksum string, creds *utils.RegistryCredentials, client *http.Client,
+				_ utils.OCIPullOptions) (string, error) {
+				Expect(ref).To(Equal("oci://registry.local:5000/fw/e810:v1"))
+				Expect(creds).To(Equal(&utils.RegistryCredentials{Username: "user", Password: "pass"}))
+				return "", pullErr
 			}
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
@@ -540,47 +739,18 @@ var _ = Describe("DaemonTests", func() {
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(fwErr.Error()))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(pullErr.Error()))
 		})
 
-		var _ = It("will update condition to UpdateSucceeded after successful firmware update", func() {
+		var _ = It("will update condition to UpdateFailed if firmware updater binary fails", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			rootAttr := &syscall.SysProcAttr{
-				Credential: &syscall.Credential{Uid: 0, Gid: 0},
-			}
 			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
-				Expect(cmd.SysProcAttr).To(Equal(rootAttr))
-				Expect(cmd.Dir).To(Equal(path.Join(artifactsFolder, data.NodeConfig.Spec.Config[0].PCIAddress)))
-				return nil
-			}
-
-			downloadFile = func(localpath, url, checksum string, client *http.Client) error {
-
-				updateDir := path.Join(artifactsFolder, data.Inventory[0].PCIAddress)
-				updatePath := updateResultPath(updateDir)
-
-				err := os.MkdirAll(updateDir, 0777)
-				Expect(err).ToNot(HaveOccurred())
-
-				tmpfile, err := os.OpenFile(updatePath, os.O_RDWR|os.O_CREATE, 0777)
-				Expect(err).ToNot(HaveOccurred())
-				defer tmpfile.Close()
-
-				nvmupdateOutput := `<?xml version="1.0" encoding="UTF-8"?>
-						   <DeviceUpdate lang="en">
-						           <RebootRequired> 0 </RebootRequired>
-						   </DeviceUpdate>`
-
-				_, err = tmpfile.Write([]byte(nvmupdateOutput))
-				Expect(err).ToNot(HaveOccurred())
-
-				return nil
+				return fmt.Errorf("FAILING NVME UPDATE BY PURPOSE")
 			}
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
@@ -595,36 +765,21 @@ var _ = Describe("DaemonTests", func() {
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionTrue))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal("Updated successfully"))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(SatisfyAny(
+				ContainSubstring("FAILING NVME UPDATE BY PURPOSE")))
 		})
 
-		var _ = It("will update update condition to UpdateFailed because of no MAC", func() {
+		var _ = It("will update condition to UpdateFailed if firmware update fails", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
-			data.Inventory[0].Firmware.MAC = ""
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			rootAttr := &syscall.SysProcAttr{
-				Credential: &syscall.Credential{Uid: 0, Gid: 0},
-			}
+			fwErr := gerrors.New("unable to update firmware")
 			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
-				Expect(cmd.SysProcAttr).To(Equal(rootAttr))
-				Expect(cmd.Dir).To(Equal(path.Join(artifactsFolder, data.NodeConfig.Spec.Config[0].PCIAddress)))
-				return nil
-			}
-
-			invCnt := 2
-
-			getInventory = func(_ logr.Logger) ([]ethernetv1.Device, error) {
-				if invCnt > 0 {
-					invCnt--
-					return data.Inventory, nil
-				} else {
-					return nil, nil
-				}
+				return fwErr
 			}
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
@@ -641,135 +796,897 @@ var _ = Describe("DaemonTests", func() {
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("failed to get MAC for device 0000:00:00.1. Device not found"))
-		})
-
-		var _ = It("if FWUrl is empty (empty device config) then FW update is skipped and status updated to NotRequested", func() {
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(fwErr.Error()))
 
-			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates).To(HaveLen(1))
+			deviceUpdate := nodeConfigs.Items[0].Status.DeviceUpdates[0]
+			Expect(deviceUpdate.PCIAddress).To(Equal("0000:00:00.1"))
+			Expect(deviceUpdate.Phase).To(Equal(DevicePhaseFailed))
+			Expect(deviceUpdate.LastError).To(Equal(fwErr.Error()))
+			Expect(deviceUpdate.ObservedFirmwareVersion).To(Equal(data.Inventory[0].Firmware.Version))
+			Expect(deviceUpdate.StartTime).ToNot(BeNil())
+			Expect(deviceUpdate.CompletionTime).ToNot(BeNil())
+		})
 
+		var _ = It("will restore saved NVM image and record rollback if firmware update fails", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			rootAttr := &syscall.SysProcAttr{
-				Credential: &syscall.Credential{Uid: 0, Gid: 0},
+			wasBackupCalled := false
+			wasRestoreCalled := false
+			fwErr := gerrors.New("unable to update firmware")
+			untarFile = func(srcPath string, dstPath string, log logr.Logger) error {
+				return writeNvmupdateTool(dstPath)
 			}
 			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
-				Expect(cmd.SysProcAttr).To(Equal(rootAttr))
-				Expect(cmd.Dir).To(Equal(path.Join(artifactsFolder, data.NodeConfig.Spec.Config[0].PCIAddress)))
+				switch {
+				case isNvmupdateUpdate(cmd):
+					return fwErr
+				case cmd.Args[1] == "-i":
+					wasBackupCalled = true
+					Expect(cmd.Args).To(ContainElement("-b"))
+					Expect(cmd.Dir).To(Equal(path.Join(nvmBackupFolder, data.Inventory[0].PCIAddress)))
+					return writeBackupImage(cmd)
+				default:
+					wasRestoreCalled = true
+					Expect(cmd.Args).To(ContainElement(path.Join(nvmBackupFolder, data.Inventory[0].PCIAddress)))
+				}
 				return nil
 			}
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
 
-			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
-				Namespace: data.NodeConfig.Namespace,
-				Name:      data.NodeConfig.Name,
-			}})
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
 			Expect(err).ToNot(HaveOccurred())
 
+			Expect(wasBackupCalled).To(BeTrue())
+			Expect(wasRestoreCalled).To(BeTrue())
+			Expect(path.Join(nvmBackupFolder, data.Inventory[0].PCIAddress)).ToNot(BeADirectory())
+
 			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateNotRequested)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal("Inventory up to date"))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(fwErr.Error()))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks[0].PCIAddress).To(Equal("0000:00:00.1"))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks[0].Result).To(Equal(RollbackSucceeded))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks[0].Message).To(ContainSubstring(fwErr.Error()))
 		})
 
-		var _ = It("will fail because of PCIAddress not matching pattern", func() {
-
+		var _ = It("will record unavailable rollback if NVM image could not be saved", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
-			// Valid pattern: ^[a-fA-F0-9]{2,4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$
-
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0:00:00.1"
-			err := k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
-
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:00.a"
-			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
-
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0:00:00"
-			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
-
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0:00:00"
-			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:20.1"
-			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				return gerrors.New("unable to run nvmupdate")
+			}
 
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:0.1"
-			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
 
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:0:00.1"
-			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
 
-			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:00.*"
-			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks[0].Result).To(Equal(RollbackUnavailable))
 		})
 
-		var _ = It("will update condition to UpdateFailed if not able to download DDP", func() {
+		var _ = It("will record unavailable rollback if NVM utility saved no image", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
-
-			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
-			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			downloadErr := gerrors.New("unable to download DDP")
-			downloadFile = func(path, url, checksum string, client *http.Client) error {
-				return downloadErr
+			wasRestoreCalled := false
+			fwErr := gerrors.New("unable to update firmware")
+			untarFile = func(srcPath string, dstPath string, log logr.Logger) error {
+				return writeNvmupdateTool(dstPath)
+			}
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				switch {
+				case isNvmupdateUpdate(cmd):
+					return fwErr
+				case cmd.Args[1] == "-u":
+					wasRestoreCalled = true
+				}
+				// backup exits with 0 leaving no image behind
+				return nil
 			}
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
 
-			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
-				Namespace: data.NodeConfig.Namespace,
-				Name:      data.NodeConfig.Name,
-			}})
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
 			Expect(err).ToNot(HaveOccurred())
 
+			Expect(wasRestoreCalled).To(BeFalse())
+			Expect(path.Join(nvmBackupFolder, data.Inventory[0].PCIAddress)).ToNot(BeADirectory())
+
 			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
-			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(downloadErr.Error()))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks[0].Result).To(Equal(RollbackUnavailable))
 		})
 
-		var _ = It("will update condition to UpdateFailed if not able to unpack DDP", func() {
+		var _ = It("will restore saved NVM image if device fails health check after reboot", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			// device is not found after the reboot
+			data.Inventory[0].PCIAddress = "0000:00:00.2"
+
+			now := metav1.Now()
+			data.NodeConfig.Status.Conditions = []metav1.Condition{{
+				Type:               UpdateCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             string(UpdatePostUpdateReboot),
+				Message:            "Post-update node reboot",
+				LastTransitionTime: now,
+			}}
+			Expect(k8sClient.Status().Update(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			backupPath := path.Join(nvmBackupFolder, "0000:00:00.1")
+			Expect(writeNvmupdateTool(backupPath)).To(Succeed())
+			defer os.RemoveAll(nvmBackupFolder)
+
+			var restoreCmd *exec.Cmd
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				restoreCmd = cmd
+				return nil
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(restoreCmd).ToNot(BeNil())
+			Expect(restoreCmd.Path).To(Equal(path.Join(backupPath, nvmupdate64e)))
+			Expect(restoreCmd.Args).To(ContainElements("-u", "-a", backupPath))
+			Expect(backupPath).ToNot(BeADirectory())
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("not found after update"))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks[0].PCIAddress).To(Equal("0000:00:00.1"))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks[0].Result).To(Equal(RollbackSucceeded))
+		})
+
+		var _ = It("will keep saved NVM image until the device is checked after reboot", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+			defer os.RemoveAll(nvmBackupFolder)
+
+			untarFile = func(srcPath string, dstPath string, log logr.Logger) error {
+				return writeNvmupdateTool(dstPath)
+			}
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				if err := writeBackupImage(cmd); err != nil {
+					return err
+				}
+				return writeUpdateResult(cmd, 1)
+			}
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				return "", nil
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+
+			backupPath := path.Join(nvmBackupFolder, "0000:00:00.1")
+			Expect(path.Join(backupPath, nvmupdate64e)).To(BeARegularFile())
+			Expect(nvmupdate64eCfgPath(backupPath)).To(BeARegularFile())
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdatePostUpdateReboot)))
+			Expect(nodeConfigs.Items[0].Status.FirmwareRollbacks).To(BeEmpty())
+		})
+
+		var _ = It("will update condition to UpdateSucceeded after successful firmware update", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			rootAttr := &syscall.SysProcAttr{
+				Credential: &syscall.Credential{Uid: 0, Gid: 0},
+			}
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				Expect(cmd.SysProcAttr).To(Equal(rootAttr))
+				if isNvmupdateUpdate(cmd) {
+					Expect(cmd.Dir).To(Equal(path.Join(artifactsFolder, data.NodeConfig.Spec.Config[0].PCIAddress)))
+				}
+				return writeUpdateResult(cmd, 0)
+			}
+
+			downloadFile = func(localpath, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
+				return os.MkdirAll(path.Join(artifactsFolder, data.Inventory[0].PCIAddress), 0777)
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			attempts := testutil.ToFloat64(updateAttempts)
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testutil.ToFloat64(updateAttempts) - attempts).To(Equal(1.0))
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionTrue))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal("Updated successfully"))
+
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseSucceeded))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].LastError).To(BeEmpty())
+		})
+
+		var _ = It("will publish update plan without flashing device configured with dry run", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			data.NodeConfig.Spec.Config[0].DryRun = true
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			var commands [][]string
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				commands = append(commands, cmd.Args)
+				inventory := `<DeviceInventory><Instance><Module type="NVM" version="800049C3" update="1"></Module></Instance></DeviceInventory>`
+				return os.WriteFile(inventoryResultPath(cmd.Dir), []byte(inventory), 0644)
+			}
+			findFw = func(localpath string) (string, error) { return localpath, nil }
+
+			wasRebootCalled := false
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				wasRebootCalled = wasRebootCalled || strings.Contains(strings.Join(args, " "), "reboot")
+				return "", nil
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(wasRebootCalled).To(BeFalse())
+			Expect(commands).To(HaveLen(1))
+			Expect(commands[0][1]).To(Equal("-i"))
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateDryRun)))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates).To(BeEmpty())
+
+			plan := nodeConfigs.Items[0].Status.UpdatePlan
+			Expect(plan).ToNot(BeNil())
+			Expect(plan.Complete).To(BeTrue())
+			Expect(plan.ObservedGeneration).To(Equal(nodeConfigs.Items[0].Generation))
+			Expect(plan.DrainRequired).To(BeTrue())
+			Expect(plan.RebootRequired).To(BeTrue())
+			Expect(plan.Devices).To(HaveLen(1))
+			Expect(plan.Devices[0].PCIAddress).To(Equal("0000:00:00.1"))
+			Expect(plan.Devices[0].FirmwareUpdate).To(BeTrue())
+			Expect(plan.Devices[0].DDPUpdate).To(BeFalse())
+			Expect(plan.Devices[0].CurrentFirmwareVersion).To(Equal("TestFWVersion"))
+		})
+
+		var _ = It("will update update condition to UpdateFailed because of no MAC", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].Firmware.MAC = ""
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			rootAttr := &syscall.SysProcAttr{
+				Credential: &syscall.Credential{Uid: 0, Gid: 0},
+			}
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				Expect(cmd.SysProcAttr).To(Equal(rootAttr))
+				if isNvmupdateUpdate(cmd) {
+					Expect(cmd.Dir).To(Equal(path.Join(artifactsFolder, data.NodeConfig.Spec.Config[0].PCIAddress)))
+				}
+				return nil
+			}
+
+			invCnt := 2
+
+			getInventory = func(_ logr.Logger) ([]ethernetv1.Device, error) {
+				if invCnt > 0 {
+					invCnt--
+					return data.Inventory, nil
+				} else {
+					return nil, nil
+				}
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("failed to get MAC for device 0000:00:00.1. Device not found"))
+		})
+
+		var _ = It("if FWUrl is empty (empty device config) then FW update is skipped and status updated to NotRequested", func() {
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			rootAttr := &syscall.SysProcAttr{
+				Credential: &syscall.Credential{Uid: 0, Gid: 0},
+			}
+			nvmupdateExec = func(cmd *exec.Cmd, log logr.Logger) error {
+				Expect(cmd.SysProcAttr).To(Equal(rootAttr))
+				if isNvmupdateUpdate(cmd) {
+					Expect(cmd.Dir).To(Equal(path.Join(artifactsFolder, data.NodeConfig.Spec.Config[0].PCIAddress)))
+				}
+				return nil
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateNotRequested)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal("Inventory up to date"))
+		})
+
+		var _ = It("will fail because of PCIAddress not matching pattern", func() {
+
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			// Valid pattern: ^[a-fA-F0-9]{2,4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0:00:00.1"
+			err := k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:00.a"
+			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0:00:00"
+			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0:00:00"
+			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:20.1"
+			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:0.1"
+			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:0:00.1"
+			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+
+			data.NodeConfig.Spec.Config[0].PCIAddress = "0000:00:00.*"
+			err = k8sClient.Create(context.TODO(), &data.NodeConfig)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.config[0].PCIAddress: Invalid value:"))
+		})
+
+		var _ = It("will update condition to UpdateFailed if not able to download DDP", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			downloadErr := gerrors.New("unable to download DDP")
+			downloadFile = func(path, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
+				return downloadErr
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(downloadErr.Error()))
+		})
+
+		var _ = It("will update condition to UpdateFailed if not able to unpack DDP", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			unzipErr := gerrors.New("unable to unzip")
+			unpackDDPArchive = func(srcPath string, dstPath string, log logr.Logger) error {
+				return unzipErr
+			}
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(unzipErr.Error()))
+		})
+
+		var _ = It("will update condition to UpdateFailed if DDP update fails", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("expected to find exactly 1 file ending with '.pkg', but found 0"))
+		})
+
+		var _ = It("will force reboot node on successful DDP update", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			tempFile, err := os.CreateTemp("/tmp", "daemontest")
+			Expect(err).To(Succeed())
+			defer tempFile.Close()
+
+			findDdp = func(targetPath string) (string, error) {
+				return tempFile.Name(), nil
+			}
+
+			wasRebootCalled := false
+
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				for _, part := range args {
+					if strings.Contains(part, "reboot") {
+						wasRebootCalled = true
+					}
+				}
+				return "", nil
+			}
+
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(wasRebootCalled).To(Equal(true))
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdatePostUpdateReboot)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal("Post-update node reboot"))
+		})
+
+		var _ = It("will wait for approval annotation before reboot with Deferred reboot strategy", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 
 			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
 			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			data.NodeConfig.Spec.Config[0].RebootStrategy = RebootStrategyDeferred
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
 
-			unzipErr := gerrors.New("unable to unzip")
-			unpackDDPArchive = func(srcPath string, dstPath string, log logr.Logger) error {
-				return unzipErr
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			bootID, err := os.CreateTemp("/tmp", "bootid")
+			Expect(err).To(Succeed())
+			defer os.Remove(bootID.Name())
+			Expect(os.WriteFile(bootID.Name(), []byte("boot-1\n"), 0600)).To(Succeed())
+			bootIDPath = bootID.Name()
+			defer func() { bootIDPath = "/proc/sys/kernel/random/boot_id" }()
+
+			tempFile, err := os.CreateTemp("/tmp", "daemontest")
+			Expect(err).To(Succeed())
+			defer tempFile.Close()
+			findDdp = func(targetPath string) (string, error) {
+				return tempFile.Name(), nil
+			}
+
+			wasRebootCalled := false
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				for _, part := range args {
+					if strings.Contains(part, "reboot") {
+						wasRebootCalled = true
+					}
+				}
+				return "", nil
+			}
+
+			reconcile := func() {
+				_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
+			}
+
+			reconcile()
+			Expect(wasRebootCalled).To(BeFalse())
+			Expect(data.NodeConfig.Status.Conditions).To(HaveLen(1))
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateRebootPending)))
+			Expect(data.NodeConfig.Status.Reboot).ToNot(BeNil())
+			Expect(data.NodeConfig.Status.Reboot.Strategy).To(Equal(RebootStrategyDeferred))
+			Expect(data.NodeConfig.Status.Reboot.Pending).To(BeTrue())
+			Expect(data.NodeConfig.Status.Reboot.BootID).To(Equal("boot-1"))
+			Expect(data.NodeConfig.Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseRebooting))
+
+			// still waiting for approval
+			reconcile()
+			Expect(wasRebootCalled).To(BeFalse())
+
+			data.NodeConfig.Annotations = map[string]string{RebootApprovedAnnotation: "true"}
+			Expect(k8sClient.Update(context.TODO(), &data.NodeConfig)).To(Succeed())
+			reconcile()
+			Expect(wasRebootCalled).To(BeTrue())
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdatePostUpdateReboot)))
+		})
+
+		var _ = It("will finish update once node is rebooted externally with External reboot strategy", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			data.NodeConfig.Spec.Config[0].RebootStrategy = RebootStrategyExternal
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			bootID, err := os.CreateTemp("/tmp", "bootid")
+			Expect(err).To(Succeed())
+			defer os.Remove(bootID.Name())
+			Expect(os.WriteFile(bootID.Name(), []byte("boot-1\n"), 0600)).To(Succeed())
+			bootIDPath = bootID.Name()
+			defer func() { bootIDPath = "/proc/sys/kernel/random/boot_id" }()
+
+			tempFile, err := os.CreateTemp("/tmp", "daemontest")
+			Expect(err).To(Succeed())
+			defer tempFile.Close()
+			findDdp = func(targetPath string) (string, error) {
+				return tempFile.Name(), nil
+			}
+
+			wasRebootCalled := false
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				for _, part := range args {
+					if strings.Contains(part, "reboot") {
+						wasRebootCalled = true
+					}
+				}
+				return "", nil
+			}
+
+			reconcile := func() {
+				_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
+			}
+
+			reconcile()
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateRebootRequired)))
+			Expect(data.NodeConfig.Status.Reboot.Strategy).To(Equal(RebootStrategyExternal))
+			Expect(data.NodeConfig.Status.Reboot.Pending).To(BeTrue())
+
+			// node keeps holding the drain while waiting for the reboot
+			node := &core.Node{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: data.Node.Name}, node)).To(Succeed())
+			Expect(node.Spec.Unschedulable).To(BeTrue())
+			Expect(node.Annotations).To(HaveKey(dh.DrainHeldAnnotation))
+
+			Expect(os.WriteFile(bootID.Name(), []byte("boot-2\n"), 0600)).To(Succeed())
+			reconcile()
+			Expect(wasRebootCalled).To(BeFalse())
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+			Expect(data.NodeConfig.Status.Reboot.Pending).To(BeFalse())
+			Expect(data.NodeConfig.Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseSucceeded))
+
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: data.Node.Name}, node)).To(Succeed())
+			Expect(node.Spec.Unschedulable).To(BeFalse())
+			Expect(node.Annotations).ToNot(HaveKey(dh.DrainHeldAnnotation))
+		})
+
+		var _ = It("will postpone update while other node holds the drain", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			otherNode := &core.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:        "other-node",
+				Annotations: map[string]string{dh.DrainHeldAnnotation: "true"},
+			}}
+			otherNode.Spec.Unschedulable = true
+			Expect(k8sClient.Create(context.TODO(), otherNode)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(context.TODO(), otherNode)).To(Succeed()) }()
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			tempFile, err := os.CreateTemp("/tmp", "daemontest")
+			Expect(err).To(Succeed())
+			defer tempFile.Close()
+			findDdp = func(targetPath string) (string, error) {
+				return tempFile.Name(), nil
+			}
+
+			wasDDPUpdated := false
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				wasDDPUpdated = true
+				return "", nil
+			}
+
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
+
+			Expect(wasDDPUpdated).To(BeFalse())
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateDeferred)))
+			Expect(data.NodeConfig.Status.Conditions[0].Message).To(ContainSubstring("other-node"))
+
+			node := &core.Node{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: data.Node.Name}, node)).To(Succeed())
+			Expect(node.Spec.Unschedulable).To(BeFalse())
+		})
+
+		var _ = It("will not prepare packages again while update is deferred by maintenance window", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			data.NodeConfig.Spec.Config[0].MaintenanceWindow = &ethernetv1.MaintenanceWindow{
+				Schedule: "0 22 * * *",
+				Duration: metav1.Duration{Duration: 4 * time.Hour},
+			}
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+			defer func() { timeNow = time.Now }()
+
+			tempFile, err := os.CreateTemp("/tmp", "daemontest")
+			Expect(err).To(Succeed())
+			defer tempFile.Close()
+			findDdp = func(targetPath string) (string, error) {
+				return tempFile.Name(), nil
+			}
+			downloads := 0
+			downloadFile = func(path, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
+				downloads++
+				return nil
+			}
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				return "", nil
+			}
+
+			reconcile := func() {
+				_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
+			}
+
+			timeNow = func() time.Time { return time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC) }
+			reconcile()
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateDeferred)))
+			Expect(downloads).To(Equal(1))
+
+			timeNow = func() time.Time { return time.Date(2023, 7, 15, 13, 0, 0, 0, time.UTC) }
+			reconcile()
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateDeferred)))
+			Expect(downloads).To(Equal(1))
+
+			timeNow = func() time.Time { return time.Date(2023, 7, 15, 22, 0, 0, 0, time.UTC) }
+			reconcile()
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdatePostUpdateReboot)))
+			Expect(downloads).To(Equal(2))
+		})
+
+		var _ = It("will not flash raw NVM image again once the device runs it", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.DrainSkip = true
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = "http://testfwurl/E810_NVM_v4_40.bin"
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWUpdateBackend = FWUpdateBackendDevlinkFlash
+			data.NodeConfig.Spec.Config[0].RebootStrategy = RebootStrategyExternal
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+			data.Inventory[0].Firmware.Version = "4.20 0x80008270 1.3236.0"
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			bootID, err := os.CreateTemp("/tmp", "bootid")
+			Expect(err).To(Succeed())
+			defer os.Remove(bootID.Name())
+			Expect(os.WriteFile(bootID.Name(), []byte("boot-1\n"), 0600)).To(Succeed())
+			bootIDPath = bootID.Name()
+			defer func() { bootIDPath = "/proc/sys/kernel/random/boot_id" }()
+
+			origSearchPath, origFlash, origProvider := firmwareSearchPath, devlinkFlash, newDeviceInfoProvider
+			defer func() {
+				firmwareSearchPath, devlinkFlash, newDeviceInfoProvider = origSearchPath, origFlash, origProvider
+			}()
+			firmwareSearchPath = GinkgoT().TempDir()
+			downloadFile = func(localpath, url, checksum string, client *http.Client, _ utils.DownloadOptions) error {
+				Expect(os.MkdirAll(filepath.Dir(localpath), 0755)).To(Succeed())
+				return os.WriteFile(localpath, []byte("nvm image"), 0600)
+			}
+			newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+				return &fakeInfoProvider{devlink: func(string) (*devlinkDeviceInfo, error) {
+					return &devlinkDeviceInfo{stored: map[string]string{"fw.bundle_id": "0x80008271"}}, nil
+				}}
+			}
+			flashes := 0
+			devlinkFlash = func(string, string, string, uint32, time.Duration) error {
+				flashes++
+				return nil
+			}
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				return "", nil
+			}
+
+			reconcile := func() {
+				_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
 			}
 
+			reconcile()
+			Expect(flashes).To(Equal(1))
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateRebootRequired)))
+			Expect(data.NodeConfig.Status.FlashedImages).To(HaveLen(1))
+			Expect(data.NodeConfig.Status.FlashedImages[0].PCIAddress).To(Equal("0000:00:00.1"))
+			Expect(data.NodeConfig.Status.FlashedImages[0].Checksum).To(HavePrefix("sha256:"))
+			Expect(data.NodeConfig.Status.FlashedImages[0].Version).To(Equal("0x80008271"))
+
+			// reboot activates flashed image
+			data.Inventory[0].Firmware.Version = "4.40 0x80008271 1.3429.0"
+			Expect(os.WriteFile(bootID.Name(), []byte("boot-2\n"), 0600)).To(Succeed())
+			reconcile()
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+
+			reconcile()
+			Expect(flashes).To(Equal(1))
+			Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+			Expect(data.NodeConfig.Status.Conditions[0].Message).To(Equal("Devices already up to date"))
+			Expect(data.NodeConfig.Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseUpToDate))
+			Expect(data.NodeConfig.Status.DeviceUpdates[0].TargetFirmwareVersion).To(Equal("0x80008271"))
+		})
+
+		var _ = It("will fail update if DDP version reported after reboot does not match applied one", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
+			data.NodeConfig.Spec.Config[0].DeviceConfig.KeepCordonedOnVersionMismatch = true
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+			data.Inventory[0].DDP.Version = "1.3.30.0"
+
+			now := metav1.Now()
+			data.NodeConfig.Status.Conditions = []metav1.Condition{{
+				Type:               UpdateCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             string(UpdatePostUpdateReboot),
+				Message:            "Post-update node reboot",
+				LastTransitionTime: now,
+			}}
+			data.NodeConfig.Status.DeviceUpdates = []ethernetv1.DeviceUpdateStatus{{
+				PCIAddress:         "0000:00:00.1",
+				Phase:              DevicePhaseRebooting,
+				TargetDDPVersion:   "1.3.35.0",
+				StartTime:          &now,
+				LastTransitionTime: now,
+			}}
+			Expect(k8sClient.Status().Update(context.TODO(), &data.NodeConfig)).To(Succeed())
+
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
 
 			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
@@ -782,38 +1699,182 @@ var _ = Describe("DaemonTests", func() {
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal(unzipErr.Error()))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateVersionMismatch)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("DDP 1.3.35.0 expected"))
+
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseFailed))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].ObservedDDPVersion).To(Equal("1.3.30.0"))
 		})
 
-		var _ = It("will update condition to UpdateFailed if DDP update fails", func() {
+		var _ = Context("devlink params", func() {
+			origSet, origReload, origProvider := setDevlinkParam, reloadDevlink, newDeviceInfoProvider
+			var set []string
+			var reloaded bool
+			// device keeps values of params set by setDevlinkParam
+			var device []devlinkParam
+
+			BeforeEach(func() {
+				set, reloaded = nil, false
+				device = []devlinkParam{
+					{name: "enable_roce", typ: devlinkParamTypeBool, values: map[string]string{"runtime": "false"}},
+					{name: "msix_vec_per_pf_max", typ: devlinkParamTypeU32, values: map[string]string{"driverinit": "64"}},
+					{name: "tx_scheduling_layers", typ: devlinkParamTypeU8, values: map[string]string{"permanent": "5"}},
+				}
+				setDevlinkParam = func(pciAddr string, change devlinkParamChange) error {
+					set = append(set, change.Name)
+					for _, p := range device {
+						if p.name == change.Name {
+							p.values[change.CMode] = change.Value
+						}
+					}
+					return nil
+				}
+				reloadDevlink = func(string) error {
+					reloaded = true
+					return nil
+				}
+				newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+					return &fakeInfoProvider{params: func(string) ([]devlinkParam, error) {
+						return device, nil
+					}}
+				}
+
+				data.Inventory[0].PCIAddress = "0000:00:00.1"
+				data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
+				data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = ""
+			})
+
+			AfterEach(func() {
+				setDevlinkParam, reloadDevlink, newDeviceInfoProvider = origSet, origReload, origProvider
+				timeNow = time.Now
+			})
+
+			var _ = It("will set runtime values only once maintenance window allows the update", func() {
+				Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+				data.NodeConfig.Spec.Config[0].DeviceConfig.DevlinkParams = []ethernetv1.DevlinkParam{
+					{Name: "enable_roce", Value: "true", CMode: DevlinkParamCModeRuntime},
+				}
+				data.NodeConfig.Spec.Config[0].MaintenanceWindow = &ethernetv1.MaintenanceWindow{
+					Schedule: "0 22 * * *",
+					Duration: metav1.Duration{Duration: 4 * time.Hour},
+				}
+				Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+				Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+				reconcile := func() {
+					_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
+				}
+
+				timeNow = func() time.Time { return time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC) }
+				reconcile()
+				Expect(set).To(BeEmpty())
+				Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateDeferred)))
+
+				timeNow = func() time.Time { return time.Date(2023, 7, 15, 22, 0, 0, 0, time.UTC) }
+				reconcile()
+				Expect(set).To(Equal([]string{"enable_roce"}))
+				Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+				Expect(data.NodeConfig.Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseSucceeded))
+			})
+
+			var _ = It("will set values reset by the reboot without another update pass", func() {
+				Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+				data.NodeConfig.Spec.Config[0].DeviceConfig.DevlinkParams = []ethernetv1.DevlinkParam{
+					{Name: "enable_roce", Value: "true", CMode: DevlinkParamCModeRuntime},
+					{Name: "msix_vec_per_pf_max", Value: "32", CMode: DevlinkParamCModeDriverinit},
+					{Name: "tx_scheduling_layers", Value: "5", CMode: DevlinkParamCModePermanent},
+				}
+				Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+				now := metav1.Now()
+				data.NodeConfig.Status.Conditions = []metav1.Condition{{
+					Type:               UpdateCondition,
+					Status:             metav1.ConditionFalse,
+					Reason:             string(UpdatePostUpdateReboot),
+					Message:            "Post-update node reboot",
+					LastTransitionTime: now,
+				}}
+				data.NodeConfig.Status.DeviceUpdates = []ethernetv1.DeviceUpdateStatus{{
+					PCIAddress:         "0000:00:00.1",
+					Phase:              DevicePhaseRebooting,
+					StartTime:          &now,
+					LastTransitionTime: now,
+				}}
+				Expect(k8sClient.Status().Update(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+				Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+				result, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: data.GetNamespacedName()})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(ctrl.Result{}))
+				Expect(set).To(Equal([]string{"enable_roce", "msix_vec_per_pf_max"}))
+				Expect(reloaded).To(BeTrue())
+
+				Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
+				Expect(data.NodeConfig.Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+				Expect(data.NodeConfig.Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseSucceeded))
+			})
+		})
+
+		var _ = It("will refuse DDP update not allowed by compatibility map", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 
 			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
 			data.NodeConfig.Spec.Config[0].DeviceConfig.DDPURL = "http://testddpurl"
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
+			// matches E810-B entry of testdata/supported_devices.json
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
+			data.Inventory[0].VendorID = "0001"
+			data.Inventory[0].DeviceID = "321"
+			data.Inventory[0].Driver = "ice"
+			data.Inventory[0].DriverVersion = "1.1.1"
+			data.Inventory[0].Firmware.Version = "3.00 0x80008271 1.2992.0"
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
 
-			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+			tempFile, err := os.CreateTemp("/tmp", "daemontest")
+			Expect(err).To(Succeed())
+			defer os.Remove(tempFile.Name())
+			_, err = tempFile.Write(testDDPPackage([4]byte{1, 3, 30, 0}))
+			Expect(err).To(Succeed())
+			Expect(tempFile.Close()).To(Succeed())
+
+			findDdp = func(targetPath string) (string, error) {
+				return tempFile.Name(), nil
+			}
+
+			var executed []string
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				executed = append(executed, strings.Join(args, " "))
+				return "", nil
+			}
+
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
 				Namespace: data.NodeConfig.Namespace,
 				Name:      data.NodeConfig.Name,
 			}})
 			Expect(err).ToNot(HaveOccurred())
+			Expect(executed).To(BeEmpty())
 
 			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateFailed)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("expected to find exactly 1 file ending with '.pkg', but found 0"))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateIncompatible)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(ContainSubstring("firmware 0x80008271 and DDP 1.3.30.0"))
+
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseFailed))
 		})
 
-		var _ = It("will force reboot node on successful DDP update", func() {
+		var _ = It("will skip DDP update without reboot if device already runs requested DDP version", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 
 			data.NodeConfig.Spec.Config[0].DeviceConfig.FWURL = ""
@@ -821,45 +1882,119 @@ var _ = Describe("DaemonTests", func() {
 			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
 
 			data.Inventory[0].PCIAddress = "0000:00:00.1"
+			data.Inventory[0].DDP.Version = "1.3.30.0"
 
 			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
 
 			tempFile, err := os.CreateTemp("/tmp", "daemontest")
 			Expect(err).To(Succeed())
-			defer tempFile.Close()
+			defer os.Remove(tempFile.Name())
+			_, err = tempFile.Write(testDDPPackage([4]byte{1, 3, 30, 0}))
+			Expect(err).To(Succeed())
+			Expect(tempFile.Close()).To(Succeed())
 
 			findDdp = func(targetPath string) (string, error) {
 				return tempFile.Name(), nil
 			}
 
-			wasRebootCalled := false
-
+			var executed []string
 			execCmd = func(args []string, log logr.Logger) (string, error) {
-				for _, part := range args {
-					if strings.Contains(part, "reboot") {
-						wasRebootCalled = true
-					}
-					if strings.Contains(part, "Device Serial") {
-						return "devId\n", nil
-					}
-				}
+				executed = append(executed, strings.Join(args, " "))
 				return "", nil
 			}
+			serialRead := false
+			readDeviceSerialNumber = func(string) (uint64, error) {
+				serialRead = true
+				return 0xb49691ffffaf6d68, nil
+			}
 
+			attempts := testutil.ToFloat64(updateAttempts)
 			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
 				Namespace: data.NodeConfig.Namespace,
 				Name:      data.NodeConfig.Name,
 			}})
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(wasRebootCalled).To(Equal(true))
+			// neither DDP copy (which reads device serial) nor reboot was run
+			Expect(serialRead).To(BeFalse())
+			Expect(executed).To(BeEmpty())
+			// device which is up to date is not counted as update attempt
+			Expect(testutil.ToFloat64(updateAttempts)).To(Equal(attempts))
 
 			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal("Devices already up to date"))
+
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseUpToDate))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].TargetDDPVersion).To(Equal("1.3.30.0"))
+		})
+
+		var _ = It("will remove DDP package applied to the device and reboot node on DDP revert", func() {
+			Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
+
+			data.NodeConfig.Spec.Config[0].DeviceConfig = ethernetv1.DeviceConfig{DDPRevert: true}
+			Expect(k8sClient.Create(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			data.Inventory[0].PCIAddress = "0000:00:00.1"
+
+			Expect(initReconciler(reconciler, data.NodeConfig.Name, data.NodeConfig.Namespace)).To(Succeed())
+
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				return "", nil
+			}
+			packages, err := reconciler.ddpUpdater.ddpPackagePaths("0000:00:00.1")
+			Expect(err).To(Succeed())
+			for _, p := range packages {
+				Expect(os.MkdirAll(filepath.Dir(p), 0700)).To(Succeed())
+				Expect(os.WriteFile(p, []byte("ddp"), 0600)).To(Succeed())
+			}
+
+			wasRebootCalled := false
+			execCmd = func(args []string, log logr.Logger) (string, error) {
+				cmd := strings.Join(args, " ")
+				if strings.Contains(cmd, "reboot") {
+					wasRebootCalled = true
+				}
+				return "", nil
+			}
+
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(wasRebootCalled).To(BeTrue())
+			for _, p := range packages {
+				Expect(p).ToNot(BeAnExistingFile())
+			}
+
+			nodeConfigs := &ethernetv1.EthernetNodeConfigList{}
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items).To(HaveLen(1))
 			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdatePostUpdateReboot)))
-			Expect(nodeConfigs.Items[0].Status.Conditions[0].Message).To(Equal("Post-update node reboot"))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseRebooting))
+
+			// nothing is left to revert, so the following reconcile does not reboot again
+			wasRebootCalled = false
+			Expect(k8sClient.Get(context.TODO(), data.GetNamespacedName(), &data.NodeConfig)).To(Succeed())
+			data.NodeConfig.Status.Conditions = nil
+			Expect(k8sClient.Status().Update(context.TODO(), &data.NodeConfig)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: data.NodeConfig.Namespace,
+				Name:      data.NodeConfig.Name,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(wasRebootCalled).To(BeFalse())
+
+			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
+			Expect(nodeConfigs.Items[0].Status.Conditions[0].Reason).To(Equal(string(UpdateSucceeded)))
+			Expect(nodeConfigs.Items[0].Status.DeviceUpdates[0].Phase).To(Equal(DevicePhaseUpToDate))
 		})
 	})
 })
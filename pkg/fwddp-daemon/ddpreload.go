@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+const (
+	DDPApplyStrategyReboot       = "Reboot"
+	DDPApplyStrategyDriverReload = "DriverReload"
+
+	ddpReloadTimeoutEnvVarName = "DDP_RELOAD_TIMEOUT_SECONDS"
+	ddpReloadTimeoutDefault    = int64(120)
+)
+
+var (
+	iceDriverPath            = "/sys/bus/pci/drivers/ice"
+	driverReloadPollInterval = time.Second
+)
+
+// reloadDriver makes ice driver load the DDP package copied for the device by rebinding all physical functions
+// of the adapter, as the package is downloaded to the adapter when its first function is probed. Once netdevs
+// of the functions are back, VFs removed by the rebind are created again and devlink is expected to report
+// targetVersion as the loaded package. If targetVersion is unknown, devlink is expected to report a version
+// other than the one loaded before the reload
+func (d *ddpUpdater) reloadDriver(pciAddr, targetVersion string) error {
+	log := d.log.WithName("reloadDriver").WithValues("device", pciAddr)
+
+	previousVersion := d.loadedDDPVersion(pciAddr)
+	if targetVersion != "" && previousVersion == targetVersion {
+		log.V(2).Info("DDP package already loaded, e.g. by reload of another function", "version", targetVersion)
+		return nil
+	}
+
+	functions, err := adapterFunctions(pciAddr)
+	if err != nil {
+		return err
+	}
+	// unbinding a function removes its VFs, the number of them is recorded to create them again
+	numVFs := make(map[string]int)
+	for _, f := range functions {
+		if n, err := readSysfsInt(filepath.Join(pciDevicesPath, f, "sriov_numvfs")); err == nil && n > 0 {
+			numVFs[f] = n
+		}
+	}
+	log.V(2).Info("Rebinding ice driver", "functions", functions, "numVFs", numVFs)
+
+	var unbound []string
+	var reloadErr error
+	for _, f := range functions {
+		if err := os.WriteFile(filepath.Join(iceDriverPath, "unbind"), []byte(f), 0200); err != nil {
+			reloadErr = fmt.Errorf("failed to unbind %v from ice driver: %v", f, err)
+			break
+		}
+		unbound = append(unbound, f)
+	}
+	// functions are bound back even if unbinding failed, so the device is not left without driver
+	for _, f := range unbound {
+		if err := os.WriteFile(filepath.Join(iceDriverPath, "bind"), []byte(f), 0200); err != nil && reloadErr == nil {
+			reloadErr = fmt.Errorf("failed to bind %v to ice driver: %v", f, err)
+		}
+	}
+	if reloadErr != nil {
+		return reloadErr
+	}
+
+	deadline := time.Now().Add(d.reloadTimeout)
+	for _, f := range functions {
+		for !hasNetdev(f) {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("netdev of %v did not come back within %v after ice driver reload", f, d.reloadTimeout)
+			}
+			time.Sleep(driverReloadPollInterval)
+		}
+	}
+
+	for _, f := range functions {
+		if numVFs[f] == 0 {
+			continue
+		}
+		err := os.WriteFile(filepath.Join(pciDevicesPath, f, "sriov_numvfs"), []byte(strconv.Itoa(numVFs[f])), 0200)
+		if err != nil {
+			return fmt.Errorf("failed to restore %v VFs of %v after ice driver reload: %v", numVFs[f], f, err)
+		}
+		log.V(2).Info("VFs restored", "function", f, "numVFs", numVFs[f])
+	}
+
+	loaded := d.loadedDDPVersion(pciAddr)
+	if targetVersion == "" {
+		if loaded == "" || loaded == previousVersion {
+			return fmt.Errorf("DDP package not changed by ice driver reload, devlink reports %q", loaded)
+		}
+		log.Info("Target DDP version unknown, other package loaded", "previous", previousVersion, "version", loaded)
+		return nil
+	}
+	if loaded != targetVersion {
+		return fmt.Errorf("DDP package %v not loaded after ice driver reload, devlink reports %q", targetVersion, loaded)
+	}
+	log.V(2).Info("DDP package loaded", "version", targetVersion)
+	return nil
+}
+
+func (d *ddpUpdater) loadedDDPVersion(pciAddr string) string {
+	device := ethernetv1.Device{PCIAddress: pciAddr}
+	addDevlinkInfo(d.log, newDeviceInfoProvider(d.log), &device)
+	return device.DDP.Version
+}
+
+// adapterFunctions returns PCI addresses of functions bound to ice driver which share domain, bus and device
+// number with pciAddr, i.e. physical functions of the same adapter
+func adapterFunctions(pciAddr string) ([]string, error) {
+	idx := strings.LastIndex(pciAddr, ".")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid PCI address %v", pciAddr)
+	}
+	slot := pciAddr[:idx+1]
+
+	entries, err := os.ReadDir(iceDriverPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var functions []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), slot) {
+			functions = append(functions, e.Name())
+		}
+	}
+	if len(functions) == 0 {
+		return nil, errors.New("device " + pciAddr + " is not bound to ice driver")
+	}
+	return functions, nil
+}
+
+func hasNetdev(pciAddr string) bool {
+	entries, err := os.ReadDir(filepath.Join(pciDevicesPath, pciAddr, "net"))
+	return err == nil && len(entries) > 0
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("reloadDriver", func() {
+	var (
+		updater         *ddpUpdater
+		loadedVersions  []string
+		devlinkCalls    int
+		origDriverPath  string
+		origDevicesPath string
+		origProvider    = newDeviceInfoProvider
+	)
+
+	BeforeEach(func() {
+		origDriverPath, origDevicesPath = iceDriverPath, pciDevicesPath
+		iceDriverPath = GinkgoT().TempDir()
+		pciDevicesPath = GinkgoT().TempDir()
+		driverReloadPollInterval = time.Millisecond
+
+		for _, f := range []string{"bind", "unbind"} {
+			Expect(os.WriteFile(filepath.Join(iceDriverPath, f), nil, 0600)).To(Succeed())
+		}
+		for _, f := range []string{"0000:18:00.0", "0000:18:00.1", "0000:19:00.0"} {
+			Expect(os.Mkdir(filepath.Join(iceDriverPath, f), 0700)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(pciDevicesPath, f, "net", "eth0"), 0700)).To(Succeed())
+		}
+
+		devlinkCalls = 0
+		loadedVersions = []string{"1.3.30.0", "1.3.35.0"}
+		newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+			return &fakeInfoProvider{devlink: func(string) (*devlinkDeviceInfo, error) {
+				v := loadedVersions[len(loadedVersions)-1]
+				if devlinkCalls < len(loadedVersions) {
+					v = loadedVersions[devlinkCalls]
+				}
+				devlinkCalls++
+				return &devlinkDeviceInfo{running: map[string]string{"fw.app": v}}, nil
+			}}
+		}
+
+		updater = &ddpUpdater{log: log, reloadTimeout: 50 * time.Millisecond}
+	})
+
+	AfterEach(func() {
+		iceDriverPath, pciDevicesPath = origDriverPath, origDevicesPath
+		driverReloadPollInterval = time.Second
+		newDeviceInfoProvider = origProvider
+	})
+
+	var _ = It("will rebind functions of the adapter and verify loaded DDP version", func() {
+		Expect(updater.reloadDriver("0000:18:00.1", "1.3.35.0")).To(Succeed())
+
+		// last function of the adapter is bound last
+		bound, err := os.ReadFile(filepath.Join(iceDriverPath, "bind"))
+		Expect(err).To(Succeed())
+		Expect(string(bound)).To(Equal("0000:18:00.1"))
+		Expect(devlinkCalls).To(Equal(2))
+	})
+
+	var _ = It("will create VFs of the functions again after rebind", func() {
+		// kernel reports the number with a trailing newline, restored number is written without it
+		numVFsPath := func(f string) string { return filepath.Join(pciDevicesPath, f, "sriov_numvfs") }
+		Expect(os.WriteFile(numVFsPath("0000:18:00.0"), []byte("4\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(numVFsPath("0000:18:00.1"), []byte("0\n"), 0600)).To(Succeed())
+
+		Expect(updater.reloadDriver("0000:18:00.1", "1.3.35.0")).To(Succeed())
+
+		numVFs, err := os.ReadFile(numVFsPath("0000:18:00.0"))
+		Expect(err).To(Succeed())
+		Expect(string(numVFs)).To(Equal("4"))
+		numVFs, err = os.ReadFile(numVFsPath("0000:18:00.1"))
+		Expect(err).To(Succeed())
+		Expect(string(numVFs)).To(Equal("0\n"))
+	})
+
+	var _ = It("will skip reload if the package is already loaded", func() {
+		loadedVersions = []string{"1.3.35.0"}
+		Expect(updater.reloadDriver("0000:18:00.0", "1.3.35.0")).To(Succeed())
+
+		unbound, err := os.ReadFile(filepath.Join(iceDriverPath, "unbind"))
+		Expect(err).To(Succeed())
+		Expect(unbound).To(BeEmpty())
+	})
+
+	var _ = It("will fail if devlink reports other DDP version after reload", func() {
+		loadedVersions = []string{"1.3.30.0"}
+		err := updater.reloadDriver("0000:18:00.0", "1.3.35.0")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`devlink reports "1.3.30.0"`))
+	})
+
+	var _ = It("will accept other DDP version after reload if target version is unknown", func() {
+		Expect(updater.reloadDriver("0000:18:00.0", "")).To(Succeed())
+		Expect(devlinkCalls).To(Equal(2))
+	})
+
+	var _ = It("will fail if devlink reports DDP version from before reload and target version is unknown", func() {
+		loadedVersions = []string{"1.3.35.0"}
+		err := updater.reloadDriver("0000:18:00.0", "")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`not changed by ice driver reload, devlink reports "1.3.35.0"`))
+	})
+
+	var _ = It("will fail if netdev does not come back", func() {
+		Expect(os.RemoveAll(filepath.Join(pciDevicesPath, "0000:18:00.1", "net"))).To(Succeed())
+		err := updater.reloadDriver("0000:18:00.0", "1.3.35.0")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("netdev of 0000:18:00.1 did not come back"))
+	})
+
+	var _ = It("will fail if device is not bound to ice driver", func() {
+		err := updater.reloadDriver("0000:20:00.0", "1.3.35.0")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("not bound to ice driver"))
+	})
+})
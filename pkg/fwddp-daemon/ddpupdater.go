@@ -9,20 +9,36 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 )
 
 var findDdp = findDdpProfile
 
 type ddpUpdater struct {
-	log        logr.Logger
-	httpClient *http.Client
+	log           logr.Logger
+	httpClient    *http.Client
+	fetcher       *packageFetcher
+	cache         *artifactCache
+	verifier      *packageVerifier
+	reloadTimeout time.Duration
 }
 
-func (d *ddpUpdater) handleDDPUpdate(pciAddr string, ddpPath string) (bool, error) {
+// withOperationLog returns copy of the updater recording its log in opLog
+func (d *ddpUpdater) withOperationLog(opLog *operationLog) *ddpUpdater {
+	c := *d
+	c.log = opLog.logger(d.log)
+	return &c
+}
+
+// handleDDPUpdate copies DDP package for the device. If reload is requested, the package is loaded by reloading
+// ice driver, otherwise node reboot is required
+func (d *ddpUpdater) handleDDPUpdate(pciAddr string, ddpPath string, reload bool, targetVersion string,
+	deviceStatus *deviceStatusReporter) (bool, error) {
 	log := d.log.WithName("handleDDPUpdate")
 	if ddpPath == "" {
 		return false, nil
@@ -33,33 +49,113 @@ func (d *ddpUpdater) handleDDPUpdate(pciAddr string, ddpPath string) (bool, erro
 		log.Error(err, "Failed to update DDP", "device", pciAddr)
 		return false, err
 	}
-	return true, nil
+	return d.loadDDP(pciAddr, reload, targetVersion, deviceStatus)
+}
+
+// handleDDPRevert removes DDP packages applied to the device, so that it loads the default package of the OS
+// after ice driver reload or node reboot
+func (d *ddpUpdater) handleDDPRevert(pciAddr string, reload bool, deviceStatus *deviceStatusReporter) (bool, error) {
+	log := d.log.WithName("handleDDPRevert")
+
+	err := d.revertDDP(pciAddr)
+	if err != nil {
+		log.Error(err, "Failed to revert DDP", "device", pciAddr)
+		return false, err
+	}
+	if !reload {
+		return true, nil
+	}
+
+	// firmware loader looks for the package in updates/intel/ice/ddp first
+	intelPath, updatesPath := d.getDdpUpdatePaths()
+	targetVersion, err := defaultDDPVersion(updatesPath, intelPath)
+	if err != nil {
+		log.Info("Failed to read version of default DDP package", "device", pciAddr, "reason", err.Error())
+	}
+	return d.loadDDP(pciAddr, reload, targetVersion, deviceStatus)
+}
+
+// loadDDP reloads ice driver if requested, so that the device loads DDP package. Otherwise it returns true,
+// as node reboot is required
+func (d *ddpUpdater) loadDDP(pciAddr string, reload bool, targetVersion string,
+	deviceStatus *deviceStatusReporter) (bool, error) {
+	if !reload {
+		return true, nil
+	}
+
+	deviceStatus.setPhase(DevicePhaseReloadingDriver)
+	if err := d.reloadDriver(pciAddr, targetVersion); err != nil {
+		d.log.Error(err, "Failed to load DDP by ice driver reload", "device", pciAddr)
+		return false, err
+	}
+	return false, nil
+}
+
+// ddpPackagePaths returns paths of DDP packages which are loaded by ice driver for the device instead
+// of the default package
+func (d *ddpUpdater) ddpPackagePaths(pciAddr string) ([]string, error) {
+	dsn, err := readDeviceSerialNumber(pciAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Device Serial Number of %v: %v", pciAddr, err)
+	}
+	// ice driver looks for package named after the serial number, e.g. ice-b49691ffffaf6d68.pkg
+	devId := fmt.Sprintf("%016x", dsn)
+
+	// both intel/ice/ddp and updates/intel/ice/ddp
+	// DDP paths are used for compatibility with different drivers
+	intelPath, updatesPath := d.getDdpUpdatePaths()
+	return []string{
+		filepath.Join(intelPath, "ice-"+devId+".pkg"),
+		filepath.Join(updatesPath, "ice-"+devId+".pkg"),
+	}, nil
+}
+
+// isDDPApplied returns true if any DDP package applied to the device is present
+func (d *ddpUpdater) isDDPApplied(pciAddr string) (bool, error) {
+	paths, err := d.ddpPackagePaths(pciAddr)
+	if err != nil {
+		return false, err
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return true, nil
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func (d *ddpUpdater) revertDDP(pciAddr string) error {
+	log := d.log.WithName("revertDDP")
+
+	paths, err := d.ddpPackagePaths(pciAddr)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		log.V(4).Info("Removing", "path", path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
 }
 
 // ddpProfilePath is the path to our extracted DDP profile
 func (d *ddpUpdater) updateDDP(pciAddr, ddpProfilePath string) error {
 	log := d.log.WithName("updateDDP")
 
-	devId, err := execCmd([]string{"sh", "-c", "lspci -vs " + pciAddr +
-		" | awk '/Device Serial/ {print $NF}' | sed s/-//g"}, log)
+	targets, err := d.ddpPackagePaths(pciAddr)
 	if err != nil {
 		return err
 	}
-	devId = strings.TrimSuffix(devId, "\n")
-	if devId == "" {
-		return fmt.Errorf("failed to extract devId")
-	}
 
-	// create both intel/ice/ddp and updates/intel/ice/ddp
-	// DDP paths for compatibility with different drivers
-	intelPath, updatesPath := d.getDdpUpdatePaths()
-	
-	for _, path := range []string{intelPath, updatesPath} {
-		if err := os.MkdirAll(path, 0600); err != nil {
+	for _, target := range targets {
+		if err := os.MkdirAll(filepath.Dir(target), 0600); err != nil {
 			return err
 		}
 
-		target := filepath.Join(path, "ice-"+devId+".pkg")
 		log.V(4).Info("Copying", "source", ddpProfilePath, "target", target)
 
 		if err :=  utils.CopyFile(ddpProfilePath, target); err != nil {
@@ -70,7 +166,7 @@ func (d *ddpUpdater) updateDDP(pciAddr, ddpProfilePath string) error {
 	return nil
 }
 
-func (d *ddpUpdater) prepareDDP(config ethernetv1.DeviceNodeConfig) (string, error) {
+func (d *ddpUpdater) prepareDDP(config ethernetv1.DeviceNodeConfig, deviceStatus *deviceStatusReporter) (string, error) {
 	log := d.log.WithName("prepareDDP")
 
 	if config.DeviceConfig.DDPURL == "" {
@@ -78,21 +174,42 @@ func (d *ddpUpdater) prepareDDP(config ethernetv1.DeviceNodeConfig) (string, err
 		return "", nil
 	}
 
-	targetPath := filepath.Join(artifactsFolder, config.PCIAddress)
-
-	err := utils.CreateFolder(targetPath, log)
+	targetPath, fullPath, cached, err := d.cache.acquire(config.PCIAddress, config.DeviceConfig.DDPURL,
+		config.DeviceConfig.DDPChecksum)
 	if err != nil {
 		return "", err
 	}
 
-	fullPath := filepath.Join(targetPath, filepath.Base(config.DeviceConfig.DDPURL))
-	log.V(4).Info("Downloading", "url", config.DeviceConfig.DDPURL, "dstPath", fullPath)
-	err = downloadFile(fullPath, config.DeviceConfig.DDPURL, config.DeviceConfig.DDPChecksum, d.httpClient)
+	if !cached {
+		deviceStatus.setPhase(DevicePhaseDownloading)
+		deviceStatus.event(corev1.EventTypeNormal, EventDownloadStarted, "Downloading DDP package %v",
+			config.DeviceConfig.DDPURL)
+		downloadStart := timeNow()
+		progress, stopProgress := deviceStatus.downloadProgress(config.DeviceConfig.DDPURL)
+		fullPath, err = d.fetcher.fetchPackage(targetPath, config.DeviceConfig.DDPURL, config.DeviceConfig.DDPChecksum,
+			config.DeviceConfig.PullSecret, progress)
+		stopProgress()
+		if err != nil {
+			return "", err
+		}
+		if info, err := os.Stat(fullPath); err == nil {
+			observeDownload("ddp", info.Size(), downloadStart)
+		}
+		deviceStatus.event(corev1.EventTypeNormal, EventDownloadCompleted, "Downloaded DDP package %v",
+			config.DeviceConfig.DDPURL)
+		if config.DeviceConfig.DDPChecksum != "" {
+			deviceStatus.event(corev1.EventTypeNormal, EventChecksumVerified, "Checksum of DDP package %v verified",
+				config.DeviceConfig.DDPURL)
+		}
+	}
+
+	err = d.verifier.verifySignature(fullPath, config.DeviceConfig.DDPSignatureURL, config.DeviceConfig.SignatureKeySecret)
 	if err != nil {
 		return "", err
 	}
 
-	log.V(4).Info("DDP file downloaded - extracting")
+	deviceStatus.setPhase(DevicePhaseExtracting)
+	log.V(4).Info("DDP file ready - extracting")
 	// XXX so this unpacks into the same directory as the source file
 	// We might add more comments here explaining the mechanics and reasoning
 	err = unpackDDPArchive(fullPath, targetPath, log)
@@ -100,6 +217,11 @@ func (d *ddpUpdater) prepareDDP(config ethernetv1.DeviceNodeConfig) (string, err
 		return "", err
 	}
 
+	err = d.cache.store(targetPath, fullPath)
+	if err != nil {
+		return "", err
+	}
+
 	return findDdp(targetPath)
 }
 
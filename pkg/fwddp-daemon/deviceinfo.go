@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	sysClassNetPath = "/sys/class/net"
+	sysModulePath   = "/sys/module"
+)
+
+// ethtoolDriverInfo is driver information of network interface, as reported by ethtool -i
+type ethtoolDriverInfo struct {
+	driver          string
+	version         string
+	firmwareVersion string
+}
+
+// devlinkDeviceInfo is information about PCI device, as reported by devlink dev info
+type devlinkDeviceInfo struct {
+	driver       string
+	serialNumber string
+	// versions of device components by name, e.g. fw.mgmt. Stored versions are activated on the next reboot
+	fixed, running, stored map[string]string
+}
+
+// deviceInfoProvider reports driver and firmware information of devices and their network interfaces
+type deviceInfoProvider interface {
+	ethtoolInfo(ifName string) (*ethtoolDriverInfo, error)
+	devlinkInfo(pciAddr string) (*devlinkDeviceInfo, error)
+	devlinkParams(pciAddr string) ([]devlinkParam, error)
+}
+
+// newDeviceInfoProvider returns provider used for a single inventory pass. Information is obtained over netlink,
+// falling back to sysfs, which doesn't report versions of the firmware components
+var newDeviceInfoProvider = func(log logr.Logger) deviceInfoProvider {
+	return &fallbackInfoProvider{
+		log:      log,
+		primary:  &netlinkInfoProvider{},
+		fallback: &sysfsInfoProvider{},
+	}
+}
+
+// fallbackInfoProvider asks fallback provider if primary one fails
+type fallbackInfoProvider struct {
+	log      logr.Logger
+	primary  deviceInfoProvider
+	fallback deviceInfoProvider
+}
+
+func (p *fallbackInfoProvider) ethtoolInfo(ifName string) (*ethtoolDriverInfo, error) {
+	info, err := p.primary.ethtoolInfo(ifName)
+	if err == nil {
+		return info, nil
+	}
+	p.log.V(2).Info("failed to get driver info over netlink, falling back to sysfs", "interface", ifName,
+		"error", err.Error())
+	return p.fallback.ethtoolInfo(ifName)
+}
+
+func (p *fallbackInfoProvider) devlinkInfo(pciAddr string) (*devlinkDeviceInfo, error) {
+	info, err := p.primary.devlinkInfo(pciAddr)
+	if err == nil {
+		return info, nil
+	}
+	p.log.V(2).Info("failed to get devlink info over netlink, falling back to sysfs", "pciAddress", pciAddr,
+		"error", err.Error())
+	return p.fallback.devlinkInfo(pciAddr)
+}
+
+// netlinkInfoProvider queries the kernel directly: ETHTOOL_GDRVINFO ioctl and DEVLINK_CMD_INFO_GET
+// generic netlink command
+type netlinkInfoProvider struct{}
+
+func (p *netlinkInfoProvider) ethtoolInfo(ifName string) (*ethtoolDriverInfo, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	drvinfo, err := unix.IoctlGetEthtoolDrvinfo(fd, ifName)
+	if err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GDRVINFO failed for %v: %v", ifName, err)
+	}
+	return &ethtoolDriverInfo{
+		driver:          cString(drvinfo.Driver[:]),
+		version:         cString(drvinfo.Version[:]),
+		firmwareVersion: cString(drvinfo.Fw_version[:]),
+	}, nil
+}
+
+func (p *netlinkInfoProvider) devlinkInfo(pciAddr string) (*devlinkDeviceInfo, error) {
+	conn, err := dialGenl()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	family, err := conn.resolveFamily(unix.DEVLINK_GENL_NAME)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := conn.execute(family, unix.DEVLINK_CMD_INFO_GET, unix.DEVLINK_GENL_VERSION, []nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_BUS_NAME, "pci"),
+		stringAttr(unix.DEVLINK_ATTR_DEV_NAME, pciAddr),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("devlink info failed for %v: %v", pciAddr, err)
+	}
+	if len(replies) == 0 {
+		return nil, fmt.Errorf("devlink info returned no reply for %v", pciAddr)
+	}
+	return parseDevlinkInfo(replies[0])
+}
+
+// parseDevlinkInfo parses attributes of DEVLINK_CMD_INFO_GET reply
+func parseDevlinkInfo(attrs []nlAttr) (*devlinkDeviceInfo, error) {
+	info := &devlinkDeviceInfo{fixed: map[string]string{}, running: map[string]string{}, stored: map[string]string{}}
+	for _, a := range attrs {
+		var versions map[string]string
+		switch a.typ {
+		case unix.DEVLINK_ATTR_INFO_DRIVER_NAME:
+			info.driver = a.str()
+			continue
+		case unix.DEVLINK_ATTR_INFO_SERIAL_NUMBER:
+			info.serialNumber = a.str()
+			continue
+		case unix.DEVLINK_ATTR_INFO_VERSION_FIXED:
+			versions = info.fixed
+		case unix.DEVLINK_ATTR_INFO_VERSION_RUNNING:
+			versions = info.running
+		case unix.DEVLINK_ATTR_INFO_VERSION_STORED:
+			versions = info.stored
+		default:
+			continue
+		}
+
+		nested, err := a.nested()
+		if err != nil {
+			return nil, err
+		}
+		var name, value string
+		for _, v := range nested {
+			switch v.typ {
+			case unix.DEVLINK_ATTR_INFO_VERSION_NAME:
+				name = v.str()
+			case unix.DEVLINK_ATTR_INFO_VERSION_VALUE:
+				value = v.str()
+			}
+		}
+		if name != "" {
+			versions[name] = value
+		}
+	}
+	return info, nil
+}
+
+// sysfsInfoProvider reports drivers bound to devices and their versions, when netlink is not available
+type sysfsInfoProvider struct{}
+
+func (p *sysfsInfoProvider) ethtoolInfo(ifName string) (*ethtoolDriverInfo, error) {
+	driver, err := boundDriver(filepath.Join(sysClassNetPath, ifName, "device"))
+	if err != nil {
+		return nil, err
+	}
+	return &ethtoolDriverInfo{
+		driver:  driver,
+		version: readSysfsString(filepath.Join(sysModulePath, driver, "version")),
+	}, nil
+}
+
+func (p *sysfsInfoProvider) devlinkInfo(pciAddr string) (*devlinkDeviceInfo, error) {
+	driver, err := boundDriver(filepath.Join(pciDevicesPath, pciAddr))
+	if err != nil {
+		return nil, err
+	}
+	return &devlinkDeviceInfo{driver: driver}, nil
+}
+
+// boundDriver returns name of the driver bound to the device in sysfs
+func boundDriver(devicePath string) (string, error) {
+	driverPath, err := os.Readlink(filepath.Join(devicePath, "driver"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errors.New("no driver bound to " + filepath.Base(devicePath))
+		}
+		return "", err
+	}
+	return filepath.Base(driverPath), nil
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
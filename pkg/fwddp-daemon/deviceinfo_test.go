@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func versionAttr(typ uint16, name, value string) nlAttr {
+	return nlAttr{typ: typ | unix.NLA_F_NESTED, data: encodeAttrs([]nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_INFO_VERSION_NAME, name),
+		stringAttr(unix.DEVLINK_ATTR_INFO_VERSION_VALUE, value),
+	})}
+}
+
+var _ = Describe("deviceInfoProvider", func() {
+	log := ctrl.Log.WithName("deviceInfoProvider-test")
+
+	var _ = It("will decode encoded netlink attributes", func() {
+		attrs := []nlAttr{stringAttr(1, "pci"), {typ: 2, data: []byte{1, 2, 3, 4, 5}}, stringAttr(3, "")}
+		decoded, err := decodeAttrs(encodeAttrs(attrs))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(attrs))
+		Expect(decoded[0].str()).To(Equal("pci"))
+
+		_, err = decodeAttrs([]byte{0xff, 0, 1, 0})
+		Expect(err).To(MatchError(errMalformedNetlinkMessage))
+	})
+
+	var _ = It("will parse devlink info reply", func() {
+		reply, err := decodeAttrs(encodeAttrs([]nlAttr{
+			stringAttr(unix.DEVLINK_ATTR_BUS_NAME, "pci"),
+			stringAttr(unix.DEVLINK_ATTR_DEV_NAME, "0000:18:00.0"),
+			stringAttr(unix.DEVLINK_ATTR_INFO_DRIVER_NAME, "ice"),
+			stringAttr(unix.DEVLINK_ATTR_INFO_SERIAL_NUMBER, "b4-96-91-ff-ff-af-6d-68"),
+			versionAttr(unix.DEVLINK_ATTR_INFO_VERSION_FIXED, "board.id", "M17659-003"),
+			versionAttr(unix.DEVLINK_ATTR_INFO_VERSION_RUNNING, "fw.mgmt", "5.4.5"),
+			versionAttr(unix.DEVLINK_ATTR_INFO_VERSION_RUNNING, "fw.app.name", "ICE OS Default Package"),
+			versionAttr(unix.DEVLINK_ATTR_INFO_VERSION_STORED, "fw.undi", "1.2900.0"),
+		}))
+		Expect(err).ToNot(HaveOccurred())
+
+		info, err := parseDevlinkInfo(reply)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info).To(Equal(&devlinkDeviceInfo{
+			driver:       "ice",
+			serialNumber: "b4-96-91-ff-ff-af-6d-68",
+			fixed:        map[string]string{"board.id": "M17659-003"},
+			running:      map[string]string{"fw.mgmt": "5.4.5", "fw.app.name": "ICE OS Default Package"},
+			stored:       map[string]string{"fw.undi": "1.2900.0"},
+		}))
+	})
+
+	var _ = Context("sysfsInfoProvider", func() {
+		var origDevicesPath, origNetPath, origModulePath string
+
+		BeforeEach(func() {
+			origDevicesPath, origNetPath, origModulePath = pciDevicesPath, sysClassNetPath, sysModulePath
+			pciDevicesPath, sysClassNetPath, sysModulePath = GinkgoT().TempDir(), GinkgoT().TempDir(), GinkgoT().TempDir()
+
+			devicePath := filepath.Join(pciDevicesPath, "0000:18:00.0")
+			Expect(os.MkdirAll(devicePath, 0700)).To(Succeed())
+			Expect(os.Symlink("../../../bus/pci/drivers/ice", filepath.Join(devicePath, "driver"))).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(sysClassNetPath, "ens1f0"), 0700)).To(Succeed())
+			Expect(os.Symlink(devicePath, filepath.Join(sysClassNetPath, "ens1f0", "device"))).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(sysModulePath, "ice"), 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sysModulePath, "ice", "version"), []byte("1.11.14\n"), 0600)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			pciDevicesPath, sysClassNetPath, sysModulePath = origDevicesPath, origNetPath, origModulePath
+		})
+
+		var _ = It("will report bound driver and its version", func() {
+			provider := &sysfsInfoProvider{}
+			info, err := provider.ethtoolInfo("ens1f0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(Equal(&ethtoolDriverInfo{driver: "ice", version: "1.11.14"}))
+
+			devlink, err := provider.devlinkInfo("0000:18:00.0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(devlink).To(Equal(&devlinkDeviceInfo{driver: "ice"}))
+
+			_, err = provider.devlinkInfo("0000:18:00.1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	var _ = It("will fall back if primary provider fails", func() {
+		provider := &fallbackInfoProvider{
+			log: log,
+			primary: &fakeInfoProvider{
+				ethtool: func(string) (*ethtoolDriverInfo, error) { return &ethtoolDriverInfo{driver: "ice"}, nil },
+				devlink: func(string) (*devlinkDeviceInfo, error) { return nil, errors.New("devlink not supported") },
+			},
+			fallback: &fakeInfoProvider{
+				devlink: func(string) (*devlinkDeviceInfo, error) { return &devlinkDeviceInfo{driver: "vfio-pci"}, nil },
+			},
+		}
+
+		info, err := provider.ethtoolInfo("ens1f0")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.driver).To(Equal("ice"))
+
+		devlink, err := provider.devlinkInfo("0000:18:00.0")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(devlink.driver).To(Equal("vfio-pci"))
+	})
+})
@@ -0,0 +1,317 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DevicePhasePending         = "Pending"
+	DevicePhaseDownloading     = "Downloading"
+	DevicePhaseExtracting      = "Extracting"
+	DevicePhaseFlashing        = "Flashing"
+	DevicePhaseCopyingDDP      = "CopyingDDP"
+	DevicePhaseRevertingDDP    = "RevertingDDP"
+	DevicePhaseReloadingDriver = "ReloadingDriver"
+	DevicePhaseSettingParams   = "SettingParams"
+	DevicePhaseRebooting       = "Rebooting"
+	DevicePhaseSucceeded       = "Succeeded"
+	DevicePhaseUpToDate        = "UpToDate"
+	DevicePhaseFailed          = "Failed"
+)
+
+// deviceStatusReporter records update progress of a single device in EthernetNodeConfig status.
+// All methods are no-op on nil reporter
+type deviceStatusReporter struct {
+	r       *NodeConfigReconciler
+	nc      *ethernetv1.EthernetNodeConfig
+	pciAddr string
+}
+
+func (r *NodeConfigReconciler) deviceStatus(nc *ethernetv1.EthernetNodeConfig, pciAddr string) *deviceStatusReporter {
+	return &deviceStatusReporter{r: r, nc: nc, pciAddr: pciAddr}
+}
+
+func (d *deviceStatusReporter) setPhase(phase string) {
+	d.update(func(s *ethernetv1.DeviceUpdateStatus) {
+		setDevicePhase(s, phase)
+	})
+}
+
+func (d *deviceStatusReporter) setTargetVersions(fwVersion, ddpVersion string) {
+	d.update(func(s *ethernetv1.DeviceUpdateStatus) {
+		s.TargetFirmwareVersion = fwVersion
+		s.TargetDDPVersion = ddpVersion
+	})
+}
+
+func (d *deviceStatusReporter) setFirmwareUpdate(summary *ethernetv1.FirmwareUpdateSummary) {
+	d.update(func(s *ethernetv1.DeviceUpdateStatus) {
+		s.FirmwareUpdate = summary
+	})
+}
+
+func (d *deviceStatusReporter) fail(err error) {
+	d.update(func(s *ethernetv1.DeviceUpdateStatus) {
+		setDevicePhase(s, DevicePhaseFailed)
+		s.LastError = err.Error()
+	})
+}
+
+// recordFlashedImage stores raw NVM image flashed to the device, replacing the one recorded before
+func (d *deviceStatusReporter) recordFlashedImage(image ethernetv1.FlashedImage) {
+	if d == nil {
+		return
+	}
+	log := d.r.log.WithName("deviceStatus")
+
+	err := d.r.modifyStatus(d.nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		for i := range nodeStatus.FlashedImages {
+			if nodeStatus.FlashedImages[i].PCIAddress == image.PCIAddress {
+				nodeStatus.FlashedImages[i] = image
+				return
+			}
+		}
+		nodeStatus.FlashedImages = append(nodeStatus.FlashedImages, image)
+	})
+	if err != nil {
+		log.Error(err, "failed to record flashed NVM image", "device", d.pciAddr)
+	}
+}
+
+// downloadProgress returns function reporting progress of package download in the update condition message and
+// function stopping the reporting once the download ends. Progress is written to the status by a separate goroutine
+// taking only the latest value, so slow status updates don't hold up the download
+func (d *deviceStatusReporter) downloadProgress(url string) (utils.DownloadProgressFunc, func()) {
+	if d == nil {
+		return nil, func() {}
+	}
+
+	// progress goroutine gets the status into its own object, nc is used by the reconcile meanwhile
+	nc := &ethernetv1.EthernetNodeConfig{}
+	nc.SetName(d.nc.GetName())
+	nc.SetNamespace(d.nc.GetNamespace())
+	log := d.r.log.WithName("downloadProgress")
+
+	latest := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range latest {
+			if err := d.r.updateConditionMessage(nc, UpdateInProgress, msg); err != nil {
+				log.Error(err, "failed to report download progress", "device", d.pciAddr)
+			}
+		}
+	}()
+
+	progress := func(downloaded, total int64) {
+		msg := fmt.Sprintf("Downloading %v for device %v: %v bytes", filepath.Base(url), d.pciAddr, downloaded)
+		if total > 0 {
+			msg = fmt.Sprintf("Downloading %v for device %v: %v/%v bytes (%v%%)", filepath.Base(url), d.pciAddr,
+				downloaded, total, downloaded*100/total)
+		}
+		// value not taken by the goroutine yet is replaced with the latest one
+		for {
+			select {
+			case latest <- msg:
+				return
+			default:
+			}
+			select {
+			case <-latest:
+			default:
+			}
+		}
+	}
+	stop := func() {
+		close(latest)
+		<-done
+	}
+	return progress, stop
+}
+
+func (d *deviceStatusReporter) update(modify func(*ethernetv1.DeviceUpdateStatus)) {
+	if d == nil {
+		return
+	}
+	log := d.r.log.WithName("deviceStatus")
+
+	err := d.r.modifyStatus(d.nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		modifyDeviceUpdate(nodeStatus, d.pciAddr, modify)
+	})
+	if err != nil {
+		log.Error(err, "failed to update device status", "device", d.pciAddr)
+	}
+}
+
+// startDeviceUpdates resets update status of all configured devices, except those configured with dry run, to Pending
+func (r *NodeConfigReconciler) startDeviceUpdates(nc *ethernetv1.EthernetNodeConfig) {
+	log := r.log.WithName("startDeviceUpdates")
+
+	now := metav1.Now()
+	err := r.modifyStatus(nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		for _, config := range nc.Spec.Config {
+			if config.DryRun {
+				continue
+			}
+			modifyDeviceUpdate(nodeStatus, config.PCIAddress, func(s *ethernetv1.DeviceUpdateStatus) {
+				*s = ethernetv1.DeviceUpdateStatus{PCIAddress: s.PCIAddress, StartTime: &now}
+				setDevicePhase(s, DevicePhasePending)
+			})
+		}
+	})
+	if err != nil {
+		log.Error(err, "failed to reset device update status")
+	}
+}
+
+// finishDeviceUpdates moves all devices which are in one of fromPhases to phase
+func (r *NodeConfigReconciler) finishDeviceUpdates(nc *ethernetv1.EthernetNodeConfig, phase string, updateErr error,
+	fromPhases ...string) {
+	log := r.log.WithName("finishDeviceUpdates")
+
+	err := r.modifyStatus(nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		for i := range nodeStatus.DeviceUpdates {
+			s := &nodeStatus.DeviceUpdates[i]
+			for _, from := range fromPhases {
+				if s.Phase == from {
+					setDevicePhase(s, phase)
+					if updateErr != nil {
+						s.LastError = updateErr.Error()
+					}
+					break
+				}
+			}
+		}
+	})
+	if err != nil {
+		log.Error(err, "failed to update device status", "phase", phase)
+	}
+}
+
+func modifyDeviceUpdate(nodeStatus *ethernetv1.EthernetNodeConfigStatus, pciAddr string,
+	modify func(*ethernetv1.DeviceUpdateStatus)) {
+	for i := range nodeStatus.DeviceUpdates {
+		if nodeStatus.DeviceUpdates[i].PCIAddress == pciAddr {
+			modify(&nodeStatus.DeviceUpdates[i])
+			return
+		}
+	}
+	s := ethernetv1.DeviceUpdateStatus{PCIAddress: pciAddr}
+	modify(&s)
+	nodeStatus.DeviceUpdates = append(nodeStatus.DeviceUpdates, s)
+}
+
+func setDevicePhase(s *ethernetv1.DeviceUpdateStatus, phase string) {
+	now := metav1.Now()
+	s.Phase = phase
+	s.LastTransitionTime = now
+	if phase == DevicePhaseSucceeded || phase == DevicePhaseUpToDate || phase == DevicePhaseFailed {
+		s.CompletionTime = &now
+	}
+}
+
+// refreshObservedVersions copies versions reported by inventory into device update status
+func refreshObservedVersions(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+	for i := range nodeStatus.DeviceUpdates {
+		for _, device := range nodeStatus.Devices {
+			if device.PCIAddress == nodeStatus.DeviceUpdates[i].PCIAddress {
+				nodeStatus.DeviceUpdates[i].ObservedFirmwareVersion = device.Firmware.Version
+				nodeStatus.DeviceUpdates[i].ObservedDDPVersion = device.DDP.Version
+				break
+			}
+		}
+	}
+}
+
+// pruneDeviceUpdates removes status of devices which are no longer configured
+func pruneDeviceUpdates(nodeStatus *ethernetv1.EthernetNodeConfigStatus, config []ethernetv1.DeviceNodeConfig) {
+	configured := map[string]bool{}
+	for _, c := range config {
+		configured[c.PCIAddress] = true
+	}
+
+	var updates []ethernetv1.DeviceUpdateStatus
+	for _, s := range nodeStatus.DeviceUpdates {
+		if configured[s.PCIAddress] {
+			updates = append(updates, s)
+		}
+	}
+	nodeStatus.DeviceUpdates = updates
+}
+
+// verifyDeviceVersions compares versions reported in inventory inv by devices waiting for the post-update reboot
+// with versions which were applied. Matching devices are moved to Succeeded, others to Failed. Returned error
+// describes all mismatches, true is returned if any mismatching device is configured to keep the node cordoned
+func (r *NodeConfigReconciler) verifyDeviceVersions(nc *ethernetv1.EthernetNodeConfig, inv []ethernetv1.Device) (bool, error) {
+	log := r.log.WithName("verifyDeviceVersions")
+
+	var mismatches []string
+	keepCordoned := false
+	err := r.modifyStatusWithInventory(nc, inv, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		mismatches, keepCordoned = nil, false
+		for i := range nodeStatus.DeviceUpdates {
+			s := &nodeStatus.DeviceUpdates[i]
+			if s.Phase != DevicePhaseRebooting {
+				continue
+			}
+
+			mismatch := versionMismatch(*s, nodeStatus.Devices)
+			if mismatch == "" {
+				setDevicePhase(s, DevicePhaseSucceeded)
+				continue
+			}
+			setDevicePhase(s, DevicePhaseFailed)
+			s.LastError = mismatch
+			mismatches = append(mismatches, mismatch)
+			for _, config := range nc.Spec.Config {
+				if config.PCIAddress == s.PCIAddress && config.DeviceConfig.KeepCordonedOnVersionMismatch {
+					keepCordoned = true
+				}
+			}
+		}
+	})
+	if err != nil {
+		log.Error(err, "failed to update device status")
+	}
+
+	if len(mismatches) == 0 {
+		return false, nil
+	}
+	return keepCordoned, fmt.Errorf("version mismatch after reboot: %v", strings.Join(mismatches, "; "))
+}
+
+// versionMismatch returns description of difference between target versions of the device update and versions
+// reported by the device, empty if they match. Unknown target versions are not verified
+func versionMismatch(s ethernetv1.DeviceUpdateStatus, inv []ethernetv1.Device) string {
+	var device *ethernetv1.Device
+	for i := range inv {
+		if inv[i].PCIAddress == s.PCIAddress {
+			device = &inv[i]
+			break
+		}
+	}
+	if device == nil {
+		return fmt.Sprintf("device %v not found", s.PCIAddress)
+	}
+
+	var diffs []string
+	if s.TargetFirmwareVersion != "" && observedEETrack(device.Firmware.Version) != s.TargetFirmwareVersion {
+		diffs = append(diffs, fmt.Sprintf("firmware %v expected, %q found", s.TargetFirmwareVersion, device.Firmware.Version))
+	}
+	if s.TargetDDPVersion != "" && device.DDP.Version != s.TargetDDPVersion {
+		diffs = append(diffs, fmt.Sprintf("DDP %v expected, %q found", s.TargetDDPVersion, device.DDP.Version))
+	}
+	if len(diffs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("device %v: %v", s.PCIAddress, strings.Join(diffs, ", "))
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("versionMismatch", func() {
+	inv := []ethernetv1.Device{
+		{
+			PCIAddress: "0000:18:00.0",
+			Firmware:   ethernetv1.FirmwareInfo{Version: "4.01 0x8001778b 1.3246.0"},
+			DDP:        ethernetv1.DDPInfo{Version: "1.3.30.0"},
+		},
+	}
+
+	var _ = It("will accept versions matching targets", func() {
+		s := ethernetv1.DeviceUpdateStatus{PCIAddress: "0000:18:00.0", TargetFirmwareVersion: "0x8001778b",
+			TargetDDPVersion: "1.3.30.0"}
+		Expect(versionMismatch(s, inv)).To(BeEmpty())
+	})
+
+	var _ = It("will not verify unknown target versions", func() {
+		s := ethernetv1.DeviceUpdateStatus{PCIAddress: "0000:18:00.0"}
+		Expect(versionMismatch(s, inv)).To(BeEmpty())
+	})
+
+	var _ = It("will describe firmware and DDP mismatch", func() {
+		s := ethernetv1.DeviceUpdateStatus{PCIAddress: "0000:18:00.0", TargetFirmwareVersion: "0x8001b9ad",
+			TargetDDPVersion: "1.3.35.0"}
+		Expect(versionMismatch(s, inv)).To(Equal(`device 0000:18:00.0: firmware 0x8001b9ad expected, ` +
+			`"4.01 0x8001778b 1.3246.0" found, DDP 1.3.35.0 expected, "1.3.30.0" found`))
+	})
+
+	var _ = It("will report missing device", func() {
+		s := ethernetv1.DeviceUpdateStatus{PCIAddress: "0000:18:00.1", TargetDDPVersion: "1.3.30.0"}
+		Expect(versionMismatch(s, inv)).To(Equal("device 0000:18:00.1 not found"))
+	})
+})
@@ -7,10 +7,13 @@ import (
 	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
 )
 
@@ -19,56 +22,202 @@ var (
 	updateXMLParseTimeout = 100 * time.Millisecond // Update xml parse timeout
 )
 
-func isRebootRequired(path string) (bool, error) {
+// nvmupdateResult is the result of NVM Update utility run in update mode
+type nvmupdateResult struct {
+	Instances           []nvmupdateInstance `xml:"Instance"`
+	NextUpdateAvailable int                 `xml:"NextUpdateAvailable"`
+	RebootRequired      int                 `xml:"RebootRequired"`
+	PowerCycleRequired  int                 `xml:"PowerCycleRequired"`
+}
+
+// nvmupdateInstance is a single function of the updated adapter
+type nvmupdateInstance struct {
+	Vendor       string            `xml:"vendor,attr"`
+	Device       string            `xml:"device,attr"`
+	Subdevice    string            `xml:"subdevice,attr"`
+	Subvendor    string            `xml:"subvendor,attr"`
+	Bus          string            `xml:"bus,attr"`
+	Dev          string            `xml:"dev,attr"`
+	Func         string            `xml:"func,attr"`
+	PBA          string            `xml:"PBA,attr"`
+	PortID       string            `xml:"port_id,attr"`
+	Display      string            `xml:"display,attr"`
+	Modules      []nvmupdateModule `xml:"Module"`
+	MACAddresses []struct {
+		Address string `xml:"address,attr"`
+	} `xml:"MACAddresses>MAC"`
+}
+
+// nvmupdateModule is the result of update of a single NVM module, e.g. NVM, Netlist or OROM (PXE, EFI)
+type nvmupdateModule struct {
+	Type            string `xml:"type,attr"`
+	Version         string `xml:"version,attr"`
+	PreviousVersion string `xml:"previous_version,attr"`
+	Status          struct {
+		Result  string `xml:"result,attr"`
+		ID      int    `xml:"id,attr"`
+		Message string `xml:",chardata"`
+	} `xml:"Status"`
+}
+
+// deadlineReader fails reads once the deadline passes
+type deadlineReader struct {
+	r   io.Reader
+	ctx context.Context
+}
+
+func (d deadlineReader) Read(p []byte) (int, error) {
+	if err := d.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return d.r.Read(p)
+}
+
+// parseUpdateResult parses result file written by NVM Update utility run in update mode
+func parseUpdateResult(path string) (*nvmupdateResult, error) {
 	invf, err := utils.OpenNoLinks(path)
 	if err != nil {
-		return true, err
+		return nil, err
 	}
 	defer invf.Close()
 
 	stat, err := invf.Stat()
 	if err != nil {
-		return true, err
+		return nil, err
 	}
 
 	kSize := stat.Size() / 1024
 	if kSize > maxFileSize {
-		return true, errors.New("Update result xml file too large: " + strconv.Itoa(int(kSize)) + "kB")
+		return nil, errors.New("Update result xml file too large: " + strconv.Itoa(int(kSize)) + "kB")
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), updateXMLParseTimeout)
 	defer cancel()
 
-	rebootRequired := false
-	decoder := xml.NewDecoder(invf)
-	for {
-		select {
-		case <-ctx.Done():
-			cancel()
-			return rebootRequired, ctx.Err()
-		default:
-			token, err := decoder.Token()
-			if token == nil {
-				return rebootRequired, nil
+	var result nvmupdateResult
+	if err := xml.NewDecoder(deadlineReader{r: invf, ctx: ctx}).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// nvmupdateExitReasons describes exit codes of NVM Update utility, as documented in its user guide
+var nvmupdateExitReasons = map[int]string{
+	0:  "All operations completed successfully",
+	1:  "Invalid command line parameter",
+	2:  "Configuration file error",
+	3:  "No update available, device already runs firmware provided by the package",
+	4:  "Image file error, file is missing or corrupted",
+	5:  "Unable to communicate with the device",
+	6:  "Insufficient privileges to run the utility",
+	7:  "No supported devices found",
+	8:  "Base driver error, driver is not loaded or not supported",
+	9:  "Unable to create inventory, result or log file",
+	10: "Flash is write protected",
+	11: "Update failed, device is in recovery mode",
+	12: "Update failed, another instance of the utility is running",
+	13: "Update failed",
+	30: "Update completed, but some devices or modules were not updated",
+	50: "Update completed, reboot required to finish the update",
+	51: "Update completed, power cycle required to finish the update",
+}
+
+// nvmupdateExitReason returns human readable description of NVM Update utility exit code
+func nvmupdateExitReason(code int) string {
+	if reason, ok := nvmupdateExitReasons[code]; ok {
+		return reason
+	}
+	return fmt.Sprintf("Unknown exit code %v", code)
+}
+
+// isNvmupdateSuccess returns true if the exit code reports completed update
+func isNvmupdateSuccess(code int) bool {
+	return code == 0 || code == 3 || code == 30 || code == 50 || code == 51
+}
+
+// firmwareUpdateSummary converts exit code and parsed result file of NVM Update utility into device status.
+// Results of modules reported by multiple functions of the adapter are merged, failures take precedence
+func firmwareUpdateSummary(exitCode int, result *nvmupdateResult) *ethernetv1.FirmwareUpdateSummary {
+	summary := &ethernetv1.FirmwareUpdateSummary{
+		ExitCode:   exitCode,
+		ExitReason: nvmupdateExitReason(exitCode),
+	}
+	if result == nil {
+		return summary
+	}
+
+	summary.RebootRequired = result.RebootRequired != 0
+	summary.PowerCycleRequired = result.PowerCycleRequired != 0
+
+	modules := map[string]int{}
+	for _, instance := range result.Instances {
+		for _, m := range instance.Modules {
+			moduleResult := ethernetv1.FirmwareModuleResult{
+				Type:            m.Type,
+				PreviousVersion: m.PreviousVersion,
+				Version:         m.Version,
+				Result:          m.Status.Result,
+				StatusID:        m.Status.ID,
+				Message:         strings.TrimSpace(m.Status.Message),
 			}
-			if err != nil {
-				if err == io.EOF {
-					return rebootRequired, nil
-				}
-				return false, err
+			i, ok := modules[m.Type]
+			if !ok {
+				modules[m.Type] = len(summary.Modules)
+				summary.Modules = append(summary.Modules, moduleResult)
+			} else if moduleResult.StatusID != 0 && summary.Modules[i].StatusID == 0 {
+				summary.Modules[i] = moduleResult
 			}
+		}
+	}
+	return summary
+}
 
-			switch t := token.(type) {
-			case xml.StartElement:
-				if t.Name.Local == "RebootRequired" {
-					var r int
-					err := decoder.DecodeElement(&r, &t)
-					if err != nil {
-						return false, err
-					}
-					rebootRequired = (r != 0)
-				}
+// nvmupdateInventory is the result of NVM Update utility run in inventory mode
+type nvmupdateInventory struct {
+	Instances []struct {
+		Modules []struct {
+			Type    string `xml:"type,attr"`
+			Version string `xml:"version,attr"`
+			Update  string `xml:"update,attr"`
+		} `xml:"Module"`
+	} `xml:"Instance"`
+}
+
+// isUpdateAvailable checks if any module of the inventoried devices is marked for update. If the utility
+// doesn't report update availability, update is assumed to be available
+func isUpdateAvailable(path string) (bool, error) {
+	invf, err := utils.OpenNoLinks(path)
+	if err != nil {
+		return true, err
+	}
+	defer invf.Close()
+
+	stat, err := invf.Stat()
+	if err != nil {
+		return true, err
+	}
+
+	kSize := stat.Size() / 1024
+	if kSize > maxFileSize {
+		return true, errors.New("Inventory result xml file too large: " + strconv.Itoa(int(kSize)) + "kB")
+	}
+
+	var inventory nvmupdateInventory
+	if err := xml.NewDecoder(invf).Decode(&inventory); err != nil {
+		return true, err
+	}
+
+	reported := false
+	for _, instance := range inventory.Instances {
+		for _, module := range instance.Modules {
+			if module.Update == "" {
+				continue
+			}
+			reported = true
+			if module.Update != "0" {
+				return true, nil
 			}
 		}
 	}
+	return !reported, nil
 }
@@ -61,8 +61,8 @@ const (
         <RebootRequired>`
 )
 
-var _ = Describe("isRebootRequired", func() {
-	var _ = It("will request reboot as specified in the XML", func() {
+var _ = Describe("parseUpdateResult", func() {
+	var _ = It("will parse module results and request reboot as specified in the XML", func() {
 		tmpfile, err := os.CreateTemp(".", "update")
 		Expect(err).ToNot(HaveOccurred())
 		defer os.Remove(tmpfile.Name())
@@ -70,10 +70,20 @@ var _ = Describe("isRebootRequired", func() {
 		_, err = tmpfile.Write([]byte(nvmupdateOutput))
 		Expect(err).ToNot(HaveOccurred())
 
-		result, err := isRebootRequired(tmpfile.Name())
+		result, err := parseUpdateResult(tmpfile.Name())
 
-		Expect(result).To(BeTrue())
 		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RebootRequired).To(Equal(1))
+		Expect(result.PowerCycleRequired).To(Equal(0))
+		Expect(result.Instances).To(HaveLen(1))
+		Expect(result.Instances[0].PortID).To(Equal("Port 2 of 2"))
+		Expect(result.Instances[0].MACAddresses[0].Address).To(Equal("B49691AA9E19"))
+		Expect(result.Instances[0].Modules).To(HaveLen(4))
+		nvm := result.Instances[0].Modules[3]
+		Expect(nvm.Type).To(Equal("NVM"))
+		Expect(nvm.PreviousVersion).To(Equal("800049C3"))
+		Expect(nvm.Version).To(Equal("800077A6"))
+		Expect(nvm.Status.Result).To(Equal("Success"))
 	})
 
 	var _ = It("will return error if too large file is provided", func() {
@@ -86,7 +96,7 @@ var _ = Describe("isRebootRequired", func() {
 
 		Expect(err).ToNot(HaveOccurred())
 
-		_, err = isRebootRequired(tmpfile.Name())
+		_, err = parseUpdateResult(tmpfile.Name())
 
 		Expect(err).To(HaveOccurred())
 	})
@@ -100,14 +110,14 @@ var _ = Describe("isRebootRequired", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		updateXMLParseTimeout = 1 * time.Nanosecond
-		_, err = isRebootRequired(tmpfile.Name())
+		_, err = parseUpdateResult(tmpfile.Name())
 		updateXMLParseTimeout = 100 * time.Millisecond
 
 		Expect(err).To(HaveOccurred())
 	})
 
 	var _ = It("will return error if unable to open file", func() {
-		_, err := isRebootRequired("/dev/null/fake")
+		_, err := parseUpdateResult("/dev/null/fake")
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -119,7 +129,120 @@ var _ = Describe("isRebootRequired", func() {
 		_, err = tmpfile.Write([]byte(nvmupdateOutputMissingRebootRequiredClause))
 		Expect(err).ToNot(HaveOccurred())
 
-		_, err = isRebootRequired(tmpfile.Name())
+		_, err = parseUpdateResult(tmpfile.Name())
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("firmwareUpdateSummary", func() {
+	var _ = It("will merge module results of all functions, preferring failures", func() {
+		result := &nvmupdateResult{RebootRequired: 1}
+		result.Instances = make([]nvmupdateInstance, 2)
+		result.Instances[0].Modules = make([]nvmupdateModule, 2)
+		result.Instances[0].Modules[0].Type = "NVM"
+		result.Instances[0].Modules[0].Status.Result = "Success"
+		result.Instances[0].Modules[1].Type = "Netlist"
+		result.Instances[0].Modules[1].Status.Result = "Success"
+		result.Instances[1].Modules = make([]nvmupdateModule, 1)
+		result.Instances[1].Modules[0].Type = "Netlist"
+		result.Instances[1].Modules[0].Status.Result = "Fail"
+		result.Instances[1].Modules[0].Status.ID = 7
+		result.Instances[1].Modules[0].Status.Message = " Update failed. "
+
+		summary := firmwareUpdateSummary(30, result)
+		Expect(summary.ExitCode).To(Equal(30))
+		Expect(summary.ExitReason).To(Equal(nvmupdateExitReasons[30]))
+		Expect(summary.RebootRequired).To(BeTrue())
+		Expect(summary.Modules).To(HaveLen(2))
+		Expect(summary.Modules[0].Type).To(Equal("NVM"))
+		Expect(summary.Modules[1].Type).To(Equal("Netlist"))
+		Expect(summary.Modules[1].Result).To(Equal("Fail"))
+		Expect(summary.Modules[1].Message).To(Equal("Update failed."))
+	})
+
+	DescribeTable("will describe exit code and tell if the update completed",
+		func(exitCode int, reason string, completed bool) {
+			Expect(firmwareUpdateSummary(exitCode, nil).ExitReason).To(Equal(reason))
+			Expect(isNvmupdateSuccess(exitCode)).To(Equal(completed))
+		},
+		Entry("success", 0, "All operations completed successfully", true),
+		Entry("invalid parameter", 1, "Invalid command line parameter", false),
+		Entry("configuration file error", 2, "Configuration file error", false),
+		Entry("no update available", 3, "No update available, device already runs firmware provided by the package", true),
+		Entry("image file error", 4, "Image file error, file is missing or corrupted", false),
+		Entry("device communication error", 5, "Unable to communicate with the device", false),
+		Entry("insufficient privileges", 6, "Insufficient privileges to run the utility", false),
+		Entry("no supported devices", 7, "No supported devices found", false),
+		Entry("base driver error", 8, "Base driver error, driver is not loaded or not supported", false),
+		Entry("file creation error", 9, "Unable to create inventory, result or log file", false),
+		Entry("write protected flash", 10, "Flash is write protected", false),
+		Entry("recovery mode", 11, "Update failed, device is in recovery mode", false),
+		Entry("utility already running", 12, "Update failed, another instance of the utility is running", false),
+		Entry("update failed", 13, "Update failed", false),
+		Entry("partial update", 30, "Update completed, but some devices or modules were not updated", true),
+		Entry("reboot required", 50, "Update completed, reboot required to finish the update", true),
+		Entry("power cycle required", 51, "Update completed, power cycle required to finish the update", true),
+		Entry("unknown exit code", 99, "Unknown exit code 99", false),
+	)
+
+	var _ = It("will describe unknown exit code without result file", func() {
+		summary := firmwareUpdateSummary(99, nil)
+		Expect(summary.ExitReason).To(Equal("Unknown exit code 99"))
+		Expect(summary.Modules).To(BeEmpty())
+	})
+})
+
+var _ = Describe("isUpdateAvailable", func() {
+	writeInventory := func(content string) string {
+		tmpfile, err := os.CreateTemp(".", "inventory")
+		Expect(err).ToNot(HaveOccurred())
+		defer tmpfile.Close()
+
+		_, err = tmpfile.Write([]byte(content))
+		Expect(err).ToNot(HaveOccurred())
+		return tmpfile.Name()
+	}
+
+	var _ = It("will report update if any module is marked for update", func() {
+		path := writeInventory(`<?xml version="1.0" encoding="UTF-8"?>
+<DeviceInventory lang="en">
+        <Instance vendor="8086" device="1592" subdevice="2" subvendor="8086" bus="6" dev="0" func="1">
+                <Module type="PXE" version="2.5.0" update="0"></Module>
+                <Module type="NVM" version="800049C3" update="1"></Module>
+        </Instance>
+</DeviceInventory>`)
+		defer os.Remove(path)
+
+		available, err := isUpdateAvailable(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+	})
+
+	var _ = It("will not report update if no module is marked for update", func() {
+		path := writeInventory(`<?xml version="1.0" encoding="UTF-8"?>
+<DeviceInventory lang="en">
+        <Instance vendor="8086" device="1592" subdevice="2" subvendor="8086" bus="6" dev="0" func="1">
+                <Module type="NVM" version="800077A6" update="0"></Module>
+        </Instance>
+</DeviceInventory>`)
+		defer os.Remove(path)
+
+		available, err := isUpdateAvailable(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeFalse())
+	})
+
+	var _ = It("will assume update if availability is not reported", func() {
+		path := writeInventory(`<DeviceInventory><Instance><Module type="NVM" version="800077A6"></Module></Instance></DeviceInventory>`)
+		defer os.Remove(path)
+
+		available, err := isUpdateAvailable(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+	})
+
+	var _ = It("will return error if unable to open file", func() {
+		_, err := isUpdateAvailable("/dev/null/fake")
 		Expect(err).To(HaveOccurred())
 	})
 })
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	"golang.org/x/sys/unix"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	devlinkFlashTimeoutEnvVarName = "DEVLINK_FLASH_TIMEOUT_SECONDS"
+	devlinkFlashTimeoutDefault    = int64(1200)
+
+	// directory relative to firmware search path where NVM images are placed for the kernel to load them
+	devlinkFlashImageDir = "intel/ice/nvm"
+	nvmImageExtension    = ".bin"
+
+	// devlink info version reporting EETrack ID of the NVM, e.g. 0x80008271
+	devlinkBundleIDVersion = "fw.bundle_id"
+)
+
+var (
+	// firmware search path of the host, as mounted in the daemon container
+	firmwareSearchPath = "/lib/firmware"
+
+	// devlinkFlash flashes NVM image, given by its name relative to firmware search path, to the device
+	devlinkFlash = devlinkFlashUpdate
+
+	errNVMImageVersionUnknown = errors.New("version of raw NVM image is not known before it is flashed")
+)
+
+// devlinkFlashOverwrite maps FWFlashOverwrite values to bits of DEVLINK_ATTR_FLASH_UPDATE_OVERWRITE_MASK
+var devlinkFlashOverwrite = map[string]uint32{
+	"Settings":    1 << unix.DEVLINK_FLASH_OVERWRITE_SETTINGS_BIT,
+	"Identifiers": 1 << unix.DEVLINK_FLASH_OVERWRITE_IDENTIFIERS_BIT,
+}
+
+// devlinkFlashBackend flashes raw .bin NVM images with devlink flash update, as devlink dev flash does.
+// Image is loaded by the driver, so neither NVM Update utility nor backup of the NVM is available
+type devlinkFlashBackend struct {
+	*fwUpdater
+}
+
+// unpack does nothing, NVM image is flashed as downloaded
+func (f *devlinkFlashBackend) unpack(string, string) error {
+	return nil
+}
+
+func (f *devlinkFlashBackend) locate(_, pkgPath string) (string, error) {
+	if !strings.HasSuffix(pkgPath, nvmImageExtension) {
+		return "", fmt.Errorf("expected %v NVM image for devlink flash, got %v", nvmImageExtension,
+			filepath.Base(pkgPath))
+	}
+	return pkgPath, nil
+}
+
+// targetVersion is unknown, version of raw image is learned once it is flashed, see flashedImageVersion
+func (f *devlinkFlashBackend) targetVersion(string, string) (string, error) {
+	return "", errNVMImageVersionUnknown
+}
+
+// isUpdateAvailable assumes that the image updates the device, devlink can't compare it with the NVM of the device
+func (f *devlinkFlashBackend) isUpdateAvailable(string, string) (bool, error) {
+	return true, nil
+}
+
+func (f *devlinkFlashBackend) update(pciAddr, fwPath string, config ethernetv1.DeviceConfig,
+	deviceStatus *deviceStatusReporter) (bool, *ethernetv1.FirmwareRollback, error) {
+	log := f.log.WithName("devlinkFlash").WithValues("device", pciAddr)
+
+	overwriteMask, err := flashOverwriteMask(config.FWFlashOverwrite)
+	if err != nil {
+		return false, nil, err
+	}
+
+	// kernel loads the image with request_firmware(), which accepts only paths within firmware search path
+	imageName := filepath.Join(devlinkFlashImageDir, pciAddr+"-"+filepath.Base(fwPath))
+	imagePath := filepath.Join(firmwareSearchPath, imageName)
+	if err := utils.CreateFolder(filepath.Dir(imagePath), log); err != nil {
+		return false, nil, err
+	}
+	if err := utils.CopyFile(fwPath, imagePath); err != nil {
+		return false, nil, fmt.Errorf("failed to place NVM image in firmware search path: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(imagePath); err != nil {
+			log.Error(err, "Failed to remove NVM image from firmware search path", "path", imagePath)
+		}
+	}()
+
+	log.V(2).Info("Starting devlink flash update", "image", imageName, "component", config.FWFlashComponent,
+		"overwrite", config.FWFlashOverwrite)
+	if err := devlinkFlash(pciAddr, imageName, config.FWFlashComponent, overwriteMask, f.flashTimeout); err != nil {
+		err = fmt.Errorf("devlink flash of device %v failed: %v", pciAddr, err)
+		log.Error(err, "Failed to update firmware")
+		return false, f.rollback(pciAddr, "", err), err
+	}
+
+	image, err := flashedImage(pciAddr, fwPath, log)
+	if err != nil {
+		log.Error(err, "Unable to identify flashed NVM image, it will be flashed again on next update")
+	} else {
+		deviceStatus.recordFlashedImage(image)
+	}
+
+	// flashed image is activated by the reset of the device
+	log.V(4).Info("Node reboot required to complete firmware update")
+	return true, nil, nil
+}
+
+// flashedImage identifies image flashed to the device by its checksum and the EETrack ID which the device
+// reports as stored in the NVM
+func flashedImage(pciAddr, fwPath string, log logr.Logger) (ethernetv1.FlashedImage, error) {
+	checksum, err := utils.FileChecksum(fwPath)
+	if err != nil {
+		return ethernetv1.FlashedImage{}, err
+	}
+	info, err := newDeviceInfoProvider(log).devlinkInfo(pciAddr)
+	if err != nil {
+		return ethernetv1.FlashedImage{}, err
+	}
+	version := strings.ToLower(info.stored[devlinkBundleIDVersion])
+	if version == "" {
+		return ethernetv1.FlashedImage{}, fmt.Errorf("device %v doesn't report stored %v", pciAddr,
+			devlinkBundleIDVersion)
+	}
+	return ethernetv1.FlashedImage{PCIAddress: pciAddr, Checksum: checksum, Version: version, Time: metav1.Now()},
+		nil
+}
+
+// flashedImageVersion returns version of the raw NVM image if the same image was already flashed to the device,
+// errNVMImageVersionUnknown otherwise
+func flashedImageVersion(flashed []ethernetv1.FlashedImage, pciAddr, fwPath string) (string, error) {
+	for _, image := range flashed {
+		if image.PCIAddress != pciAddr {
+			continue
+		}
+		checksum, err := utils.FileChecksum(fwPath)
+		if err != nil {
+			return "", err
+		}
+		if checksum == image.Checksum {
+			return image.Version, nil
+		}
+	}
+	return "", errNVMImageVersionUnknown
+}
+
+// flashOverwriteMask returns mask of NVM sections overwritten by the flashed image
+func flashOverwriteMask(overwrite []string) (uint32, error) {
+	var mask uint32
+	for _, section := range overwrite {
+		bit, ok := devlinkFlashOverwrite[section]
+		if !ok {
+			return 0, fmt.Errorf("unknown NVM section to overwrite %q", section)
+		}
+		mask |= bit
+	}
+	return mask, nil
+}
+
+// devlinkFlashUpdate sends DEVLINK_CMD_FLASH_UPDATE and waits until the kernel acknowledges completed flash
+func devlinkFlashUpdate(pciAddr, imageName, component string, overwriteMask uint32, timeout time.Duration) error {
+	conn, err := dialGenl()
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	family, err := conn.resolveFamily(unix.DEVLINK_GENL_NAME)
+	if err != nil {
+		return err
+	}
+	// flash update is acknowledged once the image is written, which takes minutes
+	if err := conn.setReceiveTimeout(timeout); err != nil {
+		return err
+	}
+	_, err = conn.execute(family, unix.DEVLINK_CMD_FLASH_UPDATE, unix.DEVLINK_GENL_VERSION,
+		flashUpdateAttrs(pciAddr, imageName, component, overwriteMask))
+	if err == unix.EAGAIN {
+		return fmt.Errorf("flash update not completed within %v", timeout)
+	}
+	return err
+}
+
+// flashUpdateAttrs returns attributes of DEVLINK_CMD_FLASH_UPDATE request
+func flashUpdateAttrs(pciAddr, imageName, component string, overwriteMask uint32) []nlAttr {
+	attrs := []nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_BUS_NAME, "pci"),
+		stringAttr(unix.DEVLINK_ATTR_DEV_NAME, pciAddr),
+		stringAttr(unix.DEVLINK_ATTR_FLASH_UPDATE_FILE_NAME, imageName),
+	}
+	if component != "" {
+		attrs = append(attrs, stringAttr(unix.DEVLINK_ATTR_FLASH_UPDATE_COMPONENT, component))
+	}
+	if overwriteMask != 0 {
+		// struct nla_bitfield32, selector marks bits of the value which are set by the request
+		bitfield := make([]byte, 8)
+		nativeEndian.PutUint32(bitfield[0:4], overwriteMask)
+		nativeEndian.PutUint32(bitfield[4:8], overwriteMask)
+		attrs = append(attrs, nlAttr{typ: unix.DEVLINK_ATTR_FLASH_UPDATE_OVERWRITE_MASK, data: bitfield})
+	}
+	return attrs
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("devlink flash backend", func() {
+	var (
+		imagePath string
+		backend   firmwareBackend
+
+		origSearchPath = firmwareSearchPath
+		origFlash      = devlinkFlash
+		origProvider   = newDeviceInfoProvider
+	)
+
+	BeforeEach(func() {
+		firmwareSearchPath = GinkgoT().TempDir()
+		imagePath = filepath.Join(GinkgoT().TempDir(), "E810_NVMUpdatePackage_v4_20.bin")
+		Expect(os.WriteFile(imagePath, []byte("nvm image"), 0600)).To(Succeed())
+		newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+			return &fakeInfoProvider{devlink: func(string) (*devlinkDeviceInfo, error) {
+				return &devlinkDeviceInfo{stored: map[string]string{"fw.bundle_id": "0x80008271"}}, nil
+			}}
+		}
+		backend = (&fwUpdater{log: logr.Discard(), flashTimeout: time.Minute}).backend(FWUpdateBackendDevlinkFlash)
+	})
+
+	AfterEach(func() {
+		firmwareSearchPath = origSearchPath
+		devlinkFlash = origFlash
+		newDeviceInfoProvider = origProvider
+	})
+
+	var _ = It("will flash raw NVM image placed in firmware search path", func() {
+		fwPath, err := backend.locate(filepath.Dir(imagePath), imagePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fwPath).To(Equal(imagePath))
+
+		flashed := false
+		devlinkFlash = func(pciAddr, imageName, component string, overwriteMask uint32, timeout time.Duration) error {
+			Expect(pciAddr).To(Equal("0000:18:00.0"))
+			Expect(imageName).To(Equal("intel/ice/nvm/0000:18:00.0-E810_NVMUpdatePackage_v4_20.bin"))
+			Expect(os.ReadFile(filepath.Join(firmwareSearchPath, imageName))).To(Equal([]byte("nvm image")))
+			Expect(component).To(Equal("fw.mgmt"))
+			Expect(overwriteMask).To(Equal(uint32(0x3)))
+			Expect(timeout).To(Equal(time.Minute))
+			flashed = true
+			return nil
+		}
+
+		rebootRequired, rollback, err := backend.update("0000:18:00.0", fwPath, ethernetv1.DeviceConfig{
+			FWUpdateBackend:  FWUpdateBackendDevlinkFlash,
+			FWFlashComponent: "fw.mgmt",
+			FWFlashOverwrite: []string{"Settings", "Identifiers"},
+		}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flashed).To(BeTrue())
+		Expect(rebootRequired).To(BeTrue())
+		Expect(rollback).To(BeNil())
+		Expect(filepath.Join(firmwareSearchPath, devlinkFlashImageDir)).To(BeADirectory())
+		Expect(os.ReadDir(filepath.Join(firmwareSearchPath, devlinkFlashImageDir))).To(BeEmpty())
+	})
+
+	var _ = It("will report unavailable rollback if flash fails", func() {
+		devlinkFlash = func(string, string, string, uint32, time.Duration) error {
+			return unix.EOPNOTSUPP
+		}
+
+		_, rollback, err := backend.update("0000:18:00.0", imagePath, ethernetv1.DeviceConfig{}, nil)
+		Expect(err).To(MatchError(ContainSubstring("devlink flash of device 0000:18:00.0 failed")))
+		Expect(rollback).ToNot(BeNil())
+		Expect(rollback.Result).To(Equal(RollbackUnavailable))
+		Expect(os.ReadDir(filepath.Join(firmwareSearchPath, devlinkFlashImageDir))).To(BeEmpty())
+	})
+
+	var _ = It("will reject packages other than raw NVM image", func() {
+		_, err := backend.locate("/tmp", "/tmp/E810_NVMUpdatePackage_v4_20_Linux.tar.gz")
+		Expect(err).To(HaveOccurred())
+
+		_, err = flashOverwriteMask([]string{"Everything"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	var _ = It("will not know target version nor compare the image with the device", func() {
+		_, err := backend.targetVersion(imagePath, "0000:18:00.0")
+		Expect(errors.Is(err, errNVMImageVersionUnknown)).To(BeTrue())
+
+		available, err := backend.isUpdateAvailable("0000:18:00.0", imagePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+	})
+
+	var _ = It("will skip image which the device already runs", func() {
+		image, err := flashedImage("0000:18:00.0", imagePath, logr.Discard())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(image.PCIAddress).To(Equal("0000:18:00.0"))
+		Expect(image.Checksum).To(Equal("sha256:94a485895a26f21a280d7ca0293608243e50b1a7dad205402120d7c820162f5e"))
+		Expect(image.Version).To(Equal("0x80008271"))
+		flashed := []ethernetv1.FlashedImage{image}
+
+		version, err := flashedImageVersion(flashed, "0000:18:00.0", imagePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal("0x80008271"))
+
+		artifacts := deviceUpdateArtifacts{fwPath: imagePath, targetFWVersion: version}
+		skipCompliantArtifacts(&artifacts, "0000:18:00.0", []ethernetv1.Device{{
+			PCIAddress: "0000:18:00.0",
+			Firmware:   ethernetv1.FirmwareInfo{Version: "4.40 0x80008271 1.3429.0"},
+		}}, logr.Discard())
+		Expect(artifacts.fwPath).To(BeEmpty())
+
+		// image flashed to another device or other image is not known
+		_, err = flashedImageVersion(flashed, "0000:18:00.1", imagePath)
+		Expect(errors.Is(err, errNVMImageVersionUnknown)).To(BeTrue())
+		Expect(os.WriteFile(imagePath, []byte("other nvm image"), 0600)).To(Succeed())
+		_, err = flashedImageVersion(flashed, "0000:18:00.0", imagePath)
+		Expect(errors.Is(err, errNVMImageVersionUnknown)).To(BeTrue())
+	})
+
+	var _ = It("will not identify image if the device doesn't report stored version", func() {
+		newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+			return &fakeInfoProvider{devlink: func(string) (*devlinkDeviceInfo, error) {
+				return &devlinkDeviceInfo{running: map[string]string{"fw.bundle_id": "0x80008270"}}, nil
+			}}
+		}
+		_, err := flashedImage("0000:18:00.0", imagePath, logr.Discard())
+		Expect(err).To(MatchError(ContainSubstring("doesn't report stored fw.bundle_id")))
+	})
+
+	var _ = It("will encode flash update request", func() {
+		attrs := flashUpdateAttrs("0000:18:00.0", "intel/ice/nvm/image.bin", "", 0)
+		Expect(attrs).To(HaveLen(3))
+		Expect(attrs[2].typ).To(Equal(uint16(unix.DEVLINK_ATTR_FLASH_UPDATE_FILE_NAME)))
+		Expect(attrs[2].str()).To(Equal("intel/ice/nvm/image.bin"))
+
+		attrs = flashUpdateAttrs("0000:18:00.0", "intel/ice/nvm/image.bin", "fw.undi",
+			1<<unix.DEVLINK_FLASH_OVERWRITE_SETTINGS_BIT)
+		Expect(attrs).To(HaveLen(5))
+		Expect(attrs[3].str()).To(Equal("fw.undi"))
+		Expect(attrs[4].typ).To(Equal(uint16(unix.DEVLINK_ATTR_FLASH_UPDATE_OVERWRITE_MASK)))
+		Expect(nativeEndian.Uint32(attrs[4].data[0:4])).To(Equal(uint32(1)))
+		Expect(nativeEndian.Uint32(attrs[4].data[4:8])).To(Equal(uint32(1)))
+
+		decoded, err := decodeAttrs(encodeAttrs(attrs))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(attrs))
+	})
+})
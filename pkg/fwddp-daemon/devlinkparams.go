@@ -0,0 +1,553 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// types of devlink parameter values, which are netlink attribute types (enum devlink_var_attr_type)
+const (
+	devlinkParamTypeU8     = 1
+	devlinkParamTypeU16    = 2
+	devlinkParamTypeU32    = 3
+	devlinkParamTypeU64    = 4
+	devlinkParamTypeString = 5
+	devlinkParamTypeBool   = 6
+
+	// devlink reload reinitializes the driver, which takes a while for devices with many queues
+	devlinkReloadTimeout = time.Minute
+)
+
+const (
+	DevlinkParamCModeRuntime    = "runtime"
+	DevlinkParamCModeDriverinit = "driverinit"
+	DevlinkParamCModePermanent  = "permanent"
+)
+
+var devlinkParamCModes = map[uint8]string{
+	unix.DEVLINK_PARAM_CMODE_RUNTIME:    DevlinkParamCModeRuntime,
+	unix.DEVLINK_PARAM_CMODE_DRIVERINIT: DevlinkParamCModeDriverinit,
+	unix.DEVLINK_PARAM_CMODE_PERMANENT:  DevlinkParamCModePermanent,
+}
+
+var (
+	// setDevlinkParam sets value of devlink parameter of the device
+	setDevlinkParam = devlinkParamSet
+	// reloadDevlink reinitializes the driver of the device, which applies driverinit values of parameters
+	reloadDevlink = devlinkReload
+
+	errDevlinkParamsUnavailable = errors.New("devlink parameters are not available without netlink")
+)
+
+// devlinkParam is devlink parameter of the device with its values by configuration mode, as reported by
+// devlink dev param show
+type devlinkParam struct {
+	name   string
+	typ    uint8
+	values map[string]string
+}
+
+// devlinkParamChange is requested value of devlink parameter which differs from the value of the device
+type devlinkParamChange struct {
+	ethernetv1.DevlinkParam
+	typ uint8
+}
+
+func (p *netlinkInfoProvider) devlinkParams(pciAddr string) ([]devlinkParam, error) {
+	conn, err := dialGenl()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	family, err := conn.resolveFamily(unix.DEVLINK_GENL_NAME)
+	if err != nil {
+		return nil, err
+	}
+	// older kernels ignore the device and dump parameters of all devices
+	replies, err := conn.dump(family, unix.DEVLINK_CMD_PARAM_GET, unix.DEVLINK_GENL_VERSION, []nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_BUS_NAME, "pci"),
+		stringAttr(unix.DEVLINK_ATTR_DEV_NAME, pciAddr),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("devlink param show failed for %v: %v", pciAddr, err)
+	}
+
+	var params []devlinkParam
+	for _, attrs := range replies {
+		param, err := parseDevlinkParam(pciAddr, attrs)
+		if err != nil {
+			return nil, err
+		}
+		if param != nil {
+			params = append(params, *param)
+		}
+	}
+	return params, nil
+}
+
+func (p *sysfsInfoProvider) devlinkParams(string) ([]devlinkParam, error) {
+	return nil, errDevlinkParamsUnavailable
+}
+
+func (p *fallbackInfoProvider) devlinkParams(pciAddr string) ([]devlinkParam, error) {
+	params, err := p.primary.devlinkParams(pciAddr)
+	if err == nil {
+		return params, nil
+	}
+	p.log.V(2).Info("failed to get devlink params over netlink, falling back to sysfs", "pciAddress", pciAddr,
+		"error", err.Error())
+	return p.fallback.devlinkParams(pciAddr)
+}
+
+// parseDevlinkParam parses attributes of DEVLINK_CMD_PARAM_GET reply. Nil is returned for parameters
+// of other devices
+func parseDevlinkParam(pciAddr string, attrs []nlAttr) (*devlinkParam, error) {
+	var paramAttrs []nlAttr
+	for _, a := range attrs {
+		switch a.typ {
+		case unix.DEVLINK_ATTR_DEV_NAME:
+			if a.str() != pciAddr {
+				return nil, nil
+			}
+		case unix.DEVLINK_ATTR_PARAM:
+			nested, err := a.nested()
+			if err != nil {
+				return nil, err
+			}
+			paramAttrs = nested
+		}
+	}
+	if paramAttrs == nil {
+		return nil, nil
+	}
+
+	param := &devlinkParam{values: map[string]string{}}
+	var valueList []nlAttr
+	for _, a := range paramAttrs {
+		switch a.typ {
+		case unix.DEVLINK_ATTR_PARAM_NAME:
+			param.name = a.str()
+		case unix.DEVLINK_ATTR_PARAM_TYPE:
+			if len(a.data) < 1 {
+				return nil, errMalformedNetlinkMessage
+			}
+			param.typ = a.data[0]
+		case unix.DEVLINK_ATTR_PARAM_VALUES_LIST:
+			valueList = append(valueList, a)
+		}
+	}
+
+	for _, list := range valueList {
+		values, err := list.nested()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			valueAttrs, err := v.nested()
+			if err != nil {
+				return nil, err
+			}
+			cmode, value, err := parseDevlinkParamValue(param.typ, valueAttrs)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of devlink param %v: %v", param.name, err)
+			}
+			if name, ok := devlinkParamCModes[cmode]; ok {
+				param.values[name] = value
+			}
+		}
+	}
+	return param, nil
+}
+
+// parseDevlinkParamValue returns configuration mode and value of DEVLINK_ATTR_PARAM_VALUE. Value of boolean
+// parameter is true if the data attribute is present
+func parseDevlinkParamValue(typ uint8, attrs []nlAttr) (uint8, string, error) {
+	var cmode uint8
+	var data *nlAttr
+	for i, a := range attrs {
+		switch a.typ {
+		case unix.DEVLINK_ATTR_PARAM_VALUE_CMODE:
+			if len(a.data) < 1 {
+				return 0, "", errMalformedNetlinkMessage
+			}
+			cmode = a.data[0]
+		case unix.DEVLINK_ATTR_PARAM_VALUE_DATA:
+			data = &attrs[i]
+		}
+	}
+
+	if typ == devlinkParamTypeBool {
+		return cmode, strconv.FormatBool(data != nil), nil
+	}
+	if data == nil {
+		return 0, "", errMalformedNetlinkMessage
+	}
+	if typ == devlinkParamTypeString {
+		return cmode, data.str(), nil
+	}
+	size, err := devlinkParamSize(typ)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(data.data) < size {
+		return 0, "", errMalformedNetlinkMessage
+	}
+	var n uint64
+	switch size {
+	case 1:
+		n = uint64(data.data[0])
+	case 2:
+		n = uint64(nativeEndian.Uint16(data.data))
+	case 4:
+		n = uint64(nativeEndian.Uint32(data.data))
+	default:
+		n = nativeEndian.Uint64(data.data)
+	}
+	return cmode, strconv.FormatUint(n, 10), nil
+}
+
+// encodeDevlinkParamValue returns data attribute of the value, which is left out for false boolean values
+func encodeDevlinkParamValue(typ uint8, value string) ([]nlAttr, error) {
+	switch typ {
+	case devlinkParamTypeBool:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return nil, nil
+		}
+		return []nlAttr{{typ: unix.DEVLINK_ATTR_PARAM_VALUE_DATA}}, nil
+	case devlinkParamTypeString:
+		return []nlAttr{stringAttr(unix.DEVLINK_ATTR_PARAM_VALUE_DATA, value)}, nil
+	}
+
+	size, err := devlinkParamSize(typ)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseUint(value, 0, size*8)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	switch size {
+	case 1:
+		data[0] = uint8(n)
+	case 2:
+		nativeEndian.PutUint16(data, uint16(n))
+	case 4:
+		nativeEndian.PutUint32(data, uint32(n))
+	default:
+		nativeEndian.PutUint64(data, n)
+	}
+	return []nlAttr{{typ: unix.DEVLINK_ATTR_PARAM_VALUE_DATA, data: data}}, nil
+}
+
+func devlinkParamSize(typ uint8) (int, error) {
+	switch typ {
+	case devlinkParamTypeU8:
+		return 1, nil
+	case devlinkParamTypeU16:
+		return 2, nil
+	case devlinkParamTypeU32:
+		return 4, nil
+	case devlinkParamTypeU64:
+		return 8, nil
+	}
+	return 0, fmt.Errorf("unsupported devlink param type %v", typ)
+}
+
+// normalizeDevlinkParamValue formats requested value as values of the type are reported by the device,
+// e.g. 0x10 as 16 or 1 as true
+func normalizeDevlinkParamValue(typ uint8, value string) (string, error) {
+	switch typ {
+	case devlinkParamTypeBool:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(enabled), nil
+	case devlinkParamTypeString:
+		return value, nil
+	}
+	size, err := devlinkParamSize(typ)
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.ParseUint(value, 0, size*8)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(n, 10), nil
+}
+
+// pendingDevlinkParams returns requested values of devlink parameters which differ from values of the device.
+// Error is returned if the device doesn't support the parameter in the requested mode or the value is invalid
+func pendingDevlinkParams(requested []ethernetv1.DevlinkParam, params []devlinkParam) ([]devlinkParamChange, error) {
+	var changes []devlinkParamChange
+	for _, r := range requested {
+		var param *devlinkParam
+		for i := range params {
+			if params[i].name == r.Name {
+				param = &params[i]
+				break
+			}
+		}
+		if param == nil {
+			return nil, fmt.Errorf("devlink param %v is not supported by the device", r.Name)
+		}
+		current, ok := param.values[r.CMode]
+		if !ok {
+			return nil, fmt.Errorf("devlink param %v does not support %v cmode", r.Name, r.CMode)
+		}
+		value, err := normalizeDevlinkParamValue(param.typ, r.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q of devlink param %v: %v", r.Value, r.Name, err)
+		}
+		if value != current {
+			r.Value = value
+			changes = append(changes, devlinkParamChange{DevlinkParam: r, typ: param.typ})
+		}
+	}
+	return changes, nil
+}
+
+// devlinkParamsStatus converts parameters of the device to values reported in the inventory
+func devlinkParamsStatus(params []devlinkParam) []ethernetv1.DevlinkParam {
+	var status []ethernetv1.DevlinkParam
+	for _, p := range params {
+		for _, cmode := range []string{DevlinkParamCModeRuntime, DevlinkParamCModeDriverinit,
+			DevlinkParamCModePermanent} {
+			if value, ok := p.values[cmode]; ok {
+				status = append(status, ethernetv1.DevlinkParam{Name: p.name, Value: value, CMode: cmode})
+			}
+		}
+	}
+	sort.SliceStable(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
+}
+
+// applyDevlinkParams sets changed values of devlink parameters of the device. Driverinit values are applied
+// by devlink reload, unless node reboot follows, which would reset them; they are set again after the reboot.
+// Set values are read back from the device and error is returned if any of them still differs.
+// True is returned if node reboot is required to apply permanent values
+func applyDevlinkParams(pciAddr string, changes []devlinkParamChange, rebootPending bool, log logr.Logger) (bool,
+	error) {
+	log = log.WithName("applyDevlinkParams").WithValues("device", pciAddr)
+
+	reload, reboot := false, false
+	var applied []ethernetv1.DevlinkParam
+	for _, c := range changes {
+		if c.CMode == DevlinkParamCModeDriverinit && rebootPending {
+			log.V(2).Info("Node reboot pending, driverinit value will be set after the reboot", "param", c.Name)
+			continue
+		}
+		log.V(2).Info("Setting devlink param", "param", c.Name, "value", c.Value, "cmode", c.CMode)
+		if err := setDevlinkParam(pciAddr, c); err != nil {
+			return false, fmt.Errorf("failed to set devlink param %v of device %v: %v", c.Name, pciAddr, err)
+		}
+		applied = append(applied, c.DevlinkParam)
+		switch c.CMode {
+		case DevlinkParamCModeDriverinit:
+			reload = true
+		case DevlinkParamCModePermanent:
+			reboot = true
+		}
+	}
+
+	if reload {
+		log.V(2).Info("Reloading device to apply driverinit values")
+		if err := reloadDevlink(pciAddr); err != nil {
+			return false, fmt.Errorf("devlink reload of device %v failed: %v", pciAddr, err)
+		}
+	}
+
+	if len(applied) != 0 {
+		if err := verifyDevlinkParams(pciAddr, applied, log); err != nil {
+			return false, err
+		}
+	}
+	return reboot, nil
+}
+
+// verifyDevlinkParams reads devlink params of the device and returns error if any of applied values is not reported
+func verifyDevlinkParams(pciAddr string, applied []ethernetv1.DevlinkParam, log logr.Logger) error {
+	params, err := newDeviceInfoProvider(log).devlinkParams(pciAddr)
+	if err != nil {
+		return fmt.Errorf("failed to verify devlink params of device %v: %v", pciAddr, err)
+	}
+	pending, err := pendingDevlinkParams(applied, params)
+	if err != nil {
+		return fmt.Errorf("failed to verify devlink params of device %v: %v", pciAddr, err)
+	}
+	if len(pending) != 0 {
+		names := make([]string, 0, len(pending))
+		for _, c := range pending {
+			names = append(names, fmt.Sprintf("%v (%v)", c.Name, c.CMode))
+		}
+		return fmt.Errorf("devlink params of device %v not applied: %v", pciAddr, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// devlinkParamSet sends DEVLINK_CMD_PARAM_SET
+func devlinkParamSet(pciAddr string, change devlinkParamChange) error {
+	var cmode uint8
+	for mode, name := range devlinkParamCModes {
+		if name == change.CMode {
+			cmode = mode
+		}
+	}
+	value, err := encodeDevlinkParamValue(change.typ, change.Value)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialGenl()
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	family, err := conn.resolveFamily(unix.DEVLINK_GENL_NAME)
+	if err != nil {
+		return err
+	}
+	attrs := append([]nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_BUS_NAME, "pci"),
+		stringAttr(unix.DEVLINK_ATTR_DEV_NAME, pciAddr),
+		stringAttr(unix.DEVLINK_ATTR_PARAM_NAME, change.Name),
+		{typ: unix.DEVLINK_ATTR_PARAM_TYPE, data: []byte{change.typ}},
+		{typ: unix.DEVLINK_ATTR_PARAM_VALUE_CMODE, data: []byte{cmode}},
+	}, value...)
+	_, err = conn.execute(family, unix.DEVLINK_CMD_PARAM_SET, unix.DEVLINK_GENL_VERSION, attrs)
+	return err
+}
+
+// devlinkReload sends DEVLINK_CMD_RELOAD with driver_reinit action
+func devlinkReload(pciAddr string) error {
+	conn, err := dialGenl()
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	family, err := conn.resolveFamily(unix.DEVLINK_GENL_NAME)
+	if err != nil {
+		return err
+	}
+	if err := conn.setReceiveTimeout(devlinkReloadTimeout); err != nil {
+		return err
+	}
+	_, err = conn.execute(family, unix.DEVLINK_CMD_RELOAD, unix.DEVLINK_GENL_VERSION, []nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_BUS_NAME, "pci"),
+		stringAttr(unix.DEVLINK_ATTR_DEV_NAME, pciAddr),
+		{typ: unix.DEVLINK_ATTR_RELOAD_ACTION, data: []byte{unix.DEVLINK_RELOAD_ACTION_DRIVER_REINIT}},
+	})
+	return err
+}
+
+// setDevlinkParams applies changed values of devlink params of the device, recording the outcome in its status.
+// True is returned if node reboot is required to apply them
+func (r *NodeConfigReconciler) setDevlinkParams(pciAddr string, changes []devlinkParamChange, rebootPending bool,
+	deviceStatus *deviceStatusReporter) (bool, error) {
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	deviceStatus.setPhase(DevicePhaseSettingParams)
+	reboot, err := applyDevlinkParams(pciAddr, changes, rebootPending, r.log)
+	if err != nil {
+		deviceStatus.event(corev1.EventTypeWarning, EventDevlinkParamsFailed, "Setting devlink params failed: %v", err)
+		updateFailures.WithLabelValues(failureReasonDevlinkParamsFailed).Inc()
+		return false, err
+	}
+
+	names := make([]string, 0, len(changes))
+	for _, c := range changes {
+		names = append(names, c.Name)
+	}
+	deviceStatus.event(corev1.EventTypeNormal, EventDevlinkParamsSet, "Devlink params %v set, reboot required: %v",
+		strings.Join(names, ", "), reboot)
+	return reboot, nil
+}
+
+// runtimeDevlinkParamsOnly is true if all changes are applied by the driver right away
+func runtimeDevlinkParamsOnly(changes []devlinkParamChange) bool {
+	for _, c := range changes {
+		if !c.IsRuntime() {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRuntimeDevlinkParams sets devlink params of queued devices, which require only runtime values to be
+// changed, and removes them from updateQueue. Runtime values are applied by the driver right away, the node is
+// not drained for them
+func (r *NodeConfigReconciler) applyRuntimeDevlinkParams(nodeConfig *ethernetv1.EthernetNodeConfig,
+	updateQueue deviceUpdateQueue) error {
+	for pciAddr, artifacts := range updateQueue {
+		if artifacts.fwPath != "" || artifacts.ddpPath != "" || artifacts.ddpRevert ||
+			!runtimeDevlinkParamsOnly(artifacts.devlinkParams) {
+			continue
+		}
+		deviceStatus := r.deviceStatus(nodeConfig, pciAddr)
+		if _, err := r.setDevlinkParams(pciAddr, artifacts.devlinkParams, false, deviceStatus); err != nil {
+			deviceStatus.fail(err)
+			return err
+		}
+		deviceStatus.setPhase(DevicePhaseSucceeded)
+		delete(updateQueue, pciAddr)
+	}
+	return nil
+}
+
+// reapplyDevlinkParams sets again runtime and driverinit values of devlink params configured for devices of the
+// node, as they are reset by the reboot. Permanent values are kept in the NVM
+func (r *NodeConfigReconciler) reapplyDevlinkParams(nodeConfig *ethernetv1.EthernetNodeConfig) error {
+	log := r.log.WithName("reapplyDevlinkParams")
+
+	for _, config := range nodeConfig.Spec.Config {
+		if config.DryRun || len(config.DeviceConfig.DevlinkParams) == 0 {
+			continue
+		}
+		params, err := newDeviceInfoProvider(log).devlinkParams(config.PCIAddress)
+		if err != nil {
+			return fmt.Errorf("failed to get devlink params of device %v: %v", config.PCIAddress, err)
+		}
+		changes, err := pendingDevlinkParams(config.DeviceConfig.DevlinkParams, params)
+		if err != nil {
+			return err
+		}
+
+		var reset []devlinkParamChange
+		for _, c := range changes {
+			if c.CMode != DevlinkParamCModePermanent {
+				reset = append(reset, c)
+			}
+		}
+		if len(reset) == 0 {
+			continue
+		}
+		log.V(2).Info("Setting devlink params reset by the reboot", "device", config.PCIAddress)
+		if _, err := applyDevlinkParams(config.PCIAddress, reset, false, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
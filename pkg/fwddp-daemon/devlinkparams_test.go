@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// testParamReply returns attributes of DEVLINK_CMD_PARAM_GET reply with given values of the parameter
+func testParamReply(pciAddr, name string, typ uint8, values ...[]nlAttr) []nlAttr {
+	var valueList []nlAttr
+	for _, v := range values {
+		valueList = append(valueList, nlAttr{typ: unix.DEVLINK_ATTR_PARAM_VALUE, data: encodeAttrs(v)})
+	}
+	param := []nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_PARAM_NAME, name),
+		{typ: unix.DEVLINK_ATTR_PARAM_TYPE, data: []byte{typ}},
+		{typ: unix.DEVLINK_ATTR_PARAM_VALUES_LIST, data: encodeAttrs(valueList)},
+	}
+	return []nlAttr{
+		stringAttr(unix.DEVLINK_ATTR_BUS_NAME, "pci"),
+		stringAttr(unix.DEVLINK_ATTR_DEV_NAME, pciAddr),
+		{typ: unix.DEVLINK_ATTR_PARAM, data: encodeAttrs(param)},
+	}
+}
+
+func testParamValue(cmode uint8, data ...nlAttr) []nlAttr {
+	return append([]nlAttr{{typ: unix.DEVLINK_ATTR_PARAM_VALUE_CMODE, data: []byte{cmode}}}, data...)
+}
+
+var _ = Describe("devlink params", func() {
+	log := ctrl.Log.WithName("FirmwareDaemon-test")
+
+	params := []devlinkParam{
+		{name: "enable_roce", typ: devlinkParamTypeBool, values: map[string]string{"runtime": "false"}},
+		{name: "msix_vec_per_pf_max", typ: devlinkParamTypeU32, values: map[string]string{"driverinit": "64"}},
+		{name: "tx_scheduling_layers", typ: devlinkParamTypeU8, values: map[string]string{"permanent": "9"}},
+	}
+
+	var _ = It("will parse parameter values of the device", func() {
+		u32 := make([]byte, 4)
+		nativeEndian.PutUint32(u32, 64)
+		reply := testParamReply("0000:18:00.0", "msix_vec_per_pf_max", devlinkParamTypeU32,
+			testParamValue(unix.DEVLINK_PARAM_CMODE_DRIVERINIT,
+				nlAttr{typ: unix.DEVLINK_ATTR_PARAM_VALUE_DATA, data: u32}))
+		param, err := parseDevlinkParam("0000:18:00.0", reply)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*param).To(Equal(params[1]))
+
+		// boolean value is true if data attribute is present
+		reply = testParamReply("0000:18:00.0", "enable_roce", devlinkParamTypeBool,
+			testParamValue(unix.DEVLINK_PARAM_CMODE_RUNTIME),
+			testParamValue(unix.DEVLINK_PARAM_CMODE_DRIVERINIT, nlAttr{typ: unix.DEVLINK_ATTR_PARAM_VALUE_DATA}))
+		param, err = parseDevlinkParam("0000:18:00.0", reply)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(param.values).To(Equal(map[string]string{"runtime": "false", "driverinit": "true"}))
+
+		param, err = parseDevlinkParam("0000:18:00.1", reply)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(param).To(BeNil())
+	})
+
+	var _ = It("will encode values by parameter type", func() {
+		attrs, err := encodeDevlinkParamValue(devlinkParamTypeBool, "true")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attrs).To(Equal([]nlAttr{{typ: unix.DEVLINK_ATTR_PARAM_VALUE_DATA}}))
+
+		attrs, err = encodeDevlinkParamValue(devlinkParamTypeBool, "false")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attrs).To(BeEmpty())
+
+		attrs, err = encodeDevlinkParamValue(devlinkParamTypeU16, "0x100")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attrs).To(HaveLen(1))
+		Expect(nativeEndian.Uint16(attrs[0].data)).To(Equal(uint16(256)))
+
+		_, err = encodeDevlinkParamValue(devlinkParamTypeU8, "256")
+		Expect(err).To(HaveOccurred())
+	})
+
+	var _ = It("will return only values differing from the device", func() {
+		changes, err := pendingDevlinkParams([]ethernetv1.DevlinkParam{
+			{Name: "enable_roce", Value: "1", CMode: "runtime"},
+			{Name: "msix_vec_per_pf_max", Value: "0x40", CMode: "driverinit"},
+			{Name: "tx_scheduling_layers", Value: "5", CMode: "permanent"},
+		}, params)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(Equal([]devlinkParamChange{
+			{DevlinkParam: ethernetv1.DevlinkParam{Name: "enable_roce", Value: "true", CMode: "runtime"},
+				typ: devlinkParamTypeBool},
+			{DevlinkParam: ethernetv1.DevlinkParam{Name: "tx_scheduling_layers", Value: "5", CMode: "permanent"},
+				typ: devlinkParamTypeU8},
+		}))
+		Expect(runtimeDevlinkParamsOnly(changes)).To(BeFalse())
+		Expect(runtimeDevlinkParamsOnly(changes[:1])).To(BeTrue())
+	})
+
+	var _ = It("will reject unsupported parameters, modes and values", func() {
+		_, err := pendingDevlinkParams([]ethernetv1.DevlinkParam{{Name: "enable_iwarp", Value: "true",
+			CMode: "runtime"}}, params)
+		Expect(err).To(MatchError(ContainSubstring("not supported")))
+
+		_, err = pendingDevlinkParams([]ethernetv1.DevlinkParam{{Name: "enable_roce", Value: "true",
+			CMode: "permanent"}}, params)
+		Expect(err).To(MatchError(ContainSubstring("does not support permanent cmode")))
+
+		_, err = pendingDevlinkParams([]ethernetv1.DevlinkParam{{Name: "tx_scheduling_layers", Value: "five",
+			CMode: "permanent"}}, params)
+		Expect(err).To(MatchError(ContainSubstring("invalid value")))
+	})
+
+	var _ = Context("applyDevlinkParams", func() {
+		origSet, origReload, origProvider := setDevlinkParam, reloadDevlink, newDeviceInfoProvider
+		var set []string
+		var reloaded bool
+		// device keeps values of params set by setDevlinkParam
+		var device []devlinkParam
+
+		BeforeEach(func() {
+			set, reloaded = nil, false
+			device = nil
+			for _, p := range params {
+				values := map[string]string{}
+				for cmode, value := range p.values {
+					values[cmode] = value
+				}
+				device = append(device, devlinkParam{name: p.name, typ: p.typ, values: values})
+			}
+			setDevlinkParam = func(pciAddr string, change devlinkParamChange) error {
+				set = append(set, change.Name)
+				for _, p := range device {
+					if p.name == change.Name {
+						p.values[change.CMode] = change.Value
+					}
+				}
+				return nil
+			}
+			reloadDevlink = func(pciAddr string) error {
+				reloaded = true
+				return nil
+			}
+			newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+				return &fakeInfoProvider{params: func(string) ([]devlinkParam, error) {
+					return device, nil
+				}}
+			}
+		})
+
+		AfterEach(func() {
+			setDevlinkParam, reloadDevlink, newDeviceInfoProvider = origSet, origReload, origProvider
+		})
+
+		changes := []devlinkParamChange{
+			{DevlinkParam: ethernetv1.DevlinkParam{Name: "enable_roce", Value: "true", CMode: "runtime"}},
+			{DevlinkParam: ethernetv1.DevlinkParam{Name: "msix_vec_per_pf_max", Value: "32", CMode: "driverinit"}},
+		}
+
+		var _ = It("will reload device to apply driverinit values", func() {
+			reboot, err := applyDevlinkParams("0000:18:00.0", changes, false, log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reboot).To(BeFalse())
+			Expect(set).To(Equal([]string{"enable_roce", "msix_vec_per_pf_max"}))
+			Expect(reloaded).To(BeTrue())
+		})
+
+		var _ = It("will leave driverinit values for after pending reboot", func() {
+			reboot, err := applyDevlinkParams("0000:18:00.0", changes, true, log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reboot).To(BeFalse())
+			Expect(set).To(Equal([]string{"enable_roce"}))
+			Expect(reloaded).To(BeFalse())
+		})
+
+		var _ = It("will require reboot for permanent values", func() {
+			reboot, err := applyDevlinkParams("0000:18:00.0", []devlinkParamChange{{DevlinkParam: ethernetv1.DevlinkParam{
+				Name: "tx_scheduling_layers", Value: "5", CMode: "permanent"}}}, false, log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reboot).To(BeTrue())
+			Expect(reloaded).To(BeFalse())
+		})
+
+		var _ = It("will return error if value can't be set", func() {
+			setDevlinkParam = func(string, devlinkParamChange) error { return unix.EINVAL }
+			_, err := applyDevlinkParams("0000:18:00.0", changes, false, log)
+			Expect(err).To(MatchError(ContainSubstring("failed to set devlink param enable_roce")))
+		})
+
+		var _ = It("will return error if device doesn't report set value", func() {
+			setDevlinkParam = func(pciAddr string, change devlinkParamChange) error {
+				set = append(set, change.Name)
+				return nil
+			}
+			_, err := applyDevlinkParams("0000:18:00.0", changes, false, log)
+			Expect(err).To(MatchError("devlink params of device 0000:18:00.0 not applied: enable_roce (runtime), " +
+				"msix_vec_per_pf_max (driverinit)"))
+			Expect(set).To(Equal([]string{"enable_roce", "msix_vec_per_pf_max"}))
+
+			// values left for after pending reboot are not verified
+			set = nil
+			_, err = applyDevlinkParams("0000:18:00.0", changes[1:], true, log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(set).To(BeEmpty())
+		})
+	})
+
+	var _ = It("will report values of parameters in the inventory", func() {
+		device := ethernetv1.Device{PCIAddress: "0000:18:00.0"}
+		addDevlinkParams(log, &fakeInfoProvider{params: func(string) ([]devlinkParam, error) {
+			return params, nil
+		}}, &device)
+		Expect(device.DevlinkParams).To(Equal([]ethernetv1.DevlinkParam{
+			{Name: "enable_roce", Value: "false", CMode: "runtime"},
+			{Name: "msix_vec_per_pf_max", Value: "64", CMode: "driverinit"},
+			{Name: "tx_scheduling_layers", Value: "9", CMode: "permanent"},
+		}))
+
+		device = ethernetv1.Device{PCIAddress: "0000:18:00.0"}
+		addDevlinkParams(log, &fakeInfoProvider{}, &device)
+		Expect(device.DevlinkParams).To(BeNil())
+	})
+})
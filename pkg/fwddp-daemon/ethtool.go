@@ -0,0 +1,532 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	FECModeAuto  = "Auto"
+	FECModeOff   = "Off"
+	FECModeRS    = "RS"
+	FECModeBaseR = "BaseR"
+
+	// string set of driver private flags, ETH_SS_PRIV_FLAGS
+	ethtoolStringSetPrivFlags = 2
+	ethtoolStringLen          = 32
+	// link_mode_masks_nwords is s8, masks can't be longer than 127 words
+	ethtoolLinkModeMaskMaxWords = 127
+
+	ethtoolAutonegDisable = 0
+	ethtoolAutonegEnable  = 1
+	ethtoolDuplexFull     = 1
+	// SPEED_UNKNOWN, reported while the link is down
+	ethtoolSpeedUnknown = 0xffffffff
+)
+
+// fecModes maps FECMode values to ETHTOOL_FEC_* bits, in the order modes are reported in
+var fecModes = []struct {
+	name string
+	bit  uint32
+}{
+	{FECModeAuto, unix.ETHTOOL_FEC_AUTO},
+	{FECModeOff, unix.ETHTOOL_FEC_OFF},
+	{FECModeRS, unix.ETHTOOL_FEC_RS},
+	{FECModeBaseR, unix.ETHTOOL_FEC_BASER},
+}
+
+// struct ethtool_ringparam
+type ethtoolRingParam struct {
+	cmd               uint32
+	rxMaxPending      uint32
+	rxMiniMaxPending  uint32
+	rxJumboMaxPending uint32
+	txMaxPending      uint32
+	rxPending         uint32
+	rxMiniPending     uint32
+	rxJumboPending    uint32
+	txPending         uint32
+}
+
+// struct ethtool_channels
+type ethtoolChannels struct {
+	cmd           uint32
+	maxRx         uint32
+	maxTx         uint32
+	maxOther      uint32
+	maxCombined   uint32
+	rxCount       uint32
+	txCount       uint32
+	otherCount    uint32
+	combinedCount uint32
+}
+
+// struct ethtool_coalesce, fields not managed by the daemon are preserved as read from the driver
+type ethtoolCoalesce struct {
+	cmd                   uint32
+	rxCoalesceUsecs       uint32
+	rxMaxCoalescedFrames  uint32
+	rxIrq                 [2]uint32
+	txCoalesceUsecs       uint32
+	txMaxCoalescedFrames  uint32
+	txIrq                 [2]uint32
+	statsBlockUsecs       uint32
+	useAdaptiveRxCoalesce uint32
+	useAdaptiveTxCoalesce uint32
+	rateSampling          [11]uint32
+}
+
+// struct ethtool_pauseparam
+type ethtoolPauseParam struct {
+	cmd     uint32
+	autoneg uint32
+	rxPause uint32
+	txPause uint32
+}
+
+// struct ethtool_fecparam, fec is the configured mode and activeFEC the one negotiated on the link
+type ethtoolFECParam struct {
+	cmd       uint32
+	activeFEC uint32
+	fec       uint32
+	reserved  uint32
+}
+
+// struct ethtool_value
+type ethtoolValue struct {
+	cmd  uint32
+	data uint32
+}
+
+// struct ethtool_link_settings followed by supported, advertising and lp_advertising link mode masks
+type ethtoolLinkSettings struct {
+	cmd                 uint32
+	speed               uint32
+	duplex              uint8
+	port                uint8
+	phyAddress          uint8
+	autoneg             uint8
+	mdioSupport         uint8
+	ethTpMdix           uint8
+	ethTpMdixCtrl       uint8
+	linkModeMasksNwords int8
+	transceiver         uint8
+	masterSlaveCfg      uint8
+	masterSlaveState    uint8
+	rateMatching        uint8
+	reserved            [7]uint32
+	linkModeMasks       [3 * ethtoolLinkModeMaskMaxWords]uint32
+}
+
+// ifreqData is struct ifreq with ifr_data pointing to the ethtool command
+type ifreqData struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ethtoolConn applies ethtool commands to network interfaces with SIOCETHTOOL ioctl, as ethtool does
+// for drivers without ethtool netlink support
+type ethtoolConn struct {
+	fd int
+}
+
+func dialEthtool() (portConfigurator, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &ethtoolConn{fd: fd}, nil
+}
+
+func (c *ethtoolConn) close() error {
+	return unix.Close(c.fd)
+}
+
+// ioctl executes ethtool command which data points to. The command is the first field of data
+func (c *ethtoolConn) ioctl(ifName string, data unsafe.Pointer) error {
+	if len(ifName) >= unix.IFNAMSIZ {
+		return fmt.Errorf("invalid interface name %q", ifName)
+	}
+	ifr := ifreqData{data: data}
+	copy(ifr.name[:], ifName)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(c.fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// observe returns settings of the interface which are requested in desired
+func (c *ethtoolConn) observe(ifName string, desired ethernetv1.PortSettings) (ethernetv1.PortSettings, error) {
+	var observed ethernetv1.PortSettings
+
+	if desired.RxRingSize != nil || desired.TxRingSize != nil {
+		ring := ethtoolRingParam{cmd: unix.ETHTOOL_GRINGPARAM}
+		if err := c.ioctl(ifName, unsafe.Pointer(&ring)); err != nil {
+			return observed, fmt.Errorf("failed to get ring sizes: %v", err)
+		}
+		observed.RxRingSize, observed.TxRingSize = intPtr(ring.rxPending), intPtr(ring.txPending)
+	}
+	if desired.CombinedChannels != nil {
+		channels := ethtoolChannels{cmd: unix.ETHTOOL_GCHANNELS}
+		if err := c.ioctl(ifName, unsafe.Pointer(&channels)); err != nil {
+			return observed, fmt.Errorf("failed to get channels: %v", err)
+		}
+		observed.CombinedChannels = intPtr(channels.combinedCount)
+	}
+	if desired.Coalescing != nil {
+		coalesce := ethtoolCoalesce{cmd: unix.ETHTOOL_GCOALESCE}
+		if err := c.ioctl(ifName, unsafe.Pointer(&coalesce)); err != nil {
+			return observed, fmt.Errorf("failed to get interrupt coalescing: %v", err)
+		}
+		observed.Coalescing = &ethernetv1.CoalescingSettings{
+			AdaptiveRx: boolPtr(coalesce.useAdaptiveRxCoalesce),
+			AdaptiveTx: boolPtr(coalesce.useAdaptiveTxCoalesce),
+			RxUsecs:    intPtr(coalesce.rxCoalesceUsecs),
+			TxUsecs:    intPtr(coalesce.txCoalesceUsecs),
+		}
+	}
+	if desired.FECMode != "" {
+		fec := ethtoolFECParam{cmd: unix.ETHTOOL_GFECPARAM}
+		if err := c.ioctl(ifName, unsafe.Pointer(&fec)); err != nil {
+			return observed, fmt.Errorf("failed to get FEC mode: %v", err)
+		}
+		observed.FECMode = fecModeName(fec.fec)
+	}
+	if desired.Autoneg != nil || desired.Speed != nil {
+		link, err := c.linkSettings(ifName)
+		if err != nil {
+			return observed, fmt.Errorf("failed to get link settings: %v", err)
+		}
+		observed.Autoneg, observed.Speed = boolPtr(uint32(link.autoneg)), speedPtr(link.speed)
+	}
+	if desired.Pause != nil {
+		pause := ethtoolPauseParam{cmd: unix.ETHTOOL_GPAUSEPARAM}
+		if err := c.ioctl(ifName, unsafe.Pointer(&pause)); err != nil {
+			return observed, fmt.Errorf("failed to get pause frames: %v", err)
+		}
+		observed.Pause = &ethernetv1.PauseSettings{
+			Autoneg: boolPtr(pause.autoneg),
+			Rx:      boolPtr(pause.rxPause),
+			Tx:      boolPtr(pause.txPause),
+		}
+	}
+	if len(desired.PrivateFlags) != 0 {
+		names, flags, err := c.privateFlags(ifName)
+		if err != nil {
+			return observed, fmt.Errorf("failed to get private flags: %v", err)
+		}
+		observed.PrivateFlags = map[string]bool{}
+		for i, name := range names {
+			observed.PrivateFlags[name] = flags&(1<<i) != 0
+		}
+	}
+	return observed, nil
+}
+
+// apply changes settings of the interface which differ from ones requested in desired. Each group of settings,
+// read and set by a single ethtool command, is applied independently of failures of others
+func (c *ethtoolConn) apply(ifName string, desired ethernetv1.PortSettings) error {
+	var groups []func() error
+	add := func(requested bool, apply func(string, ethernetv1.PortSettings) error) {
+		if requested {
+			groups = append(groups, func() error { return apply(ifName, desired) })
+		}
+	}
+	add(desired.RxRingSize != nil || desired.TxRingSize != nil, c.applyRingSizes)
+	add(desired.CombinedChannels != nil, c.applyChannels)
+	add(desired.Coalescing != nil, c.applyCoalescing)
+	add(desired.FECMode != "", c.applyFECMode)
+	add(desired.Autoneg != nil || desired.Speed != nil, c.applyLinkSettings)
+	add(desired.Pause != nil, c.applyPause)
+	add(len(desired.PrivateFlags) != 0, c.applyPrivateFlags)
+	return applyPortSettingsGroups(groups)
+}
+
+// applyPortSettingsGroups applies all groups of settings, returning errors of all failed ones
+func applyPortSettingsGroups(groups []func() error) error {
+	var errs []string
+	for _, apply := range groups {
+		if err := apply(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (c *ethtoolConn) applyRingSizes(ifName string, desired ethernetv1.PortSettings) error {
+	ring := ethtoolRingParam{cmd: unix.ETHTOOL_GRINGPARAM}
+	if err := c.ioctl(ifName, unsafe.Pointer(&ring)); err != nil {
+		return fmt.Errorf("failed to get ring sizes: %v", err)
+	}
+	changed := ring
+	setUint32(&changed.rxPending, desired.RxRingSize)
+	setUint32(&changed.txPending, desired.TxRingSize)
+	if changed != ring {
+		changed.cmd = unix.ETHTOOL_SRINGPARAM
+		if err := c.ioctl(ifName, unsafe.Pointer(&changed)); err != nil {
+			return fmt.Errorf("failed to set ring sizes: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *ethtoolConn) applyChannels(ifName string, desired ethernetv1.PortSettings) error {
+	channels := ethtoolChannels{cmd: unix.ETHTOOL_GCHANNELS}
+	if err := c.ioctl(ifName, unsafe.Pointer(&channels)); err != nil {
+		return fmt.Errorf("failed to get channels: %v", err)
+	}
+	changed := channels
+	setUint32(&changed.combinedCount, desired.CombinedChannels)
+	if changed != channels {
+		changed.cmd = unix.ETHTOOL_SCHANNELS
+		if err := c.ioctl(ifName, unsafe.Pointer(&changed)); err != nil {
+			return fmt.Errorf("failed to set channels: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *ethtoolConn) applyCoalescing(ifName string, desired ethernetv1.PortSettings) error {
+	coalesce := ethtoolCoalesce{cmd: unix.ETHTOOL_GCOALESCE}
+	if err := c.ioctl(ifName, unsafe.Pointer(&coalesce)); err != nil {
+		return fmt.Errorf("failed to get interrupt coalescing: %v", err)
+	}
+	changed := coalesce
+	setBool(&changed.useAdaptiveRxCoalesce, desired.Coalescing.AdaptiveRx)
+	setBool(&changed.useAdaptiveTxCoalesce, desired.Coalescing.AdaptiveTx)
+	setUint32(&changed.rxCoalesceUsecs, desired.Coalescing.RxUsecs)
+	setUint32(&changed.txCoalesceUsecs, desired.Coalescing.TxUsecs)
+	if changed != coalesce {
+		changed.cmd = unix.ETHTOOL_SCOALESCE
+		if err := c.ioctl(ifName, unsafe.Pointer(&changed)); err != nil {
+			return fmt.Errorf("failed to set interrupt coalescing: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *ethtoolConn) applyFECMode(ifName string, desired ethernetv1.PortSettings) error {
+	bit, err := fecModeBit(desired.FECMode)
+	if err != nil {
+		return err
+	}
+	fec := ethtoolFECParam{cmd: unix.ETHTOOL_GFECPARAM}
+	if err := c.ioctl(ifName, unsafe.Pointer(&fec)); err != nil {
+		return fmt.Errorf("failed to get FEC mode: %v", err)
+	}
+	if fec.fec != bit {
+		fec = ethtoolFECParam{cmd: unix.ETHTOOL_SFECPARAM, fec: bit}
+		if err := c.ioctl(ifName, unsafe.Pointer(&fec)); err != nil {
+			return fmt.Errorf("failed to set FEC mode: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *ethtoolConn) applyPause(ifName string, desired ethernetv1.PortSettings) error {
+	pause := ethtoolPauseParam{cmd: unix.ETHTOOL_GPAUSEPARAM}
+	if err := c.ioctl(ifName, unsafe.Pointer(&pause)); err != nil {
+		return fmt.Errorf("failed to get pause frames: %v", err)
+	}
+	changed := pause
+	setBool(&changed.autoneg, desired.Pause.Autoneg)
+	setBool(&changed.rxPause, desired.Pause.Rx)
+	setBool(&changed.txPause, desired.Pause.Tx)
+	if changed != pause {
+		changed.cmd = unix.ETHTOOL_SPAUSEPARAM
+		if err := c.ioctl(ifName, unsafe.Pointer(&changed)); err != nil {
+			return fmt.Errorf("failed to set pause frames: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *ethtoolConn) applyPrivateFlags(ifName string, desired ethernetv1.PortSettings) error {
+	names, flags, err := c.privateFlags(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get private flags: %v", err)
+	}
+	changed, err := privateFlagsValue(names, flags, desired.PrivateFlags)
+	if err != nil {
+		return err
+	}
+	if changed != flags {
+		value := ethtoolValue{cmd: unix.ETHTOOL_SPFLAGS, data: changed}
+		if err := c.ioctl(ifName, unsafe.Pointer(&value)); err != nil {
+			return fmt.Errorf("failed to set private flags: %v", err)
+		}
+	}
+	return nil
+}
+
+// linkSettings reads link settings, first asking the kernel for the length of link mode masks
+func (c *ethtoolConn) linkSettings(ifName string) (*ethtoolLinkSettings, error) {
+	link := &ethtoolLinkSettings{cmd: unix.ETHTOOL_GLINKSETTINGS}
+	if err := c.ioctl(ifName, unsafe.Pointer(link)); err != nil {
+		return nil, err
+	}
+	// kernel replies to the handshake with negated length of the masks
+	if link.linkModeMasksNwords >= 0 {
+		return nil, fmt.Errorf("unexpected link mode masks length %v", link.linkModeMasksNwords)
+	}
+	nwords := -link.linkModeMasksNwords
+	*link = ethtoolLinkSettings{cmd: unix.ETHTOOL_GLINKSETTINGS, linkModeMasksNwords: nwords}
+	if err := c.ioctl(ifName, unsafe.Pointer(link)); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// applyLinkSettings enables autonegotiation with advertised link modes kept, or forces the speed with full duplex
+func (c *ethtoolConn) applyLinkSettings(ifName string, desired ethernetv1.PortSettings) error {
+	link, err := c.linkSettings(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get link settings: %v", err)
+	}
+	changed := *link
+	if desired.Autoneg != nil {
+		changed.autoneg = ethtoolAutonegDisable
+		if *desired.Autoneg {
+			changed.autoneg = ethtoolAutonegEnable
+		}
+	}
+	if desired.Speed != nil {
+		if changed.autoneg != ethtoolAutonegDisable {
+			return fmt.Errorf("link speed can be set only with autoneg disabled")
+		}
+		changed.speed = uint32(*desired.Speed)
+		changed.duplex = ethtoolDuplexFull
+	}
+	if changed == *link {
+		return nil
+	}
+	changed.cmd = unix.ETHTOOL_SLINKSETTINGS
+	if err := c.ioctl(ifName, unsafe.Pointer(&changed)); err != nil {
+		return fmt.Errorf("failed to set link settings: %v", err)
+	}
+	return nil
+}
+
+// privateFlags returns names of private flags of the driver and the bitmask of enabled ones
+func (c *ethtoolConn) privateFlags(ifName string) ([]string, uint32, error) {
+	info := unix.EthtoolDrvinfo{Cmd: unix.ETHTOOL_GDRVINFO}
+	if err := c.ioctl(ifName, unsafe.Pointer(&info)); err != nil {
+		return nil, 0, err
+	}
+	if info.N_priv_flags == 0 {
+		return nil, 0, nil
+	}
+
+	// struct ethtool_gstrings header (cmd, string_set, len) followed by the strings
+	buf := make([]byte, 12+int(info.N_priv_flags)*ethtoolStringLen)
+	nativeEndian.PutUint32(buf[0:4], unix.ETHTOOL_GSTRINGS)
+	nativeEndian.PutUint32(buf[4:8], ethtoolStringSetPrivFlags)
+	nativeEndian.PutUint32(buf[8:12], info.N_priv_flags)
+	if err := c.ioctl(ifName, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, 0, err
+	}
+	names := parseEthtoolStrings(buf[12:], int(nativeEndian.Uint32(buf[8:12])))
+
+	value := ethtoolValue{cmd: unix.ETHTOOL_GPFLAGS}
+	if err := c.ioctl(ifName, unsafe.Pointer(&value)); err != nil {
+		return nil, 0, err
+	}
+	return names, value.data, nil
+}
+
+// parseEthtoolStrings returns count NUL padded strings of ETH_GSTRING_LEN bytes
+func parseEthtoolStrings(data []byte, count int) []string {
+	var strs []string
+	for i := 0; i < count && (i+1)*ethtoolStringLen <= len(data); i++ {
+		s := data[i*ethtoolStringLen : (i+1)*ethtoolStringLen]
+		strs = append(strs, strings.TrimRight(string(s), "\x00"))
+	}
+	return strs
+}
+
+// privateFlagsValue returns the bitmask of private flags with requested flags changed
+func privateFlagsValue(names []string, flags uint32, requested map[string]bool) (uint32, error) {
+	for flag, enabled := range requested {
+		bit := -1
+		for i, name := range names {
+			if name == flag {
+				bit = i
+				break
+			}
+		}
+		if bit < 0 {
+			return 0, fmt.Errorf("private flag %v not supported by the driver", flag)
+		}
+		if enabled {
+			flags |= 1 << bit
+		} else {
+			flags &^= 1 << bit
+		}
+	}
+	return flags, nil
+}
+
+func fecModeBit(mode string) (uint32, error) {
+	for _, m := range fecModes {
+		if m.name == mode {
+			return m.bit, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown FEC mode %q", mode)
+}
+
+// fecModeName returns FECMode of configured ETHTOOL_FEC_* bits, or the bits if no known mode is set
+func fecModeName(bits uint32) string {
+	for _, m := range fecModes {
+		if bits&m.bit != 0 {
+			return m.name
+		}
+	}
+	return fmt.Sprintf("0x%x", bits)
+}
+
+func setUint32(field *uint32, value *int) {
+	if value != nil {
+		*field = uint32(*value)
+	}
+}
+
+func setBool(field *uint32, value *bool) {
+	if value != nil {
+		*field = 0
+		if *value {
+			*field = 1
+		}
+	}
+}
+
+func intPtr(v uint32) *int {
+	i := int(v)
+	return &i
+}
+
+// speedPtr returns link speed in Mb/s, nil if the speed is unknown
+func speedPtr(v uint32) *int {
+	if v == ethtoolSpeedUnknown {
+		return nil
+	}
+	return intPtr(v)
+}
+
+func boolPtr(v uint32) *bool {
+	b := v != 0
+	return &b
+}
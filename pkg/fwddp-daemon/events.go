@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Reasons of events recorded for the EthernetNodeConfig and the Node
+const (
+	EventDownloadStarted   = "DownloadStarted"
+	EventDownloadCompleted = "DownloadCompleted"
+	EventChecksumVerified  = "ChecksumVerified"
+	EventNodeDraining      = "NodeDraining"
+	EventNodeDrained       = "NodeDrained"
+	EventDrainFailed       = "DrainFailed"
+	EventFlashStarted      = "FlashStarted"
+	EventFlashCompleted    = "FlashCompleted"
+	EventFlashFailed       = "FlashFailed"
+	EventDDPCopied         = "DDPCopied"
+	EventDDPReverted       = "DDPReverted"
+	EventDDPFailed         = "DDPFailed"
+	EventRebootIssued      = "RebootIssued"
+	EventRebootPending     = "RebootPending"
+	EventNodeUncordoned    = "NodeUncordoned"
+
+	EventDevlinkParamsSet    = "DevlinkParamsSet"
+	EventDevlinkParamsFailed = "DevlinkParamsFailed"
+
+	EventPortSettingsApplied = "PortSettingsApplied"
+	EventPortSettingsFailed  = "PortSettingsFailed"
+
+	// PCIAddressAnnotation holds PCI address of the device the event was recorded for
+	PCIAddressAnnotation = "ethernet.intel.com/pci-address"
+)
+
+// nodeRef refers to the Node the daemon runs on. As in events recorded by kubelet, the UID is the name of the node
+func (r *NodeConfigReconciler) nodeRef() *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind: "Node",
+		Name: r.nodeNameRef.Name,
+		UID:  types.UID(r.nodeNameRef.Name),
+	}
+}
+
+// nodeEvent records event for the EthernetNodeConfig and the Node. No-op if no recorder is set
+func (r *NodeConfigReconciler) nodeEvent(nc *ethernetv1.EthernetNodeConfig, eventType, reason, messageFmt string,
+	args ...interface{}) {
+	r.annotatedEvent(nc, nil, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *NodeConfigReconciler) annotatedEvent(nc *ethernetv1.EthernetNodeConfig, annotations map[string]string,
+	eventType, reason, message string) {
+	if r.recorder == nil {
+		return
+	}
+	r.recorder.AnnotatedEventf(nc, annotations, eventType, reason, "%v", message)
+	r.recorder.AnnotatedEventf(r.nodeRef(), annotations, eventType, reason, "%v", message)
+}
+
+// event records event of the device for the EthernetNodeConfig and the Node. PCI address of the device
+// is included in the message and in the annotations of the event
+func (d *deviceStatusReporter) event(eventType, reason, messageFmt string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	d.r.annotatedEvent(d.nc, map[string]string{PCIAddressAnnotation: d.pciAddr}, eventType, reason,
+		fmt.Sprintf("Device %v: ", d.pciAddr)+fmt.Sprintf(messageFmt, args...))
+}
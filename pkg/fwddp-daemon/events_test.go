@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+var _ = Describe("events", func() {
+	nc := &ethernetv1.EthernetNodeConfig{}
+	nc.Name = "node1"
+
+	var _ = It("will record node event for EthernetNodeConfig and Node", func() {
+		recorder := record.NewFakeRecorder(10)
+		r := &NodeConfigReconciler{recorder: recorder, nodeNameRef: types.NamespacedName{Name: "node1"}}
+
+		r.nodeEvent(nc, corev1.EventTypeNormal, EventNodeDraining, "Draining node to update %v device(s)", 2)
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect(<-recorder.Events).To(Equal("Normal NodeDraining Draining node to update 2 device(s)"))
+		Expect(<-recorder.Events).To(Equal("Normal NodeDraining Draining node to update 2 device(s)"))
+		Expect(r.nodeRef().UID).To(Equal(types.UID("node1")))
+	})
+
+	var _ = It("will include PCI address in device event", func() {
+		recorder := record.NewFakeRecorder(10)
+		r := &NodeConfigReconciler{recorder: recorder, nodeNameRef: types.NamespacedName{Name: "node1"}}
+
+		r.deviceStatus(nc, "0000:01:00.0").event(corev1.EventTypeWarning, EventFlashFailed, "Firmware update failed: %v",
+			"exit code 7")
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect(<-recorder.Events).To(Equal("Warning FlashFailed Device 0000:01:00.0: Firmware update failed: exit code 7"))
+	})
+
+	var _ = It("will be no-op without recorder", func() {
+		r := &NodeConfigReconciler{}
+		r.nodeEvent(nc, corev1.EventTypeNormal, EventRebootIssued, "Post-update node reboot issued")
+		var d *deviceStatusReporter
+		d.event(corev1.EventTypeNormal, EventDDPCopied, "DDP package copied")
+	})
+})
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+const (
+	FWUpdateBackendNVMUpdate    = "NVMUpdate"
+	FWUpdateBackendDevlinkFlash = "DevlinkFlash"
+)
+
+// firmwareBackend applies firmware of a particular package format to the device
+type firmwareBackend interface {
+	// unpack prepares package downloaded to pkgPath in targetPath
+	unpack(pkgPath, targetPath string) error
+	// locate returns path of the firmware prepared in targetPath, which is passed to other methods as fwPath
+	locate(targetPath, pkgPath string) (string, error)
+	// targetVersion returns EETrack ID of the NVM image which fwPath provides for the device
+	targetVersion(fwPath, pciAddr string) (string, error)
+	// isUpdateAvailable checks if fwPath would update any module of the device, without flashing it
+	isUpdateAvailable(pciAddr, fwPath string) (bool, error)
+	// update flashes fwPath to the device and returns true if node reboot is required to complete the update.
+	// Outcome of the rollback is returned if the update failed
+	update(pciAddr, fwPath string, config ethernetv1.DeviceConfig,
+		deviceStatus *deviceStatusReporter) (bool, *ethernetv1.FirmwareRollback, error)
+}
+
+// backend returns firmware backend selected in the device config, NVM Update utility by default
+func (f *fwUpdater) backend(name string) firmwareBackend {
+	if name == FWUpdateBackendDevlinkFlash {
+		return &devlinkFlashBackend{f}
+	}
+	return &nvmupdateBackend{f}
+}
+
+// nvmupdateBackend applies .tar.gz NVM Update packages with NVM Update utility included in them
+type nvmupdateBackend struct {
+	*fwUpdater
+}
+
+func (f *nvmupdateBackend) unpack(pkgPath, targetPath string) error {
+	return untarFile(pkgPath, targetPath, f.log)
+}
+
+func (f *nvmupdateBackend) locate(targetPath, _ string) (string, error) {
+	return findFw(targetPath)
+}
+
+func (f *nvmupdateBackend) targetVersion(fwPath, pciAddr string) (string, error) {
+	return firmwareTargetVersion(fwPath, pciAddr)
+}
+
+func (f *nvmupdateBackend) isUpdateAvailable(pciAddr, fwPath string) (bool, error) {
+	return f.isFirmwareUpdateAvailable(pciAddr, fwPath)
+}
@@ -12,15 +12,20 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-logr/logr"
 	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const (
-	nvmupdate64e  = "./nvmupdate64e"
-	updateOutFile = "update.xml"
+	nvmupdate64e     = "./nvmupdate64e"
+	updateOutFile    = "update.xml"
+	inventoryOutFile = "inventory.xml"
+	// log written by NVM Update utility run with -l option
+	nvmupdateLogFile = "nvmupdate.log"
 )
 
 var (
@@ -31,115 +36,153 @@ var (
 type fwUpdater struct {
 	log        logr.Logger
 	httpClient *http.Client
+	fetcher    *packageFetcher
+	cache      *artifactCache
+	verifier   *packageVerifier
+	// how long devlink flash backend waits for the flash update to complete
+	flashTimeout time.Duration
+	// logs of the current device update, nil if not kept
+	opLog *operationLog
 }
 
-func (f *fwUpdater) prepareFirmware(config ethernetv1.DeviceNodeConfig) (string, error) {
+// withOperationLog returns copy of the updater recording its log and output of NVM Update utility in opLog
+func (f *fwUpdater) withOperationLog(opLog *operationLog) *fwUpdater {
+	c := *f
+	c.log = opLog.logger(f.log)
+	c.opLog = opLog
+	return &c
+}
+
+func (f *fwUpdater) prepareFirmware(config ethernetv1.DeviceNodeConfig, deviceStatus *deviceStatusReporter) (string, error) {
 	log := f.log.WithName("prepareFirmware")
 
 	if config.DeviceConfig.FWURL == "" {
 		log.V(4).Info("Empty FWURL")
 		return "", nil
 	}
+	backend := f.backend(config.DeviceConfig.FWUpdateBackend)
 
-	targetPath := filepath.Join(artifactsFolder, config.PCIAddress)
+	targetPath, fullPath, cached, err := f.cache.acquire(config.PCIAddress, config.DeviceConfig.FWURL,
+		config.DeviceConfig.FWChecksum)
+	if err != nil {
+		return "", err
+	}
 
-	err := utils.CreateFolder(targetPath, log)
+	if !cached {
+		deviceStatus.setPhase(DevicePhaseDownloading)
+		deviceStatus.event(corev1.EventTypeNormal, EventDownloadStarted, "Downloading firmware package %v",
+			config.DeviceConfig.FWURL)
+		downloadStart := timeNow()
+		progress, stopProgress := deviceStatus.downloadProgress(config.DeviceConfig.FWURL)
+		fullPath, err = f.fetcher.fetchPackage(targetPath, config.DeviceConfig.FWURL, config.DeviceConfig.FWChecksum,
+			config.DeviceConfig.PullSecret, progress)
+		stopProgress()
+		if err != nil {
+			return "", err
+		}
+		if info, err := os.Stat(fullPath); err == nil {
+			observeDownload("firmware", info.Size(), downloadStart)
+		}
+		deviceStatus.event(corev1.EventTypeNormal, EventDownloadCompleted, "Downloaded firmware package %v",
+			config.DeviceConfig.FWURL)
+		if config.DeviceConfig.FWChecksum != "" {
+			deviceStatus.event(corev1.EventTypeNormal, EventChecksumVerified, "Checksum of firmware package %v verified",
+				config.DeviceConfig.FWURL)
+		}
+	}
+
+	err = f.verifier.verifySignature(fullPath, config.DeviceConfig.FWSignatureURL, config.DeviceConfig.SignatureKeySecret)
 	if err != nil {
 		return "", err
 	}
 
-	fullPath := filepath.Join(targetPath, filepath.Base(config.DeviceConfig.FWURL))
-	log.V(4).Info("Downloading", "url", config.DeviceConfig.FWURL, "dstPath", fullPath)
-	err = downloadFile(fullPath, config.DeviceConfig.FWURL, config.DeviceConfig.FWChecksum, f.httpClient)
+	deviceStatus.setPhase(DevicePhaseExtracting)
+	log.V(4).Info("FW file ready - extracting")
+	err = backend.unpack(fullPath, targetPath)
 	if err != nil {
 		return "", err
 	}
 
-	log.V(4).Info("FW file downloaded - extracting")
-	err = untarFile(fullPath, targetPath, log)
+	err = f.cache.store(targetPath, fullPath)
 	if err != nil {
 		return "", err
 	}
 
-	return findFw(targetPath)
+	return backend.locate(targetPath, fullPath)
+}
+
+// handleFWUpdate applies firmware prepared in fwPath with the backend selected in the device config
+func (f *fwUpdater) handleFWUpdate(pciAddr, fwPath string, config ethernetv1.DeviceConfig,
+	deviceStatus *deviceStatusReporter) (bool, *ethernetv1.FirmwareRollback, error) {
+	if fwPath == "" {
+		return false, nil, nil
+	}
+	return f.backend(config.FWUpdateBackend).update(pciAddr, fwPath, config, deviceStatus)
 }
 
-func (f *fwUpdater) handleFWUpdate(pciAddr, fwPath, fwUpdateParam string) (bool, error) {
+func (f *nvmupdateBackend) update(pciAddr, fwPath string, config ethernetv1.DeviceConfig,
+	deviceStatus *deviceStatusReporter) (bool, *ethernetv1.FirmwareRollback, error) {
 	log := f.log.WithName("handleFWUpdate")
 	rebootRequired := false
 
-	if fwPath == "" {
-		return false, nil
+	backupPath, err := f.backupNVM(pciAddr, fwPath)
+	if err != nil {
+		log.Error(err, "Failed to save NVM image, rollback will not be available", "device", pciAddr)
+		backupPath = ""
 	}
 
-	returnCode, err := f.updateFirmware(pciAddr, fwPath, fwUpdateParam)
+	returnCode, err := f.updateFirmware(pciAddr, fwPath, config.FWUpdateParam)
+	for _, path := range []string{nvmupdateLogPath(fwPath), updateResultPath(fwPath)} {
+		if err := f.opLog.addFile(filepath.Base(path), path); err != nil {
+			log.Error(err, "Failed to add file to operation log", "path", path)
+		}
+	}
+	result, parseErr := parseUpdateResult(updateResultPath(fwPath))
+	if returnCode >= 0 {
+		deviceStatus.setFirmwareUpdate(firmwareUpdateSummary(returnCode, result))
+	}
 	if err != nil {
 		log.Error(err, "Failed to update firmware", "device", pciAddr)
-		return false, err
+		rollback := f.rollback(pciAddr, backupPath, err)
+		if backupPath != "" {
+			discardBackup(backupPath, log)
+		}
+		return false, rollback, err
 	}
 
-	var reboot bool
+	// saved NVM image is kept until health of the device is checked with the new firmware active
+
 	// update successful despite exit code being >0, reboot needed to finish process
 	if returnCode == 50 || returnCode == 51 {
-		reboot = true
-	} else {
-		reboot, err = isRebootRequired(updateResultPath(fwPath))
-	}
-
-	if err != nil {
-		log.Error(err, "Failed to extract reboot required flag from file")
+		log.V(4).Info("Node reboot required to complete firmware update", "device", pciAddr,
+			"reason", nvmupdateExitReason(returnCode))
+		rebootRequired = true
+	} else if parseErr != nil {
+		log.Error(parseErr, "Failed to extract reboot required flag from file")
 		rebootRequired = true // failsafe
-	} else if reboot {
+	} else if result.RebootRequired != 0 {
 		log.V(4).Info("Node reboot required to complete firmware update", "device", pciAddr)
 		rebootRequired = true
 	}
-	return rebootRequired, nil
+	return rebootRequired, nil, nil
 }
 
+// updateFirmware runs NVM Update utility in update mode and returns its exit code. Error is returned if the
+// exit code doesn't report completed update, or the utility couldn't be run (exit code -1)
 func (f *fwUpdater) updateFirmware(pciAddr, fwPath, fwUpdateParam string) (int, error) {
 	log := f.log.WithName("updateFirmware")
 
-	rootAttr := &syscall.SysProcAttr{
-		Credential: &syscall.Credential{Uid: 0, Gid: 0},
-	}
-
-	// read alternative fw search path, path might get modified on
-	// NVM Update runtime so it needs to be restored after tool execution
-	altFwPathBytes, err := os.ReadFile("/sys/module/firmware_class/parameters/path")
-	if err != nil {
-		log.V(2).Info("Error reading /sys/module/firmware_class/parameters/path", "error", err)
-	}
-
-	altFwPath := strings.TrimSuffix(string(altFwPathBytes), "\n")
-	if altFwPath != "" {
-		log.V(2).Info("Alternative firmware search path found", "path", altFwPath)
-	}
-
-	log.V(2).Info("Splitting PCI addr and converting to decimal", "pciAddr", pciAddr)
-	domain, bus, _, _, err := splitPCIAddr(pciAddr, log)
+	pciLocation, err := nvmupdateLocation(pciAddr, log)
 	if err != nil {
-		log.V(2).Info("Error spitting PCI Addr", "error", err)
-		return -1, err
-	}
-
-	bus_dec, err := strconv.ParseInt(bus, 16, 32)
-	if err != nil {
-		log.V(2).Info("Error converting bus PCI to decimal", "error", err)
 		return -1, err
 	}
 
-	domain_dec, err := strconv.ParseInt(domain, 16, 32)
-	if err != nil {
-		log.V(2).Info("Error converting PCI domain to decimal", "error", err)
-		return -1, err
-	}
-
-	log.V(2).Info("PCI Addr splitted and converted successfully", "domain",
-		domain_dec, "bus", bus_dec)
-
 	configPath := nvmupdate64eCfgPath(fwPath)
 	resultPath := updateResultPath(fwPath)
-	pciLocation := fmt.Sprintf("%02d:%03d", domain_dec, bus_dec)
+	// result of previous update of another device sharing the package may be left in the folder
+	if err := os.Remove(resultPath); err != nil && !os.IsNotExist(err) {
+		return -1, err
+	}
 
 	log.V(2).Info("Starting Firmware Update", "pciLocation", pciLocation,
 		"configPath", configPath, "resultPath", resultPath)
@@ -153,8 +196,79 @@ func (f *fwUpdater) updateFirmware(pciAddr, fwPath, fwUpdateParam string) (int,
 			configPath, "-o", resultPath, "-l")
 	}
 
-	cmd.SysProcAttr = rootAttr
 	cmd.Dir = fwPath
+	if f.opLog != nil {
+		cmd.Stdout = f.opLog.writer(operationLogNvmupdateEntry)
+		cmd.Stderr = cmd.Stdout
+	}
+	err = runNvmupdate(cmd, log)
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code := exitErr.ExitCode()
+			if !isNvmupdateSuccess(code) {
+				return code, fmt.Errorf("nvmupdate64e failed with exit code %v: %v", code, nvmupdateExitReason(code))
+			}
+			log.V(2).Info("Firmware update completed", "exitCode", code, "reason", nvmupdateExitReason(code))
+			return code, nil
+		} else {
+			return -1, err
+		}
+	}
+
+	return 0, nil
+}
+
+// isFirmwareUpdateAvailable runs NVM Update utility in inventory mode to check if the package in fwPath
+// would update any module of the device, without flashing it
+func (f *fwUpdater) isFirmwareUpdateAvailable(pciAddr, fwPath string) (bool, error) {
+	log := f.log.WithName("isFirmwareUpdateAvailable")
+
+	pciLocation, err := nvmupdateLocation(pciAddr, log)
+	if err != nil {
+		return true, err
+	}
+
+	resultPath := inventoryResultPath(fwPath)
+	if err := os.Remove(resultPath); err != nil && !os.IsNotExist(err) {
+		return true, err
+	}
+
+	log.V(2).Info("Running firmware inventory", "pciLocation", pciLocation, "resultPath", resultPath)
+	cmd := exec.Command(nvmupdate64e, "-i", "-location", pciLocation, "-c", nvmupdate64eCfgPath(fwPath),
+		"-o", resultPath, "-l")
+	cmd.Dir = fwPath
+	if err := runNvmupdate(cmd, log); err != nil {
+		return true, err
+	}
+
+	return isUpdateAvailable(resultPath)
+}
+
+// runNvmupdate executes NVM Update utility as root and restores alternative
+// firmware search path which might get modified on the tool runtime. Log of
+// the previous run, which the utility appends to, is removed from the working
+// directory first, so the log holds output of this run only
+func runNvmupdate(cmd *exec.Cmd, log logr.Logger) error {
+	rootAttr := &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: 0, Gid: 0},
+	}
+
+	if err := os.Remove(nvmupdateLogPath(cmd.Dir)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	altFwPathBytes, err := os.ReadFile("/sys/module/firmware_class/parameters/path")
+	if err != nil {
+		log.V(2).Info("Error reading /sys/module/firmware_class/parameters/path", "error", err)
+	}
+
+	altFwPath := strings.TrimSuffix(string(altFwPathBytes), "\n")
+	if altFwPath != "" {
+		log.V(2).Info("Alternative firmware search path found", "path", altFwPath)
+	}
+
+	cmd.SysProcAttr = rootAttr
 	err = nvmupdateExec(cmd, log)
 
 	// restore alternative firmware search path after update if necessary
@@ -168,20 +282,34 @@ func (f *fwUpdater) updateFirmware(pciAddr, fwPath, fwUpdateParam string) (int,
 		}
 	}
 
+	return err
+}
+
+// nvmupdateLocation converts PCI address to <domain>:<bus> location in decimal format expected by NVM Update utility
+func nvmupdateLocation(pciAddr string, log logr.Logger) (string, error) {
+	log.V(2).Info("Splitting PCI addr and converting to decimal", "pciAddr", pciAddr)
+	domain, bus, _, _, err := splitPCIAddr(pciAddr, log)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			code := exitErr.ExitCode()
-			if code != 3 && code != 30 && code != 50 && code != 51 {
-				return -1, exitErr
-			} else {
-				return code, nil
-			}
-		} else {
-			return -1, err
-		}
+		log.V(2).Info("Error spitting PCI Addr", "error", err)
+		return "", err
 	}
 
-	return 0, nil
+	bus_dec, err := strconv.ParseInt(bus, 16, 32)
+	if err != nil {
+		log.V(2).Info("Error converting bus PCI to decimal", "error", err)
+		return "", err
+	}
+
+	domain_dec, err := strconv.ParseInt(domain, 16, 32)
+	if err != nil {
+		log.V(2).Info("Error converting PCI domain to decimal", "error", err)
+		return "", err
+	}
+
+	log.V(2).Info("PCI Addr splitted and converted successfully", "domain",
+		domain_dec, "bus", bus_dec)
+
+	return fmt.Sprintf("%02d:%03d", domain_dec, bus_dec), nil
 }
 
 func findFwExec(targetPath string) (string, error) {
@@ -204,4 +332,6 @@ func findFwExec(targetPath string) (string, error) {
 
 func nvmupdate64eCfgPath(p string) string { return filepath.Join(p, "nvmupdate.cfg") }
 func updateResultPath(p string) string    { return filepath.Join(p, updateOutFile) }
+func inventoryResultPath(p string) string { return filepath.Join(p, inventoryOutFile) }
+func nvmupdateLogPath(p string) string    { return filepath.Join(p, nvmupdateLogFile) }
 func isExecutable(info os.FileInfo) bool  { return info.Mode()&0100 != 0 }
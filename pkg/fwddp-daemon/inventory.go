@@ -5,11 +5,11 @@ package daemon
 
 import (
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
-	"regexp"
-
 	"github.com/go-logr/logr"
 	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
@@ -18,14 +18,8 @@ import (
 	"github.com/jaypipes/ghw/pkg/pci"
 )
 
-const (
-	ethtoolPath = "ethtool"
-)
-
-var (
-	ethtoolRegex = regexp.MustCompile(`^([a-z-]+?)(?:\s*:\s)(.+)$`)
-	devlinkRegex = regexp.MustCompile(`^\s+([\w\.]+) (.+)$`)
-)
+// drivers used by DPDK applications, devices bound to them have no network interfaces
+var dpdkDrivers = map[string]bool{"vfio-pci": true, "igb_uio": true, "uio_pci_generic": true}
 
 var getPCIDevices = func() ([]*ghw.PCIDevice, error) {
 	pci, err := ghw.PCI()
@@ -48,16 +42,6 @@ var getNetworkInfo = func() (*net.Info, error) {
 	return net, nil
 }
 
-var execEthtool = func(nicName string) ([]byte, error) {
-	return exec.Command(ethtoolPath, "-i", nicName).Output()
-}
-
-var execDevlink = func(pciAddr string) ([]byte, error) {
-	devName := fmt.Sprintf("pci/%s", pciAddr)
-
-	return exec.Command("devlink", "dev", "info", devName).CombinedOutput()
-}
-
 func isDeviceSupported(d *pci.Device) bool {
 	if d == nil {
 		return false
@@ -80,6 +64,12 @@ func GetInventory(log logr.Logger) ([]ethernetv1.Device, error) {
 		return nil, err
 	}
 
+	net, err := getNetworkInfo()
+	if err != nil {
+		log.Error(err, "failed to get network interfaces")
+	}
+	provider := newDeviceInfoProvider(log)
+
 	var devices []ethernetv1.Device
 
 	for _, pciDevice := range pciDevices {
@@ -90,8 +80,10 @@ func GetInventory(log logr.Logger) ([]ethernetv1.Device, error) {
 				VendorID:   pciDevice.Vendor.ID,
 				DeviceID:   pciDevice.Product.ID,
 			}
-			addNetInfo(log, &d)
-			addDDPInfo(log, &d)
+			addNetInfo(log, provider, net, &d)
+			addDevlinkInfo(log, provider, &d)
+			addDevlinkParams(log, provider, &d)
+			addSysfsInfo(log, &d)
 			devices = append(devices, d)
 		}
 	}
@@ -99,14 +91,12 @@ func GetInventory(log logr.Logger) ([]ethernetv1.Device, error) {
 	return devices, nil
 }
 
-func addNetInfo(log logr.Logger, device *ethernetv1.Device) {
-	log.Info("adding netInfo for supported device", "device", device)
-
-	net, err := getNetworkInfo()
-	if err != nil {
-		log.Error(err, "failed to get network interfaces")
+// addNetInfo adds MAC address of the device and driver and firmware versions reported for its network interface
+func addNetInfo(log logr.Logger, provider deviceInfoProvider, net *net.Info, device *ethernetv1.Device) {
+	if net == nil {
 		return
 	}
+	log.Info("adding netInfo for supported device", "device", device)
 
 	nicName := ""
 	for _, nic := range net.NICs {
@@ -121,48 +111,117 @@ func addNetInfo(log logr.Logger, device *ethernetv1.Device) {
 		return // NIC not found
 	}
 
-	out, err := execEthtool(nicName)
+	info, err := provider.ethtoolInfo(nicName)
 	if err != nil {
-		log.Error(err, "failed when executing", "cmd", ethtoolPath)
+		log.Error(err, "failed to get driver info", "interface", nicName)
 		return
 	}
-	for _, line := range strings.Split(string(out), "\n") {
-		m := ethtoolRegex.FindStringSubmatch(line)
-		if len(m) == 3 {
-			switch m[1] {
-			case "driver":
-				device.Driver = m[2]
-			case "version":
-				device.DriverVersion = m[2]
-			case "firmware-version":
-				device.Firmware.Version = m[2]
-			}
-		}
-	}
+	device.Driver = info.driver
+	device.DriverVersion = info.version
+	device.Firmware.Version = info.firmwareVersion
+}
 
+// addDevlinkInfo adds DDP package, running versions of firmware components and serial number reported by devlink
+func addDevlinkInfo(log logr.Logger, provider deviceInfoProvider, device *ethernetv1.Device) {
+	info, err := provider.devlinkInfo(device.PCIAddress)
+	if err != nil {
+		log.Error(err, "failed to get devlink info", "pciAddress", device.PCIAddress)
+		return
+	}
+	device.SerialNumber = info.serialNumber
+	device.DDP.PackageName = info.running["fw.app.name"]
+	device.DDP.Version = info.running["fw.app"]
+	device.DDP.TrackID = info.running["fw.app.bundle_id"]
+	device.Firmware.MgmtVersion = info.running["fw.mgmt"]
+	device.Firmware.UNDIVersion = info.running["fw.undi"]
+	device.Firmware.NetlistVersion = info.running["fw.netlist"]
 }
 
-func addDDPInfo(log logr.Logger, device *ethernetv1.Device) {
-	out, err := execDevlink(device.PCIAddress)
+// addDevlinkParams adds values of devlink parameters of the device
+func addDevlinkParams(log logr.Logger, provider deviceInfoProvider, device *ethernetv1.Device) {
+	params, err := provider.devlinkParams(device.PCIAddress)
 	if err != nil {
-		log.Error(err, "failed when executing devlink", "out", string(out))
+		log.V(2).Info("failed to get devlink params", "pciAddress", device.PCIAddress, "error", err.Error())
 		return
 	}
-	for _, line := range strings.Split(string(out), "\n") {
-		tokens := devlinkRegex.FindStringSubmatch(line)
-		if len(tokens) != 3 {
-			continue
+	device.DevlinkParams = devlinkParamsStatus(params)
+}
+
+// addSysfsInfo adds NUMA node, PCIe link, SR-IOV capabilities, serial number, bound driver and network interfaces
+// of the device. Attributes missing in sysfs are not reported
+func addSysfsInfo(log logr.Logger, device *ethernetv1.Device) {
+	devicePath := filepath.Join(pciDevicesPath, device.PCIAddress)
+
+	// -1 if the platform doesn't report NUMA node of the device
+	if numa, err := readSysfsInt(filepath.Join(devicePath, "numa_node")); err == nil && numa >= 0 {
+		device.NUMANode = &numa
+	}
+
+	link := ethernetv1.PCIeLinkInfo{
+		Speed:    readSysfsString(filepath.Join(devicePath, "current_link_speed")),
+		MaxSpeed: readSysfsString(filepath.Join(devicePath, "max_link_speed")),
+	}
+	link.Width, _ = readSysfsInt(filepath.Join(devicePath, "current_link_width"))
+	link.MaxWidth, _ = readSysfsInt(filepath.Join(devicePath, "max_link_width"))
+	if link != (ethernetv1.PCIeLinkInfo{}) {
+		device.PCIeLink = &link
+	}
+
+	if totalVFs, err := readSysfsInt(filepath.Join(devicePath, "sriov_totalvfs")); err == nil {
+		numVFs, _ := readSysfsInt(filepath.Join(devicePath, "sriov_numvfs"))
+		device.SRIOV = &ethernetv1.SRIOVInfo{TotalVFs: totalVFs, NumVFs: numVFs}
+	}
+
+	// devlink doesn't report serial number of devices without kernel network driver
+	if device.SerialNumber == "" {
+		if dsn, err := readDeviceSerialNumber(device.PCIAddress); err == nil {
+			device.SerialNumber = formatDSN(dsn)
 		}
+	}
 
-		switch tokens[1] {
-		case "fw.app.name":
-			device.DDP.PackageName = tokens[2]
-		case "fw.app":
-			device.DDP.Version = tokens[2]
-		case "fw.app.bundle_id":
-			device.DDP.TrackID = tokens[2]
+	if driver, err := boundDriver(devicePath); err == nil {
+		device.DPDKBound = dpdkDrivers[driver]
+		// ethtool doesn't report driver of devices without network interface
+		if device.Driver == "" {
+			device.Driver = driver
 		}
 	}
+
+	entries, err := os.ReadDir(filepath.Join(devicePath, "net"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Error(err, "failed to list network interfaces", "pciAddress", device.PCIAddress)
+		}
+		return
+	}
+	for _, e := range entries {
+		netPath := filepath.Join(devicePath, "net", e.Name())
+		nic := ethernetv1.NetworkInterface{
+			Name:      e.Name(),
+			LinkState: readSysfsString(filepath.Join(netPath, "operstate")),
+		}
+		// reading speed fails or returns -1 if the link is down
+		if speed, err := readSysfsInt(filepath.Join(netPath, "speed")); err == nil && speed > 0 {
+			nic.Speed = speed
+		}
+		device.NetworkInterfaces = append(device.NetworkInterfaces, nic)
+	}
+}
+
+func readSysfsString(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func readSysfsInt(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(content)))
 }
 
 func splitPCIAddr(pciAddr string, log logr.Logger) (string, string, string, string, error) {
@@ -5,7 +5,11 @@ package daemon
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
 	"github.com/jaypipes/ghw"
 	"github.com/jaypipes/ghw/pkg/net"
@@ -37,8 +41,35 @@ var pcis = func() ([]*ghw.PCIDevice, error) {
 	}, nil
 }
 
+type fakeInfoProvider struct {
+	ethtool func(ifName string) (*ethtoolDriverInfo, error)
+	devlink func(pciAddr string) (*devlinkDeviceInfo, error)
+	params  func(pciAddr string) ([]devlinkParam, error)
+}
+
+func (p *fakeInfoProvider) ethtoolInfo(ifName string) (*ethtoolDriverInfo, error) {
+	return p.ethtool(ifName)
+}
+
+func (p *fakeInfoProvider) devlinkInfo(pciAddr string) (*devlinkDeviceInfo, error) {
+	return p.devlink(pciAddr)
+}
+
+func (p *fakeInfoProvider) devlinkParams(pciAddr string) ([]devlinkParam, error) {
+	if p.params == nil {
+		return nil, errDevlinkParamsUnavailable
+	}
+	return p.params(pciAddr)
+}
+
 var _ = Describe("InventoryTest", func() {
 	log := ctrl.Log.WithName("FirmwareDaemon-test")
+	origNewDeviceInfoProvider := newDeviceInfoProvider
+
+	AfterEach(func() {
+		newDeviceInfoProvider = origNewDeviceInfoProvider
+	})
+
 	var _ = Context("GetInventory", func() {
 		var _ = It("will return error when is not able to get PCI devices", func() {
 			getPCIDevices = func() ([]*ghw.PCIDevice, error) {
@@ -155,11 +186,15 @@ var _ = Describe("InventoryTest", func() {
 					},
 				}, nil
 			}
-			execEthtool = func(string) ([]byte, error) {
-				return nil, fmt.Errorf("error when calling ethtool")
-			}
-			execDevlink = func(string) ([]byte, error) {
-				return nil, fmt.Errorf("error when calling devlink")
+			newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+				return &fakeInfoProvider{
+					ethtool: func(string) (*ethtoolDriverInfo, error) {
+						return nil, fmt.Errorf("error when calling ethtool")
+					},
+					devlink: func(string) (*devlinkDeviceInfo, error) {
+						return nil, fmt.Errorf("error when calling devlink")
+					},
+				}
 			}
 
 			compatibilityMap = &CompatibilityMap{
@@ -202,48 +237,34 @@ var _ = Describe("InventoryTest", func() {
 				}, nil
 			}
 
-			execEthtool = func(string) ([]byte, error) {
-				return []byte(
-					`driver: i40e
-version: 2.8.20-k
-firmware-version: 3.31 0x80000d31 1.1767.0
-expansion-rom-version:
-bus-info: 0000:00:00.0
-supports-statistics: yes
-supports-test: yes
-supports-eeprom-access: yes
-supports-register-dump: yes
-supports-priv-flags: yes
-`), nil
-			}
-
-			execDevlink = func(string) ([]byte, error) {
-				return []byte(`
-pci/0000:51:00.0:
-  driver ice
-  serial_number b4-96-91-ff-ff-af-6d-68
-  versions:
-      fixed:
-        board.id M17659-003
-      running:
-        fw.mgmt 5.4.5
-        fw.mgmt.api 1.7
-        fw.mgmt.build 0x391f7640
-        fw.undi 1.2898.0
-        fw.psid.api 2.40
-        fw.bundle_id 0x80007064
-        fw.app.name ICE OS Default Package
-        fw.app 1.3.4.0
-        fw.app.bundle_id 0x00000000
-        fw.netlist 2.40.2000-6.22.0
-        fw.netlist.build 0x0ee8f468
-      stored:
-        fw.undi 1.2898.0
-        fw.psid.api 2.40
-        fw.bundle_id 0x80007064
-        fw.netlist 2.40.2000-6.22.0
-        fw.netlist.build 0x0ee8f468
-`), nil
+			newDeviceInfoProvider = func(logr.Logger) deviceInfoProvider {
+				return &fakeInfoProvider{
+					ethtool: func(ifName string) (*ethtoolDriverInfo, error) {
+						Expect(ifName).To(Equal("eno0"))
+						return &ethtoolDriverInfo{
+							driver:          "i40e",
+							version:         "2.8.20-k",
+							firmwareVersion: "3.31 0x80000d31 1.1767.0",
+						}, nil
+					},
+					devlink: func(pciAddr string) (*devlinkDeviceInfo, error) {
+						Expect(pciAddr).To(Equal("00:00:00.0"))
+						return &devlinkDeviceInfo{
+							driver:       "ice",
+							serialNumber: "b4-96-91-ff-ff-af-6d-68",
+							fixed:        map[string]string{"board.id": "M17659-003"},
+							running: map[string]string{
+								"fw.mgmt":          "5.4.5",
+								"fw.undi":          "1.2898.0",
+								"fw.app.name":      "ICE OS Default Package",
+								"fw.app":           "1.3.4.0",
+								"fw.app.bundle_id": "0x00000000",
+								"fw.netlist":       "2.40.2000-6.22.0",
+							},
+							stored: map[string]string{"fw.undi": "1.2900.0"},
+						}, nil
+					},
+				}
 			}
 
 			compatibilityMap = &CompatibilityMap{
@@ -269,6 +290,78 @@ pci/0000:51:00.0:
 			Expect(d[0].DDP.PackageName).To(Equal("ICE OS Default Package"))
 			Expect(d[0].DDP.Version).To(Equal("1.3.4.0"))
 			Expect(d[0].DDP.TrackID).To(Equal("0x00000000"))
+			Expect(d[0].Firmware.MgmtVersion).To(Equal("5.4.5"))
+			Expect(d[0].Firmware.UNDIVersion).To(Equal("1.2898.0"))
+			Expect(d[0].Firmware.NetlistVersion).To(Equal("2.40.2000-6.22.0"))
+			Expect(d[0].SerialNumber).To(Equal("b4-96-91-ff-ff-af-6d-68"))
+		})
+	})
+
+	var _ = Context("addSysfsInfo", func() {
+		var origDevicesPath string
+
+		BeforeEach(func() {
+			origDevicesPath = pciDevicesPath
+			pciDevicesPath = GinkgoT().TempDir()
+		})
+
+		AfterEach(func() {
+			pciDevicesPath = origDevicesPath
+		})
+
+		writeAttrs := func(dir string, attrs map[string]string) {
+			Expect(os.MkdirAll(dir, 0700)).To(Succeed())
+			for name, value := range attrs {
+				Expect(os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0600)).To(Succeed())
+			}
+		}
+
+		var _ = It("will add NUMA node, PCIe link, SR-IOV and network interfaces", func() {
+			devicePath := filepath.Join(pciDevicesPath, "0000:18:00.0")
+			writeAttrs(devicePath, map[string]string{
+				"numa_node":          "1",
+				"current_link_speed": "16.0 GT/s PCIe",
+				"current_link_width": "8",
+				"max_link_speed":     "16.0 GT/s PCIe",
+				"max_link_width":     "16",
+				"sriov_totalvfs":     "128",
+				"sriov_numvfs":       "4",
+			})
+			writeAttrs(filepath.Join(devicePath, "net", "ens1f0"), map[string]string{"operstate": "up", "speed": "100000"})
+			writeAttrs(filepath.Join(devicePath, "net", "ens1f0v1"), map[string]string{"operstate": "down", "speed": "-1"})
+			Expect(os.Symlink("../../../bus/pci/drivers/ice", filepath.Join(devicePath, "driver"))).To(Succeed())
+
+			d := ethernetv1.Device{PCIAddress: "0000:18:00.0", Driver: "ice"}
+			addSysfsInfo(log, &d)
+
+			Expect(d.NUMANode).ToNot(BeNil())
+			Expect(*d.NUMANode).To(Equal(1))
+			Expect(d.PCIeLink).To(Equal(&ethernetv1.PCIeLinkInfo{Speed: "16.0 GT/s PCIe", Width: 8,
+				MaxSpeed: "16.0 GT/s PCIe", MaxWidth: 16}))
+			Expect(d.SRIOV).To(Equal(&ethernetv1.SRIOVInfo{TotalVFs: 128, NumVFs: 4}))
+			Expect(d.DPDKBound).To(BeFalse())
+			Expect(d.NetworkInterfaces).To(Equal([]ethernetv1.NetworkInterface{
+				{Name: "ens1f0", LinkState: "up", Speed: 100000},
+				{Name: "ens1f0v1", LinkState: "down"},
+			}))
+		})
+
+		var _ = It("will report device bound to vfio-pci and skip missing attributes", func() {
+			devicePath := filepath.Join(pciDevicesPath, "0000:18:00.1")
+			writeAttrs(devicePath, map[string]string{"numa_node": "-1"})
+			Expect(os.Symlink("../../../bus/pci/drivers/vfio-pci", filepath.Join(devicePath, "driver"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "config"), testConfigSpace(0xb49691ffffaf6d68), 0600)).To(Succeed())
+
+			d := ethernetv1.Device{PCIAddress: "0000:18:00.1"}
+			addSysfsInfo(log, &d)
+
+			Expect(d.DPDKBound).To(BeTrue())
+			Expect(d.Driver).To(Equal("vfio-pci"))
+			Expect(d.SerialNumber).To(Equal("b4-96-91-ff-ff-af-6d-68"))
+			Expect(d.NUMANode).To(BeNil())
+			Expect(d.PCIeLink).To(BeNil())
+			Expect(d.SRIOV).To(BeNil())
+			Expect(d.NetworkInterfaces).To(BeEmpty())
 		})
 	})
 })
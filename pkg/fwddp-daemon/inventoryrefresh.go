@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"golang.org/x/sys/unix"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+const (
+	inventoryRefreshIntervalEnvVarName = "INVENTORY_REFRESH_INTERVAL_SECONDS"
+	inventoryRefreshIntervalDefault    = int64(300)
+)
+
+// uevents come in bursts, e.g. driver bind is followed by creation of network interfaces, inventory is
+// refreshed once they settle
+var ueventSettleDelay = 5 * time.Second
+
+// uevents which may change the inventory, by subsystem
+var inventoryUevents = map[string]map[string]bool{
+	"pci": {"add": true, "remove": true, "bind": true, "unbind": true, "change": true},
+	"net": {"add": true, "remove": true, "move": true},
+}
+
+// listenUevents notifies events about kernel uevents changing the inventory until ctx is done
+var listenUevents = listenKernelUevents
+
+// inventoryRefresher refreshes inventory of the node periodically and when devices or their drivers change,
+// independently of reconciling changes of EthernetNodeConfig
+type inventoryRefresher struct {
+	log      logr.Logger
+	interval time.Duration
+	refresh  func(ctx context.Context) error
+}
+
+// NeedLeaderElection is false, the refresher is run by the daemon of each node
+func (i *inventoryRefresher) NeedLeaderElection() bool {
+	return false
+}
+
+func (i *inventoryRefresher) Start(ctx context.Context) error {
+	events := make(chan struct{}, 1)
+	go func() {
+		if err := listenUevents(ctx, events); err != nil {
+			i.log.Error(err, "failed to listen to kernel uevents, inventory is refreshed only periodically")
+		}
+	}()
+
+	var tick <-chan time.Time
+	if i.interval > 0 {
+		ticker := time.NewTicker(i.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+			i.run(ctx)
+		case <-events:
+			settle = time.After(ueventSettleDelay)
+		case <-settle:
+			settle = nil
+			i.run(ctx)
+		}
+	}
+}
+
+func (i *inventoryRefresher) run(ctx context.Context) {
+	if err := i.refresh(ctx); err != nil {
+		i.log.Error(err, "failed to refresh inventory")
+	}
+}
+
+// refreshInventory updates inventory in the status of EthernetNodeConfig of the node and reports port settings
+// which drifted. Settings are only observed, they are re-applied by Reconcile once devices are updated. Status is
+// not written if it didn't change, nor while devices are being updated
+func (r *NodeConfigReconciler) refreshInventory(ctx context.Context) error {
+	log := r.log.WithName("refreshInventory")
+
+	nc := &ethernetv1.EthernetNodeConfig{}
+	if err := r.Get(ctx, r.nodeNameRef, nc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	condition := meta.FindStatusCondition(nc.Status.Conditions, UpdateCondition)
+	if condition != nil && (condition.Reason == string(UpdateInProgress) ||
+		condition.Reason == string(UpdatePostUpdateReboot)) {
+		log.V(4).Info("Update in progress, inventory not refreshed")
+		return nil
+	}
+
+	inv, err := getInventory(log)
+	if err != nil {
+		return err
+	}
+	return r.syncPortSettings(nc, inv, false)
+}
+
+// listenKernelUevents receives uevents broadcast by the kernel on netlink socket
+func listenKernelUevents(ctx context.Context, events chan<- struct{}) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1}); err != nil {
+		return err
+	}
+	// receive times out to check if ctx is done
+	timeout := unix.NsecToTimeval(time.Second.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	for ctx.Err() == nil {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == unix.EAGAIN || err == unix.EINTR {
+				continue
+			}
+			return err
+		}
+		if isInventoryUevent(buf[:n]) {
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// isInventoryUevent returns true if uevent (ACTION@DEVPATH header followed by KEY=VALUE properties,
+// separated with NUL) may change the inventory
+func isInventoryUevent(msg []byte) bool {
+	var action, subsystem string
+	for _, field := range bytes.Split(msg, []byte{0}) {
+		key, value, found := bytes.Cut(field, []byte("="))
+		if !found {
+			continue
+		}
+		switch string(key) {
+		case "ACTION":
+			action = string(value)
+		case "SUBSYSTEM":
+			subsystem = string(value)
+		}
+	}
+	return inventoryUevents[subsystem][action]
+}
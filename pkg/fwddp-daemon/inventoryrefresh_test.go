@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func uevent(fields ...string) []byte {
+	return []byte(strings.Join(fields, "\x00") + "\x00")
+}
+
+var _ = Describe("inventoryRefresher", func() {
+	log := ctrl.Log.WithName("inventoryRefresher-test")
+
+	var _ = It("will select uevents of PCI devices and network interfaces", func() {
+		Expect(isInventoryUevent(uevent("bind@/devices/pci0000:17/0000:17:02.0/0000:18:00.0", "ACTION=bind",
+			"DEVPATH=/devices/pci0000:17/0000:17:02.0/0000:18:00.0", "SUBSYSTEM=pci", "DRIVER=ice"))).To(BeTrue())
+		Expect(isInventoryUevent(uevent("add@/devices/virtual/net/ens1f0", "ACTION=add", "SUBSYSTEM=net",
+			"INTERFACE=ens1f0"))).To(BeTrue())
+		Expect(isInventoryUevent(uevent("change@/devices/virtual/net/ens1f0", "ACTION=change",
+			"SUBSYSTEM=net"))).To(BeFalse())
+		Expect(isInventoryUevent(uevent("add@/devices/virtual/block/loop0", "ACTION=add",
+			"SUBSYSTEM=block"))).To(BeFalse())
+		Expect(isInventoryUevent([]byte("libudev\x00garbage"))).To(BeFalse())
+	})
+
+	var _ = Context("Start", func() {
+		var (
+			origListenUevents func(context.Context, chan<- struct{}) error
+			origSettleDelay   time.Duration
+			uevents           chan chan<- struct{}
+			refreshed         chan struct{}
+		)
+
+		BeforeEach(func() {
+			origListenUevents, origSettleDelay = listenUevents, ueventSettleDelay
+			uevents = make(chan chan<- struct{}, 1)
+			listenUevents = func(ctx context.Context, events chan<- struct{}) error {
+				uevents <- events
+				<-ctx.Done()
+				return nil
+			}
+			ueventSettleDelay = 10 * time.Millisecond
+			refreshed = make(chan struct{}, 10)
+		})
+
+		AfterEach(func() {
+			listenUevents, ueventSettleDelay = origListenUevents, origSettleDelay
+		})
+
+		start := func(interval time.Duration) (context.CancelFunc, chan error) {
+			refresher := &inventoryRefresher{
+				log:      log,
+				interval: interval,
+				refresh: func(context.Context) error {
+					refreshed <- struct{}{}
+					return nil
+				},
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- refresher.Start(ctx) }()
+			return cancel, done
+		}
+
+		var _ = It("will refresh inventory periodically", func() {
+			cancel, done := start(10 * time.Millisecond)
+			Eventually(refreshed).Should(Receive())
+			Eventually(refreshed).Should(Receive())
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+
+		var _ = It("will refresh inventory once after burst of uevents settles", func() {
+			cancel, done := start(0)
+			events := <-uevents
+			for i := 0; i < 3; i++ {
+				events <- struct{}{}
+			}
+			Eventually(refreshed).Should(Receive())
+			Consistently(refreshed, 50*time.Millisecond).ShouldNot(Receive())
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+})
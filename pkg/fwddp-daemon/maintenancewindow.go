@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	maintenanceUpdateDurationEnvVarName = "MAINTENANCE_UPDATE_DURATION_SECONDS"
+	maintenanceUpdateDurationDefault    = int64(3600)
+)
+
+var timeNow = time.Now
+
+// deferredUpdate describes update postponed because of maintenance window of a device
+type deferredUpdate struct {
+	// time after which the update is to be retried, zero if the window cannot be evaluated
+	retryAt time.Time
+	reason  string
+}
+
+// checkMaintenanceWindows returns deferredUpdate if node drain, update and reboot, expected to take
+// updateDuration, cannot be completed inside maintenance windows of all queued devices
+func (r *NodeConfigReconciler) checkMaintenanceWindows(nodeConfig *ethernetv1.EthernetNodeConfig,
+	updateQueue deviceUpdateQueue) *deferredUpdate {
+	log := r.log.WithName("checkMaintenanceWindows")
+	now := timeNow()
+
+	var deferred *deferredUpdate
+	for _, config := range nodeConfig.Spec.Config {
+		if _, queued := updateQueue[config.PCIAddress]; !queued || config.MaintenanceWindow == nil {
+			continue
+		}
+
+		mw := config.MaintenanceWindow
+		window, err := utils.ParseMaintenanceWindow(mw.Schedule, mw.Duration.Duration, mw.TimeZone)
+		if err == nil {
+			err = window.CheckUpdateFits(r.updateDuration)
+		}
+		if err != nil {
+			log.Error(err, "invalid maintenance window", "device", config.PCIAddress)
+			return &deferredUpdate{reason: fmt.Sprintf("Update of device %v deferred, invalid maintenance window: %v",
+				config.PCIAddress, err)}
+		}
+
+		end, open := window.End(now)
+		if open && !end.Before(now.Add(r.updateDuration)) {
+			continue
+		}
+
+		next := window.NextStart(now)
+		var reason string
+		if open {
+			reason = fmt.Sprintf("Update deferred, maintenance window of device %v closes at %v, before the update "+
+				"could complete. Next window opens at %v", config.PCIAddress, end.Format(time.RFC3339), next.Format(time.RFC3339))
+		} else {
+			reason = fmt.Sprintf("Update deferred until maintenance window of device %v opens at %v",
+				config.PCIAddress, next.Format(time.RFC3339))
+		}
+		log.V(2).Info(reason)
+		if deferred == nil || next.Before(deferred.retryAt) {
+			deferred = &deferredUpdate{retryAt: next, reason: reason}
+		}
+	}
+	return deferred
+}
+
+// deferredUpdateQueue returns devices of the update deferred by the previous reconcile, if the config was not
+// changed since. Windows of these devices are checked before the packages are prepared again
+func deferredUpdateQueue(nodeConfig *ethernetv1.EthernetNodeConfig) deviceUpdateQueue {
+	condition := meta.FindStatusCondition(nodeConfig.Status.Conditions, UpdateCondition)
+	if condition == nil || condition.Reason != string(UpdateDeferred) ||
+		condition.ObservedGeneration != nodeConfig.GetGeneration() {
+		return nil
+	}
+
+	updateQueue := make(deviceUpdateQueue)
+	for _, s := range nodeConfig.Status.DeviceUpdates {
+		if s.Phase != DevicePhaseUpToDate {
+			updateQueue[s.PCIAddress] = deviceUpdateArtifacts{}
+		}
+	}
+	return updateQueue
+}
+
+// deferUpdate reports the update deferred until the maintenance window allows it
+func (r *NodeConfigReconciler) deferUpdate(nodeConfig *ethernetv1.EthernetNodeConfig,
+	deferred *deferredUpdate) (reconcile.Result, error) {
+	r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateDeferred, deferred.reason)
+	if deferred.retryAt.IsZero() {
+		return doNotRequeue()
+	}
+	return reconcile.Result{RequeueAfter: deferred.retryAt.Sub(timeNow())}, nil
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"time"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("checkMaintenanceWindows", func() {
+	var (
+		reconciler  *NodeConfigReconciler
+		nodeConfig  *ethernetv1.EthernetNodeConfig
+		updateQueue deviceUpdateQueue
+	)
+
+	BeforeEach(func() {
+		reconciler = &NodeConfigReconciler{log: log, updateDuration: time.Hour}
+		// every day 22:00 - 02:00 UTC
+		nodeConfig = &ethernetv1.EthernetNodeConfig{
+			Spec: ethernetv1.EthernetNodeConfigSpec{
+				Config: []ethernetv1.DeviceNodeConfig{
+					{
+						PCIAddress: "0000:18:00.0",
+						MaintenanceWindow: &ethernetv1.MaintenanceWindow{
+							Schedule: "0 22 * * *",
+							Duration: metav1.Duration{Duration: 4 * time.Hour},
+						},
+					},
+					{PCIAddress: "0000:19:00.0"},
+				},
+			},
+		}
+		updateQueue = deviceUpdateQueue{"0000:18:00.0": {ddpPath: "ice.pkg"}, "0000:19:00.0": {ddpPath: "ice.pkg"}}
+	})
+
+	AfterEach(func() {
+		timeNow = time.Now
+	})
+
+	var _ = It("will allow update which completes inside the window", func() {
+		timeNow = func() time.Time { return time.Date(2023, 7, 15, 23, 0, 0, 0, time.UTC) }
+		Expect(reconciler.checkMaintenanceWindows(nodeConfig, updateQueue)).To(BeNil())
+	})
+
+	var _ = It("will defer update outside of the window until it opens", func() {
+		timeNow = func() time.Time { return time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC) }
+		deferred := reconciler.checkMaintenanceWindows(nodeConfig, updateQueue)
+		Expect(deferred).ToNot(BeNil())
+		Expect(deferred.retryAt).To(BeTemporally("==", time.Date(2023, 7, 15, 22, 0, 0, 0, time.UTC)))
+		Expect(deferred.reason).To(ContainSubstring("opens at 2023-07-15T22:00:00Z"))
+	})
+
+	var _ = It("will defer update which cannot complete before the window closes", func() {
+		timeNow = func() time.Time { return time.Date(2023, 7, 16, 1, 30, 0, 0, time.UTC) }
+		deferred := reconciler.checkMaintenanceWindows(nodeConfig, updateQueue)
+		Expect(deferred).ToNot(BeNil())
+		Expect(deferred.retryAt).To(BeTemporally("==", time.Date(2023, 7, 16, 22, 0, 0, 0, time.UTC)))
+		Expect(deferred.reason).To(ContainSubstring("closes at 2023-07-16T02:00:00Z"))
+	})
+
+	var _ = It("will ignore window of device which is not queued", func() {
+		timeNow = func() time.Time { return time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC) }
+		delete(updateQueue, "0000:18:00.0")
+		Expect(reconciler.checkMaintenanceWindows(nodeConfig, updateQueue)).To(BeNil())
+	})
+
+	var _ = It("will defer update without retry if the window is invalid", func() {
+		nodeConfig.Spec.Config[0].MaintenanceWindow.Schedule = "0 22 * *"
+		deferred := reconciler.checkMaintenanceWindows(nodeConfig, updateQueue)
+		Expect(deferred).ToNot(BeNil())
+		Expect(deferred.retryAt.IsZero()).To(BeTrue())
+		Expect(deferred.reason).To(ContainSubstring("invalid maintenance window schedule"))
+	})
+
+	var _ = It("will defer update without retry if the window is shorter than the update", func() {
+		nodeConfig.Spec.Config[0].MaintenanceWindow.Duration = metav1.Duration{Duration: 30 * time.Minute}
+		deferred := reconciler.checkMaintenanceWindows(nodeConfig, updateQueue)
+		Expect(deferred).ToNot(BeNil())
+		Expect(deferred.retryAt.IsZero()).To(BeTrue())
+		Expect(deferred.reason).To(ContainSubstring("invalid maintenance window"))
+		Expect(deferred.reason).To(ContainSubstring("shorter than expected update duration 1h0m0s"))
+	})
+
+	var _ = It("will queue devices of deferred update which were not up to date", func() {
+		nodeConfig.Generation = 2
+		nodeConfig.Status.Conditions = []metav1.Condition{{
+			Type:               UpdateCondition,
+			Reason:             string(UpdateDeferred),
+			ObservedGeneration: 2,
+		}}
+		nodeConfig.Status.DeviceUpdates = []ethernetv1.DeviceUpdateStatus{
+			{PCIAddress: "0000:18:00.0", Phase: DevicePhaseExtracting},
+			{PCIAddress: "0000:19:00.0", Phase: DevicePhaseUpToDate},
+		}
+		Expect(deferredUpdateQueue(nodeConfig)).To(Equal(deviceUpdateQueue{"0000:18:00.0": {}}))
+
+		// config changed since the update was deferred
+		nodeConfig.Generation = 3
+		Expect(deferredUpdateQueue(nodeConfig)).To(BeNil())
+
+		nodeConfig.Status.Conditions[0].Reason = string(UpdateSucceeded)
+		nodeConfig.Generation = 2
+		Expect(deferredUpdateQueue(nodeConfig)).To(BeNil())
+	})
+})
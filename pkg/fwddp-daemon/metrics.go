@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+const (
+	metricsNamespace = "intel_ethernet"
+
+	// reasons of update failures, the reason label of update_failures_total
+	failureReasonPrepare             = "PrepareFailed"
+	failureReasonIncompatible        = "Incompatible"
+	failureReasonDrainFailed         = "DrainFailed"
+	failureReasonFlashFailed         = "FlashFailed"
+	failureReasonDDPFailed           = "DDPFailed"
+	failureReasonDevlinkParamsFailed = "DevlinkParamsFailed"
+	failureReasonRebootFailed        = "RebootFailed"
+	failureReasonVersionMismatch     = "VersionMismatch"
+)
+
+var (
+	deviceInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "device_info",
+		Help:      "Device reported by the node inventory, with its firmware, DDP and driver versions as labels",
+	}, []string{"pci_address", "device_name", "firmware_version", "ddp_package", "ddp_version", "driver",
+		"driver_version"})
+
+	updateAttempts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "update_attempts_total",
+		Help:      "Number of firmware/DDP updates of devices attempted",
+	})
+
+	updateFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "update_failures_total",
+		Help:      "Number of failed firmware/DDP updates by reason",
+	}, []string{"reason"})
+
+	downloadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "download_bytes_total",
+		Help:      "Number of bytes of downloaded firmware and DDP packages",
+	}, []string{"package"})
+
+	downloadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "download_duration_seconds",
+		Help:      "Duration of firmware and DDP package downloads, including retries",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 3600},
+	}, []string{"package"})
+
+	drainDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "drain_duration_seconds",
+		Help:      "Duration of node cordon and drain before the update",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	})
+
+	rebootPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "reboot_pending",
+		Help:      "1 if the node waits for the reboot completing the update, 0 otherwise",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(deviceInfo, updateAttempts, updateFailures, downloadBytes, downloadDuration,
+		drainDuration, rebootPending)
+}
+
+// observeNodeStatus updates metrics derived from status of EthernetNodeConfig
+func observeNodeStatus(status *ethernetv1.EthernetNodeConfigStatus) {
+	deviceInfo.Reset()
+	for _, d := range status.Devices {
+		deviceInfo.WithLabelValues(d.PCIAddress, d.Name, d.Firmware.Version, d.DDP.PackageName, d.DDP.Version,
+			d.Driver, d.DriverVersion).Set(1)
+	}
+
+	if status.Reboot != nil && status.Reboot.Pending {
+		rebootPending.Set(1)
+	} else {
+		rebootPending.Set(0)
+	}
+}
+
+// observeDownload records size and duration of package download started at start
+func observeDownload(pkg string, size int64, start time.Time) {
+	downloadBytes.WithLabelValues(pkg).Add(float64(size))
+	downloadDuration.WithLabelValues(pkg).Observe(timeNow().Sub(start).Seconds())
+}
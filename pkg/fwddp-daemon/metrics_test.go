@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+var _ = Describe("metrics", func() {
+	var _ = It("will report devices and pending reboot from node status", func() {
+		status := &ethernetv1.EthernetNodeConfigStatus{
+			Devices: []ethernetv1.Device{{
+				PCIAddress:    "0000:01:00.0",
+				Name:          "E810-C",
+				Driver:        "ice",
+				DriverVersion: "1.11.14",
+				Firmware:      ethernetv1.FirmwareInfo{Version: "4.20 0x8001778b 1.3346.0"},
+				DDP:           ethernetv1.DDPInfo{PackageName: "ICE OS Default Package", Version: "1.3.30.0"},
+			}},
+			Reboot: &ethernetv1.RebootStatus{Pending: true},
+		}
+		observeNodeStatus(status)
+		Expect(testutil.ToFloat64(deviceInfo.WithLabelValues("0000:01:00.0", "E810-C", "4.20 0x8001778b 1.3346.0",
+			"ICE OS Default Package", "1.3.30.0", "ice", "1.11.14"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(rebootPending)).To(Equal(1.0))
+
+		observeNodeStatus(&ethernetv1.EthernetNodeConfigStatus{})
+		Expect(testutil.CollectAndCount(deviceInfo)).To(Equal(0))
+		Expect(testutil.ToFloat64(rebootPending)).To(Equal(0.0))
+	})
+
+	var _ = It("will record size and duration of download", func() {
+		oldTimeNow := timeNow
+		defer func() { timeNow = oldTimeNow }()
+		start := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+		timeNow = func() time.Time { return start.Add(3 * time.Second) }
+
+		before := testutil.ToFloat64(downloadBytes.WithLabelValues("firmware"))
+		observeDownload("firmware", 1024, start)
+		Expect(testutil.ToFloat64(downloadBytes.WithLabelValues("firmware")) - before).To(Equal(1024.0))
+		Expect(testutil.CollectAndCount(downloadDuration)).To(BeNumerically(">=", 1))
+	})
+})
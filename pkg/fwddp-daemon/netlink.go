@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	nlaTypeMask = ^uint16(unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+
+	// replies are expected promptly, inventory shouldn't hang if the kernel doesn't respond
+	genlReceiveTimeout = 5 * time.Second
+)
+
+var errMalformedNetlinkMessage = errors.New("malformed netlink message")
+
+// netlink messages are in the byte order of the host
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// nlAttr is netlink attribute, type excludes nested and byte order flags
+type nlAttr struct {
+	typ  uint16
+	data []byte
+}
+
+func stringAttr(typ uint16, value string) nlAttr {
+	return nlAttr{typ: typ, data: append([]byte(value), 0)}
+}
+
+func (a nlAttr) str() string {
+	return strings.TrimRight(string(a.data), "\x00")
+}
+
+func (a nlAttr) nested() ([]nlAttr, error) {
+	return decodeAttrs(a.data)
+}
+
+func nlAlign(length int) int {
+	return (length + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
+
+func encodeAttrs(attrs []nlAttr) []byte {
+	var b []byte
+	for _, a := range attrs {
+		header := make([]byte, unix.NLA_HDRLEN)
+		nativeEndian.PutUint16(header[0:2], uint16(unix.NLA_HDRLEN+len(a.data)))
+		nativeEndian.PutUint16(header[2:4], a.typ)
+		b = append(b, header...)
+		b = append(b, a.data...)
+		b = append(b, make([]byte, nlAlign(len(a.data))-len(a.data))...)
+	}
+	return b
+}
+
+func decodeAttrs(b []byte) ([]nlAttr, error) {
+	var attrs []nlAttr
+	for len(b) >= unix.NLA_HDRLEN {
+		length := int(nativeEndian.Uint16(b[0:2]))
+		if length < unix.NLA_HDRLEN || length > len(b) {
+			return nil, errMalformedNetlinkMessage
+		}
+		attrs = append(attrs, nlAttr{typ: nativeEndian.Uint16(b[2:4]) & nlaTypeMask, data: b[unix.NLA_HDRLEN:length]})
+		if nlAlign(length) >= len(b) {
+			break
+		}
+		b = b[nlAlign(length):]
+	}
+	return attrs, nil
+}
+
+// genlConn is a minimal generic netlink client, sufficient for simple requests and dumps
+type genlConn struct {
+	fd  int
+	seq uint32
+}
+
+func dialGenl() (*genlConn, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_GENERIC)
+	if err != nil {
+		return nil, err
+	}
+	c := &genlConn{fd: fd}
+	if err := c.setReceiveTimeout(genlReceiveTimeout); err != nil {
+		c.close()
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// setReceiveTimeout sets how long execute waits for each reply, receive fails with EAGAIN on timeout
+func (c *genlConn) setReceiveTimeout(timeout time.Duration) error {
+	tv := unix.NsecToTimeval(timeout.Nanoseconds())
+	return unix.SetsockoptTimeval(c.fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+}
+
+func (c *genlConn) close() error {
+	return unix.Close(c.fd)
+}
+
+// execute sends cmd to the family and returns attributes of the replies. Error reported by the kernel is returned
+// as unix.Errno
+func (c *genlConn) execute(family uint16, cmd, version uint8, attrs []nlAttr) ([][]nlAttr, error) {
+	return c.request(family, cmd, version, unix.NLM_F_REQUEST|unix.NLM_F_ACK, attrs)
+}
+
+// dump sends cmd to the family requesting all objects, e.g. parameters of all devices, and returns attributes
+// of the replies
+func (c *genlConn) dump(family uint16, cmd, version uint8, attrs []nlAttr) ([][]nlAttr, error) {
+	return c.request(family, cmd, version, unix.NLM_F_REQUEST|unix.NLM_F_DUMP, attrs)
+}
+
+func (c *genlConn) request(family uint16, cmd, version uint8, flags uint16, attrs []nlAttr) ([][]nlAttr, error) {
+	c.seq++
+	msg := make([]byte, unix.NLMSG_HDRLEN+unix.GENL_HDRLEN)
+	msg = append(msg, encodeAttrs(attrs)...)
+	nativeEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	nativeEndian.PutUint16(msg[4:6], family)
+	nativeEndian.PutUint16(msg[6:8], flags)
+	nativeEndian.PutUint32(msg[8:12], c.seq)
+	msg[unix.NLMSG_HDRLEN] = cmd
+	msg[unix.NLMSG_HDRLEN+1] = version
+
+	if err := unix.Sendto(c.fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	var replies [][]nlAttr
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(c.fd, buf, 0)
+		if err != nil {
+			return nil, err
+		}
+		for b := buf[:n]; len(b) >= unix.NLMSG_HDRLEN; {
+			length := int(nativeEndian.Uint32(b[0:4]))
+			if length < unix.NLMSG_HDRLEN || length > len(b) {
+				return nil, errMalformedNetlinkMessage
+			}
+			msgType, seq := nativeEndian.Uint16(b[4:6]), nativeEndian.Uint32(b[8:12])
+			body := b[unix.NLMSG_HDRLEN:length]
+			if nlAlign(length) >= len(b) {
+				b = nil
+			} else {
+				b = b[nlAlign(length):]
+			}
+			if seq != c.seq {
+				continue
+			}
+
+			switch msgType {
+			case unix.NLMSG_ERROR:
+				if len(body) < 4 {
+					return nil, errMalformedNetlinkMessage
+				}
+				// error code 0 acknowledges the request
+				if code := int32(nativeEndian.Uint32(body[0:4])); code != 0 {
+					return nil, unix.Errno(-code)
+				}
+				return replies, nil
+			case unix.NLMSG_DONE:
+				return replies, nil
+			default:
+				if len(body) < unix.GENL_HDRLEN {
+					return nil, errMalformedNetlinkMessage
+				}
+				replyAttrs, err := decodeAttrs(body[unix.GENL_HDRLEN:])
+				if err != nil {
+					return nil, err
+				}
+				replies = append(replies, replyAttrs)
+			}
+		}
+	}
+}
+
+// resolveFamily returns ID of the generic netlink family
+func (c *genlConn) resolveFamily(name string) (uint16, error) {
+	replies, err := c.execute(unix.GENL_ID_CTRL, unix.CTRL_CMD_GETFAMILY, 1,
+		[]nlAttr{stringAttr(unix.CTRL_ATTR_FAMILY_NAME, name)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve generic netlink family %v: %v", name, err)
+	}
+	for _, attrs := range replies {
+		for _, a := range attrs {
+			if a.typ == unix.CTRL_ATTR_FAMILY_ID && len(a.data) >= 2 {
+				return nativeEndian.Uint16(a.data), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("generic netlink family %v not found", name)
+}
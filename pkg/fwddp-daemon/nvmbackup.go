@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	RollbackSucceeded   = "Succeeded"
+	RollbackFailed      = "Failed"
+	RollbackUnavailable = "Unavailable"
+
+	backupOutFile  = "backup.xml"
+	restoreOutFile = "restore.xml"
+	// extension of NVM image files saved by NVM Update utility run with -b option
+	backupImageExt = ".bin"
+)
+
+// nvmBackupFolder is kept on persistent host storage outside of artifactsFolder, so saved NVM images survive
+// artifacts cleanup and the reboot activating the new firmware
+var nvmBackupFolder = "/var/lib/intel-ethernet-operator/nvmbackup"
+
+// backupNVM saves NVM images of the device using NVM Update utility from the package placed in fwPath.
+// Images are stored in a per-device folder which is returned on success, together with the utility and its
+// config used to restore them once the package is removed.
+func (f *fwUpdater) backupNVM(pciAddr, fwPath string) (string, error) {
+	log := f.log.WithName("backupNVM")
+
+	pciLocation, err := nvmupdateLocation(pciAddr, log)
+	if err != nil {
+		return "", err
+	}
+
+	backupPath := filepath.Join(nvmBackupFolder, pciAddr)
+	if err := os.RemoveAll(backupPath); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(backupPath, 0700); err != nil {
+		return "", err
+	}
+
+	log.V(2).Info("Saving NVM image", "pciLocation", pciLocation, "backupPath", backupPath)
+
+	// -b saves images into the working directory of the tool
+	cmd := exec.Command(filepath.Join(fwPath, nvmupdate64e), "-i", "-b", "-location", pciLocation,
+		"-c", nvmupdate64eCfgPath(fwPath), "-o", filepath.Join(backupPath, backupOutFile), "-l")
+	cmd.Dir = backupPath
+
+	if err := runNvmupdate(cmd, log); err != nil {
+		discardBackup(backupPath, log)
+		return "", fmt.Errorf("failed to save NVM image of device %v: %v", pciAddr, err)
+	}
+	// the utility may exit with 0 without saving the images, e.g. if the device was not found
+	if err := verifyBackupImages(backupPath); err != nil {
+		discardBackup(backupPath, log)
+		return "", fmt.Errorf("failed to save NVM image of device %v: %v", pciAddr, err)
+	}
+
+	for _, path := range []string{filepath.Join(fwPath, nvmupdate64e), nvmupdate64eCfgPath(fwPath)} {
+		dst := filepath.Join(backupPath, filepath.Base(path))
+		if err := utils.CopyFile(path, dst); err == nil {
+			err = os.Chmod(dst, 0700)
+		}
+		if err != nil {
+			discardBackup(backupPath, log)
+			return "", fmt.Errorf("failed to keep NVM Update utility with saved NVM image of device %v: %v", pciAddr, err)
+		}
+	}
+
+	return backupPath, nil
+}
+
+// verifyBackupImages checks that NVM images saved in backupPath are present and not empty
+func verifyBackupImages(backupPath string) error {
+	images, err := filepath.Glob(filepath.Join(backupPath, "*"+backupImageExt))
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("no NVM image found in %v", backupPath)
+	}
+	for _, image := range images {
+		info, err := os.Stat(image)
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			return fmt.Errorf("saved NVM image %v is empty", image)
+		}
+	}
+	return nil
+}
+
+// discardBackup removes NVM image saved in backupPath
+func discardBackup(backupPath string, log logr.Logger) {
+	if err := os.RemoveAll(backupPath); err != nil {
+		log.Error(err, "Failed to remove saved NVM image", "path", backupPath)
+	}
+}
+
+// restoreNVM writes NVM images saved by backupNVM back to the device, using the utility kept with them
+func (f *fwUpdater) restoreNVM(pciAddr, backupPath string) error {
+	log := f.log.WithName("restoreNVM")
+
+	pciLocation, err := nvmupdateLocation(pciAddr, log)
+	if err != nil {
+		return err
+	}
+
+	log.V(2).Info("Restoring NVM image", "pciLocation", pciLocation, "backupPath", backupPath)
+
+	// -a points the tool to the images saved with -b instead of the ones shipped in the package
+	cmd := exec.Command(filepath.Join(backupPath, nvmupdate64e), "-u", "-f", "-location", pciLocation,
+		"-c", nvmupdate64eCfgPath(backupPath), "-a", backupPath, "-o", filepath.Join(backupPath, restoreOutFile), "-l")
+	cmd.Dir = backupPath
+
+	if err := runNvmupdate(cmd, log); err != nil {
+		return fmt.Errorf("failed to restore NVM image of device %v: %v", pciAddr, err)
+	}
+
+	return nil
+}
+
+// rollback restores saved NVM image after failed firmware update and returns the outcome
+func (f *fwUpdater) rollback(pciAddr, backupPath string, updateErr error) *ethernetv1.FirmwareRollback {
+	log := f.log.WithName("rollback")
+
+	result := &ethernetv1.FirmwareRollback{
+		PCIAddress: pciAddr,
+	}
+
+	switch {
+	case backupPath == "":
+		log.Info("NVM image was not saved before update - unable to rollback", "device", pciAddr)
+		result.Result = RollbackUnavailable
+		result.Message = fmt.Sprintf("firmware update failed: %v; no saved NVM image to restore", updateErr)
+	default:
+		if err := f.restoreNVM(pciAddr, backupPath); err != nil {
+			log.Error(err, "Rollback failed", "device", pciAddr)
+			result.Result = RollbackFailed
+			result.Message = fmt.Sprintf("firmware update failed: %v; %v", updateErr, err)
+		} else {
+			log.Info("Saved NVM image restored", "device", pciAddr)
+			result.Result = RollbackSucceeded
+			result.Message = fmt.Sprintf("firmware update failed: %v; saved NVM image restored", updateErr)
+		}
+	}
+
+	result.Time = metav1.Now()
+	return result
+}
+
+// checkFlashedDevices verifies health of devices flashed with a saved NVM image in inventory inv, taken once the
+// new firmware is active, and restores the image of devices which are not healthy. Saved images are discarded
+// afterwards
+func (r *NodeConfigReconciler) checkFlashedDevices(nc *ethernetv1.EthernetNodeConfig, inv []ethernetv1.Device) error {
+	log := r.log.WithName("checkFlashedDevices")
+
+	entries, err := os.ReadDir(nvmBackupFolder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var failures []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pciAddr := entry.Name()
+		backupPath := filepath.Join(nvmBackupFolder, pciAddr)
+		if err := isDeviceHealthy(pciAddr, inv); err != nil {
+			log.Error(err, "Device failed post-update health check", "device", pciAddr)
+			rollback := r.fwUpdater.rollback(pciAddr, backupPath, err)
+			r.recordRollback(nc, *rollback)
+			failures = append(failures, rollback.Message)
+		}
+		discardBackup(backupPath, log)
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("post-update health check failed: %v", strings.Join(failures, "; "))
+}
+
+// isDeviceHealthy checks if the device is present in inventory inv and reports its firmware version
+func isDeviceHealthy(pciAddr string, inv []ethernetv1.Device) error {
+	for _, device := range inv {
+		if device.PCIAddress != pciAddr {
+			continue
+		}
+		if device.Firmware.Version == "" {
+			return fmt.Errorf("device %v does not report firmware version after update", pciAddr)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("device %v not found after update", pciAddr)
+}
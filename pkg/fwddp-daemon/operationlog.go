@@ -0,0 +1,498 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	OperationLogStorageNone      = "None"
+	OperationLogStorageConfigMap = "ConfigMap"
+	OperationLogStorageSecret    = "Secret"
+	OperationLogStorageS3        = "S3"
+
+	operationLogStorageEnvVarName     = "OPERATION_LOG_STORAGE"
+	operationLogSizeLimitEnvVarName   = "OPERATION_LOG_SIZE_LIMIT_KB"
+	operationLogSizeLimitDefault      = int64(64)
+	operationLogS3EndpointEnvVarName  = "OPERATION_LOG_S3_ENDPOINT"
+	operationLogS3BucketEnvVarName    = "OPERATION_LOG_S3_BUCKET"
+	operationLogS3RegionEnvVarName    = "OPERATION_LOG_S3_REGION"
+	operationLogS3AccessKeyEnvVarName = "AWS_ACCESS_KEY_ID"
+	operationLogS3SecretKeyEnvVarName = "AWS_SECRET_ACCESS_KEY"
+
+	// data of ConfigMap or Secret is limited to 1MiB, some room is left for metadata
+	maxOperationLogObjectSize = 900 * 1024
+
+	operationLogDaemonEntry    = "daemon.log"
+	operationLogNvmupdateEntry = "nvmupdate.out"
+)
+
+// operationLog collects logs of the update of a single device: log of the daemon, output of the tools and
+// their log and result files. Each entry keeps only the last limit bytes written to it. All methods are no-op
+// on nil log
+type operationLog struct {
+	mu      sync.Mutex
+	limit   int
+	names   []string
+	entries map[string]*operationLogEntry
+}
+
+type operationLogEntry struct {
+	log     *operationLog
+	data    []byte
+	dropped int
+}
+
+func newOperationLog(limit int) *operationLog {
+	return &operationLog{limit: limit, entries: map[string]*operationLogEntry{}}
+}
+
+// writer returns writer appending to the named entry
+func (o *operationLog) writer(name string) io.Writer {
+	if o == nil {
+		return io.Discard
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if e, ok := o.entries[name]; ok {
+		return e
+	}
+	e := &operationLogEntry{log: o}
+	o.entries[name] = e
+	o.names = append(o.names, name)
+	return e
+}
+
+func (e *operationLogEntry) Write(p []byte) (int, error) {
+	e.log.mu.Lock()
+	defer e.log.mu.Unlock()
+
+	e.data = append(e.data, p...)
+	if excess := len(e.data) - e.log.limit; excess > 0 {
+		e.dropped += excess
+		e.data = append([]byte(nil), e.data[excess:]...)
+	}
+	return len(p), nil
+}
+
+// addFile copies file into the named entry, missing file is skipped
+func (o *operationLog) addFile(name, path string) error {
+	if o == nil {
+		return nil
+	}
+	f, err := utils.OpenNoLinks(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(o.writer(name), f)
+	return err
+}
+
+// logger returns logger which writes to base and to the daemon entry of the log. Messages of all levels
+// are recorded in the log, regardless of verbosity of base
+func (o *operationLog) logger(base logr.Logger) logr.Logger {
+	if o == nil {
+		return base
+	}
+	sink := base.GetSink()
+	// messages are passed to sink by operationLogSink, one frame deeper
+	if withDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = withDepth.WithCallDepth(1)
+	}
+	return logr.New(&operationLogSink{sink: sink, w: o.writer(operationLogDaemonEntry)})
+}
+
+// data returns content of all entries. Truncated entries are prefixed with the number of dropped bytes
+func (o *operationLog) data() (map[string][]byte, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	data := map[string][]byte{}
+	truncated := false
+	for _, name := range o.names {
+		e := o.entries[name]
+		if e.dropped == 0 {
+			data[name] = append([]byte(nil), e.data...)
+			continue
+		}
+		truncated = true
+		data[name] = append([]byte(fmt.Sprintf("[... %v bytes truncated ...]\n", e.dropped)), e.data...)
+	}
+	return data, truncated
+}
+
+// operationLogSink is logr.LogSink which formats messages into w in addition to passing them to sink
+type operationLogSink struct {
+	sink   logr.LogSink
+	w      io.Writer
+	name   string
+	values []interface{}
+}
+
+// Init is no-op, sink is already initialized by its logger
+func (s *operationLogSink) Init(info logr.RuntimeInfo) {}
+
+func (s *operationLogSink) Enabled(level int) bool {
+	return true
+}
+
+func (s *operationLogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if s.sink != nil && s.sink.Enabled(level) {
+		s.sink.Info(level, msg, keysAndValues...)
+	}
+	s.write("INFO", msg, nil, keysAndValues)
+}
+
+func (s *operationLogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if s.sink != nil {
+		s.sink.Error(err, msg, keysAndValues...)
+	}
+	s.write("ERROR", msg, err, keysAndValues)
+}
+
+func (s *operationLogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	if s.sink != nil {
+		c.sink = s.sink.WithValues(keysAndValues...)
+	}
+	c.values = append(append([]interface{}(nil), s.values...), keysAndValues...)
+	return &c
+}
+
+func (s *operationLogSink) WithName(name string) logr.LogSink {
+	c := *s
+	if s.sink != nil {
+		c.sink = s.sink.WithName(name)
+	}
+	c.name = name
+	if s.name != "" {
+		c.name = s.name + "." + name
+	}
+	return &c
+}
+
+func (s *operationLogSink) write(level, msg string, err error, keysAndValues []interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v %v %v %v", timeNow().UTC().Format("2006-01-02T15:04:05.000Z"), level, s.name, msg)
+	if err != nil {
+		fmt.Fprintf(&b, " error=%q", err.Error())
+	}
+	kv := append(append([]interface{}(nil), s.values...), keysAndValues...)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Fprintf(&b, " %v=%q", kv[i], fmt.Sprint(kv[i+1]))
+	}
+	b.WriteString("\n")
+	_, _ = s.w.Write([]byte(b.String()))
+}
+
+// operationLogStore keeps logs of device updates outside of the daemon
+type operationLogStore interface {
+	store(nc *ethernetv1.EthernetNodeConfig, pciAddr string, data map[string][]byte) (*ethernetv1.OperationLogReference, error)
+}
+
+// newOperationLogStore returns store selected with OPERATION_LOG_STORAGE, nil if logs are not to be kept
+func newOperationLogStore(log logr.Logger, coreClient typedcorev1.CoreV1Interface, ns string,
+	httpClient *http.Client) operationLogStore {
+	storage := os.Getenv(operationLogStorageEnvVarName)
+	switch storage {
+	case "", OperationLogStorageConfigMap:
+		return &objectLogStore{configMaps: coreClient.ConfigMaps(ns)}
+	case OperationLogStorageSecret:
+		return &objectLogStore{secrets: coreClient.Secrets(ns)}
+	case OperationLogStorageS3:
+		s3 := &s3LogStore{
+			httpClient: httpClient,
+			endpoint:   strings.TrimSuffix(os.Getenv(operationLogS3EndpointEnvVarName), "/"),
+			bucket:     os.Getenv(operationLogS3BucketEnvVarName),
+			region:     os.Getenv(operationLogS3RegionEnvVarName),
+			accessKey:  os.Getenv(operationLogS3AccessKeyEnvVarName),
+			secretKey:  os.Getenv(operationLogS3SecretKeyEnvVarName),
+		}
+		if s3.endpoint == "" || s3.bucket == "" {
+			log.Error(nil, "S3 endpoint and bucket are required, operation logs will not be kept",
+				"endpoint", operationLogS3EndpointEnvVarName, "bucket", operationLogS3BucketEnvVarName)
+			return nil
+		}
+		if s3.region == "" {
+			s3.region = "us-east-1"
+		}
+		return s3
+	case OperationLogStorageNone:
+		return nil
+	default:
+		log.Error(nil, "unknown operation log storage, operation logs will not be kept", "storage", storage)
+		return nil
+	}
+}
+
+// newOperationLog returns log collecting logs of the update of a single device, nil if logs are not kept
+func (r *NodeConfigReconciler) newOperationLog() *operationLog {
+	if r.logStore == nil {
+		return nil
+	}
+	return newOperationLog(r.logSizeLimit)
+}
+
+// storeOperationLog stores logs of the device update and references them from the device status. Failure to
+// store the logs doesn't fail the update
+func (r *NodeConfigReconciler) storeOperationLog(nc *ethernetv1.EthernetNodeConfig, pciAddr string, opLog *operationLog,
+	deviceStatus *deviceStatusReporter) {
+	if opLog == nil || r.logStore == nil {
+		return
+	}
+	log := r.log.WithName("storeOperationLog")
+
+	data, truncated := opLog.data()
+	ref, err := r.logStore.store(nc, pciAddr, data)
+	if err != nil {
+		log.Error(err, "failed to store operation logs", "device", pciAddr)
+		return
+	}
+	ref.Truncated = truncated
+	deviceStatus.update(func(s *ethernetv1.DeviceUpdateStatus) {
+		s.Logs = ref
+	})
+}
+
+// objectLogStore keeps logs in ConfigMap (or Secret, if secrets is set) named after the node, created by the manager.
+// Each entry is stored under a key prefixed with the PCI address of the device, replacing entries of its previous
+// update
+type objectLogStore struct {
+	configMaps typedcorev1.ConfigMapInterface
+	secrets    typedcorev1.SecretInterface
+}
+
+func operationLogObjectName(nc *ethernetv1.EthernetNodeConfig) string {
+	return "fwddp-logs-" + nc.Name
+}
+
+func operationLogKeyPrefix(pciAddr string) string {
+	return strings.ReplaceAll(pciAddr, ":", "-") + "_"
+}
+
+func (s *objectLogStore) store(nc *ethernetv1.EthernetNodeConfig, pciAddr string,
+	data map[string][]byte) (*ethernetv1.OperationLogReference, error) {
+	name := operationLogObjectName(nc)
+	prefix := operationLogKeyPrefix(pciAddr)
+
+	// object is created by the manager, only its data is changed, so concurrent changes and owner are kept
+	var entries map[string][]byte
+	ref := &ethernetv1.OperationLogReference{Location: name}
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		var err error
+		if s.secrets != nil {
+			ref.Storage = OperationLogStorageSecret
+			entries, err = s.storeSecret(name, prefix, data)
+		} else {
+			ref.Storage = OperationLogStorageConfigMap
+			entries, err = s.storeConfigMap(name, prefix, data)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range entries {
+		if strings.HasPrefix(key, prefix) {
+			ref.Entries = append(ref.Entries, key)
+		}
+	}
+	sort.Strings(ref.Entries)
+	return ref, nil
+}
+
+// mergeOperationLogEntries replaces entries with the prefix by data. Entries exceeding size limit of the object are
+// dropped, entries of other devices first
+func mergeOperationLogEntries(current map[string][]byte, prefix string, data map[string][]byte) map[string][]byte {
+	own := map[string][]byte{}
+	for name, content := range data {
+		own[prefix+name] = content
+	}
+	others := map[string][]byte{}
+	for key, content := range current {
+		if !strings.HasPrefix(key, prefix) {
+			others[key] = content
+		}
+	}
+
+	entries := map[string][]byte{}
+	size := 0
+	for _, group := range []map[string][]byte{own, others} {
+		keys := make([]string, 0, len(group))
+		for key := range group {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if size+len(group[key]) > maxOperationLogObjectSize {
+				continue
+			}
+			entries[key] = group[key]
+			size += len(group[key])
+		}
+	}
+	return entries
+}
+
+func (s *objectLogStore) storeConfigMap(name, prefix string, data map[string][]byte) (map[string][]byte, error) {
+	cm, err := s.configMaps.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	current := map[string][]byte{}
+	for key, value := range cm.Data {
+		current[key] = []byte(value)
+	}
+	for key, value := range cm.BinaryData {
+		current[key] = value
+	}
+
+	entries := mergeOperationLogEntries(current, prefix, data)
+	cm.Data, cm.BinaryData = map[string]string{}, map[string][]byte{}
+	for key, value := range entries {
+		if utf8.Valid(value) {
+			cm.Data[key] = string(value)
+		} else {
+			cm.BinaryData[key] = value
+		}
+	}
+	_, err = s.configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return entries, err
+}
+
+func (s *objectLogStore) storeSecret(name, prefix string, data map[string][]byte) (map[string][]byte, error) {
+	secret, err := s.secrets.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := mergeOperationLogEntries(secret.Data, prefix, data)
+	secret.Data = entries
+	_, err = s.secrets.Update(context.TODO(), secret, metav1.UpdateOptions{})
+	return entries, err
+}
+
+// s3LogStore uploads logs to S3-compatible object storage (path-style requests), under
+// <node>/<pci address>/<time of upload>/ prefix. Requests are signed with AWS Signature Version 4
+// if credentials are provided
+type s3LogStore struct {
+	httpClient *http.Client
+	endpoint   string
+	bucket     string
+	region     string
+	accessKey  string
+	secretKey  string
+}
+
+func (s *s3LogStore) store(nc *ethernetv1.EthernetNodeConfig, pciAddr string,
+	data map[string][]byte) (*ethernetv1.OperationLogReference, error) {
+	prefix := strings.Join([]string{nc.Name, strings.ReplaceAll(pciAddr, ":", "-"),
+		timeNow().UTC().Format("20060102T150405Z")}, "/") + "/"
+
+	ref := &ethernetv1.OperationLogReference{
+		Storage:  OperationLogStorageS3,
+		Location: s.endpoint + "/" + s.bucket + "/" + prefix,
+	}
+	for name, content := range data {
+		if err := s.putObject(prefix+name, content); err != nil {
+			return nil, err
+		}
+		ref.Entries = append(ref.Entries, name)
+	}
+	sort.Strings(ref.Entries)
+	return ref, nil
+}
+
+func (s *s3LogStore) putObject(key string, content []byte) error {
+	req, err := http.NewRequest(http.MethodPut, s.endpoint+"/"+s.bucket+"/"+key, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	if s.accessKey != "" {
+		s.sign(req, content)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to upload %v: %v %v", key, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 authorization to the request
+func (s *s3LogStore) sign(req *http.Request, content []byte) {
+	now := timeNow().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(content)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+var _ = Describe("operationLog", func() {
+	var _ = It("will keep the tail of entries exceeding the limit", func() {
+		opLog := newOperationLog(8)
+		_, err := io.WriteString(opLog.writer("out"), "0123456789")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.WriteString(opLog.writer("short"), "abc")
+		Expect(err).ToNot(HaveOccurred())
+
+		data, truncated := opLog.data()
+		Expect(truncated).To(BeTrue())
+		Expect(string(data["out"])).To(Equal("[... 2 bytes truncated ...]\n23456789"))
+		Expect(string(data["short"])).To(Equal("abc"))
+	})
+
+	var _ = It("will add existing files and skip missing ones", func() {
+		dir, err := os.MkdirTemp("", "oplog")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		Expect(os.WriteFile(filepath.Join(dir, "update.xml"), []byte("<DeviceUpdate/>"), 0644)).To(Succeed())
+
+		opLog := newOperationLog(1024)
+		Expect(opLog.addFile("update.xml", filepath.Join(dir, "update.xml"))).To(Succeed())
+		Expect(opLog.addFile("nvmupdate.log", filepath.Join(dir, "nvmupdate.log"))).To(Succeed())
+
+		data, truncated := opLog.data()
+		Expect(truncated).To(BeFalse())
+		Expect(data).To(HaveLen(1))
+		Expect(string(data["update.xml"])).To(Equal("<DeviceUpdate/>"))
+	})
+
+	var _ = It("will record messages of all levels with names and values", func() {
+		opLog := newOperationLog(1024)
+		logger := opLog.logger(logr.Discard()).WithName("handleFWUpdate").WithValues("device", "0000:01:00.0")
+		logger.V(4).Info("Starting update")
+		logger.Error(io.EOF, "Update failed", "code", 7)
+
+		data, _ := opLog.data()
+		lines := strings.Split(strings.TrimSpace(string(data[operationLogDaemonEntry])), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(ContainSubstring(`INFO handleFWUpdate Starting update device="0000:01:00.0"`))
+		Expect(lines[1]).To(ContainSubstring(`ERROR handleFWUpdate Update failed error="EOF" device="0000:01:00.0" code="7"`))
+	})
+
+	var _ = It("will hold log of the last NVM Update utility run only", func() {
+		dir := GinkgoT().TempDir()
+		origExec := nvmupdateExec
+		defer func() { nvmupdateExec = origExec }()
+		// the utility appends to the log in its working directory
+		nvmupdateExec = func(cmd *exec.Cmd, _ logr.Logger) error {
+			f, err := os.OpenFile(nvmupdateLogPath(cmd.Dir), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.WriteString(f, strings.Join(cmd.Args[1:], " ")+"\n")
+			return err
+		}
+
+		for _, mode := range []string{"-i", "-u"} {
+			cmd := exec.Command(nvmupdate64e, mode, "-l")
+			cmd.Dir = dir
+			Expect(runNvmupdate(cmd, logr.Discard())).To(Succeed())
+		}
+
+		opLog := newOperationLog(1024)
+		Expect(opLog.addFile(nvmupdateLogFile, nvmupdateLogPath(dir))).To(Succeed())
+		data, _ := opLog.data()
+		Expect(string(data[nvmupdateLogFile])).To(Equal("-u -l\n"))
+	})
+
+	var _ = It("will be no-op if nil", func() {
+		var opLog *operationLog
+		_, err := io.WriteString(opLog.writer("out"), "abc")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opLog.addFile("update.xml", "/dev/null/fake")).To(Succeed())
+		Expect(opLog.logger(logr.Discard())).To(Equal(logr.Discard()))
+	})
+})
+
+var _ = Describe("objectLogStore", func() {
+	nc := &ethernetv1.EthernetNodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: "default", UID: "uid"},
+	}
+	// log objects are created by the manager, owned by the node
+	objectMeta := metav1.ObjectMeta{
+		Name:            "fwddp-logs-node1",
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: "node1", UID: "node-uid"}},
+	}
+
+	var _ = It("will keep logs of the last update of each device in ConfigMap", func() {
+		clientSet := fake.NewSimpleClientset(&corev1.ConfigMap{ObjectMeta: objectMeta})
+		configMaps := clientSet.CoreV1().ConfigMaps("default")
+		store := &objectLogStore{configMaps: configMaps}
+
+		_, err := store.store(nc, "0000:01:00.0", map[string][]byte{"daemon.log": []byte("first")})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = store.store(nc, "0000:02:00.0", map[string][]byte{"daemon.log": []byte("other")})
+		Expect(err).ToNot(HaveOccurred())
+		ref, err := store.store(nc, "0000:01:00.0", map[string][]byte{
+			"update.xml":    []byte("<DeviceUpdate/>"),
+			"nvmupdate.log": {0xff, 0xfe},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ref.Storage).To(Equal(OperationLogStorageConfigMap))
+		Expect(ref.Location).To(Equal("fwddp-logs-node1"))
+		Expect(ref.Entries).To(Equal([]string{"0000-01-00.0_nvmupdate.log", "0000-01-00.0_update.xml"}))
+
+		cm, err := configMaps.Get(context.TODO(), "fwddp-logs-node1", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cm.OwnerReferences).To(Equal(objectMeta.OwnerReferences))
+		Expect(cm.Data).To(Equal(map[string]string{
+			"0000-01-00.0_update.xml": "<DeviceUpdate/>",
+			"0000-02-00.0_daemon.log": "other",
+		}))
+		Expect(cm.BinaryData).To(Equal(map[string][]byte{"0000-01-00.0_nvmupdate.log": {0xff, 0xfe}}))
+	})
+
+	var _ = It("will keep logs in Secret", func() {
+		clientSet := fake.NewSimpleClientset(&corev1.Secret{ObjectMeta: objectMeta})
+		secrets := clientSet.CoreV1().Secrets("default")
+		store := &objectLogStore{secrets: secrets}
+
+		ref, err := store.store(nc, "0000:01:00.0", map[string][]byte{"daemon.log": []byte("log")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ref.Storage).To(Equal(OperationLogStorageSecret))
+
+		secret, err := secrets.Get(context.TODO(), "fwddp-logs-node1", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.OwnerReferences).To(Equal(objectMeta.OwnerReferences))
+		Expect(secret.Data).To(Equal(map[string][]byte{"0000-01-00.0_daemon.log": []byte("log")}))
+	})
+
+	var _ = It("will read the object again if it was changed concurrently", func() {
+		clientSet := fake.NewSimpleClientset(&corev1.ConfigMap{ObjectMeta: objectMeta})
+		conflicts := 0
+		clientSet.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if conflicts > 0 {
+				return false, nil, nil
+			}
+			conflicts++
+			// other device's logs are stored in the meantime
+			cm := &corev1.ConfigMap{ObjectMeta: objectMeta, Data: map[string]string{"0000-02-00.0_daemon.log": "other"}}
+			Expect(clientSet.Tracker().Update(corev1.SchemeGroupVersion.WithResource("configmaps"), cm, "default")).
+				To(Succeed())
+			return true, nil, apierr.NewConflict(corev1.Resource("configmaps"), objectMeta.Name, nil)
+		})
+		store := &objectLogStore{configMaps: clientSet.CoreV1().ConfigMaps("default")}
+
+		_, err := store.store(nc, "0000:01:00.0", map[string][]byte{"daemon.log": []byte("log")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conflicts).To(Equal(1))
+
+		cm, err := clientSet.CoreV1().ConfigMaps("default").Get(context.TODO(), objectMeta.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cm.Data).To(Equal(map[string]string{
+			"0000-01-00.0_daemon.log": "log",
+			"0000-02-00.0_daemon.log": "other",
+		}))
+	})
+
+	var _ = It("will fail if the object was not created by the manager", func() {
+		store := &objectLogStore{configMaps: fake.NewSimpleClientset().CoreV1().ConfigMaps("default")}
+
+		_, err := store.store(nc, "0000:01:00.0", map[string][]byte{"daemon.log": []byte("log")})
+		Expect(apierr.IsNotFound(err)).To(BeTrue())
+	})
+
+	var _ = It("will drop logs of other devices exceeding object size limit", func() {
+		current := map[string][]byte{
+			"0000-02-00.0_daemon.log": make([]byte, maxOperationLogObjectSize/2),
+			"0000-03-00.0_daemon.log": make([]byte, maxOperationLogObjectSize/2),
+		}
+		entries := mergeOperationLogEntries(current, "0000-01-00.0_", map[string][]byte{"daemon.log": []byte("log")})
+		Expect(entries).To(HaveLen(2))
+		Expect(entries).To(HaveKey("0000-01-00.0_daemon.log"))
+		Expect(entries).To(HaveKey("0000-02-00.0_daemon.log"))
+	})
+
+	var _ = It("will drop logs of the device exceeding object size limit", func() {
+		data := map[string][]byte{
+			"daemon.log":    make([]byte, maxOperationLogObjectSize/2),
+			"nvmupdate.log": make([]byte, maxOperationLogObjectSize/2+1),
+			"update.xml":    make([]byte, maxOperationLogObjectSize/2),
+		}
+		entries := mergeOperationLogEntries(nil, "0000-01-00.0_", data)
+		Expect(entries).To(HaveLen(2))
+		Expect(entries).To(HaveKey("0000-01-00.0_daemon.log"))
+		Expect(entries).To(HaveKey("0000-01-00.0_update.xml"))
+	})
+})
+
+var _ = Describe("s3LogStore", func() {
+	var _ = It("will upload signed logs under node, device and time prefix", func() {
+		uploaded := map[string]string{}
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).To(Equal(http.MethodPut))
+			body, err := io.ReadAll(req.Body)
+			Expect(err).ToNot(HaveOccurred())
+			uploaded[req.URL.Path] = string(body)
+			authorization = req.Header.Get("Authorization")
+			Expect(req.Header.Get("x-amz-content-sha256")).To(Equal(sha256Hex(body)))
+		}))
+		defer server.Close()
+
+		oldTimeNow := timeNow
+		defer func() { timeNow = oldTimeNow }()
+		timeNow = func() time.Time { return time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC) }
+
+		store := &s3LogStore{httpClient: server.Client(), endpoint: server.URL, bucket: "logs", region: "eu-west-1",
+			accessKey: "access", secretKey: "secret"}
+		nc := &ethernetv1.EthernetNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+
+		ref, err := store.store(nc, "0000:01:00.0", map[string][]byte{"daemon.log": []byte("log")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ref.Storage).To(Equal(OperationLogStorageS3))
+		Expect(ref.Location).To(Equal(server.URL + "/logs/node1/0000-01-00.0/20230506T070809Z/"))
+		Expect(ref.Entries).To(Equal([]string{"daemon.log"}))
+		Expect(uploaded).To(Equal(map[string]string{"/logs/node1/0000-01-00.0/20230506T070809Z/daemon.log": "log"}))
+		Expect(authorization).To(HavePrefix("AWS4-HMAC-SHA256 Credential=access/20230506/eu-west-1/s3/aws4_request, " +
+			"SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="))
+	})
+
+	var _ = It("will return error if upload is rejected", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		store := &s3LogStore{httpClient: server.Client(), endpoint: server.URL, bucket: "logs"}
+		nc := &ethernetv1.EthernetNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+
+		_, err := store.store(nc, "0000:01:00.0", map[string][]byte{"daemon.log": []byte("log")})
+		Expect(err).To(MatchError(ContainSubstring("403")))
+	})
+})
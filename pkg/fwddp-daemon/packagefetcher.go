@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	downloadRetriesEnvVarName = "DOWNLOAD_RETRIES"
+	downloadRetriesDefault    = int64(5)
+	downloadTimeoutEnvVarName = "DOWNLOAD_TIMEOUT_SECONDS"
+	downloadTimeoutDefault    = int64(3600)
+	// comma separated registries (host[:port]) accessed over plain HTTP
+	plainHTTPRegistriesEnvVarName = "PLAIN_HTTP_REGISTRIES"
+
+	downloadInitialBackoff   = 5 * time.Second
+	downloadMaxBackoff       = 2 * time.Minute
+	downloadProgressInterval = 10 * time.Second
+)
+
+var pullOCIArtifact = utils.PullOCIArtifact
+
+type packageFetcher struct {
+	log                 logr.Logger
+	httpClient          *http.Client
+	secrets             typedcorev1.SecretInterface
+	downloadOptions     utils.DownloadOptions
+	plainHTTPRegistries []string
+}
+
+func newDownloadOptions(log logr.Logger) utils.DownloadOptions {
+	retries := utils.GetOsVarOrUseDefault(log, downloadRetriesEnvVarName, downloadRetriesDefault)
+	timeout := utils.GetOsVarOrUseDefault(log, downloadTimeoutEnvVarName, downloadTimeoutDefault)
+	log.Info("download settings", "retries", retries, "timeout seconds", timeout)
+
+	return utils.DownloadOptions{
+		Retries:          int(retries),
+		InitialBackoff:   downloadInitialBackoff,
+		MaxBackoff:       downloadMaxBackoff,
+		Timeout:          time.Duration(timeout) * time.Second,
+		ProgressInterval: downloadProgressInterval,
+	}
+}
+
+func newPlainHTTPRegistries(log logr.Logger) []string {
+	var registries []string
+	for _, registry := range strings.Split(os.Getenv(plainHTTPRegistriesEnvVarName), ",") {
+		if registry = strings.TrimSpace(registry); registry != "" {
+			registries = append(registries, registry)
+		}
+	}
+	if len(registries) != 0 {
+		log.Info("registries accessed over plain HTTP", "registries", registries)
+	}
+	return registries
+}
+
+// fetchPackage downloads package from HTTP(S) URL or pulls it as OCI artifact into targetPath
+// and returns path of the fetched file. Download progress is reported with progress
+func (p *packageFetcher) fetchPackage(targetPath, url, checksum, pullSecret string,
+	progress utils.DownloadProgressFunc) (string, error) {
+	log := p.log.WithName("fetchPackage")
+
+	if !utils.IsOCIReference(url) {
+		fullPath := filepath.Join(targetPath, filepath.Base(url))
+		log.V(4).Info("Downloading", "url", url, "dstPath", fullPath)
+		opts := p.downloadOptions
+		opts.Progress = progress
+		return fullPath, downloadFile(fullPath, url, checksum, p.httpClient, opts)
+	}
+
+	ref, err := utils.ParseOCIReference(url)
+	if err != nil {
+		return "", err
+	}
+
+	var creds *utils.RegistryCredentials
+	if pullSecret != "" {
+		creds, err = p.getRegistryCredentials(pullSecret, ref.Registry)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log.V(4).Info("Pulling OCI artifact", "reference", url, "dstPath", targetPath)
+	opts := utils.OCIPullOptions{PlainHTTPRegistries: p.plainHTTPRegistries, Download: p.downloadOptions}
+	opts.Download.Progress = progress
+	return pullOCIArtifact(targetPath, url, checksum, creds, p.httpClient, opts)
+}
+
+func (p *packageFetcher) getRegistryCredentials(pullSecret, registry string) (*utils.RegistryCredentials, error) {
+	secret, err := p.secrets.Get(context.TODO(), pullSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull secret %v: %v", pullSecret, err)
+	}
+
+	dockerConfig, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return nil, fmt.Errorf("pull secret %v does not contain %q field", pullSecret, corev1.DockerConfigJsonKey)
+	}
+	return utils.RegistryCredentialsFromDockerConfig(dockerConfig, registry)
+}
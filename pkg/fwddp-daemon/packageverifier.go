@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	signatureKeySecretField = "publicKey"
+	signatureFileSuffix     = ".sig"
+)
+
+var verifyDetachedSignature = utils.VerifyDetachedSignature
+
+type packageVerifier struct {
+	log             logr.Logger
+	httpClient      *http.Client
+	secrets         typedcorev1.SecretInterface
+	downloadOptions utils.DownloadOptions
+}
+
+// verifySignature downloads detached signature of the package and verifies it against public key stored in keySecret.
+// Verification is skipped only if neither signature nor key is configured.
+func (v *packageVerifier) verifySignature(pkgPath, signatureURL, keySecret string) error {
+	log := v.log.WithName("verifySignature")
+
+	if signatureURL == "" && keySecret == "" {
+		return nil
+	}
+	if signatureURL == "" {
+		return fmt.Errorf("signature key secret %v is set, but no signature URL provided for %v", keySecret, filepath.Base(pkgPath))
+	}
+	if keySecret == "" {
+		return fmt.Errorf("signature URL is set, but no signature key secret provided for %v", filepath.Base(pkgPath))
+	}
+
+	publicKey, err := v.getPublicKey(keySecret)
+	if err != nil {
+		return err
+	}
+
+	signaturePath := pkgPath + signatureFileSuffix
+	log.V(4).Info("Downloading signature", "url", signatureURL, "dstPath", signaturePath)
+	if err := downloadFile(signaturePath, signatureURL, "", v.httpClient, v.downloadOptions); err != nil {
+		return err
+	}
+
+	if err := verifyDetachedSignature(pkgPath, signaturePath, publicKey); err != nil {
+		return fmt.Errorf("failed to verify signature of %v: %v", filepath.Base(pkgPath), err)
+	}
+
+	log.V(4).Info("Signature verified", "package", pkgPath)
+	return nil
+}
+
+func (v *packageVerifier) getPublicKey(keySecret string) ([]byte, error) {
+	secret, err := v.secrets.Get(context.TODO(), keySecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signature key secret %v: %v", keySecret, err)
+	}
+
+	publicKey, ok := secret.Data[signatureKeySecretField]
+	if !ok || len(publicKey) == 0 {
+		return nil, fmt.Errorf("signature key secret %v does not contain %q field", keySecret, signatureKeySecretField)
+	}
+	return publicKey, nil
+}
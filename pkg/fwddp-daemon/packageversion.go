@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+)
+
+const (
+	// ICE DDP package layout, see ice_pkg_hdr and ice_global_metadata_seg in ice driver
+	ddpPkgHeaderSize       = 8
+	ddpSegmentHeaderSize   = 44
+	ddpSegmentTypeMetadata = 0x00000001
+	ddpMaxSegments         = 64
+)
+
+var pciDevicesPath = "/sys/bus/pci/devices"
+
+// pciIDs identifies device model in nvmupdate.cfg
+type pciIDs struct {
+	vendor, device, subVendor, subDevice string
+}
+
+func readPCIIDs(pciAddr string) (pciIDs, error) {
+	read := func(name string) (string, error) {
+		content, err := os.ReadFile(filepath.Join(pciDevicesPath, pciAddr, name))
+		if err != nil {
+			return "", err
+		}
+		return normalizeHexID(string(content)), nil
+	}
+
+	var ids pciIDs
+	var err error
+	for _, id := range []struct {
+		name  string
+		value *string
+	}{
+		{"vendor", &ids.vendor},
+		{"device", &ids.device},
+		{"subsystem_vendor", &ids.subVendor},
+		{"subsystem_device", &ids.subDevice},
+	} {
+		if *id.value, err = read(id.name); err != nil {
+			return pciIDs{}, err
+		}
+	}
+	return ids, nil
+}
+
+func normalizeHexID(id string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(id)), "0x")
+}
+
+// firmwareTargetVersion returns EETrack ID (e.g. 0x8000ad7b) of the NVM image which the NVM Update package
+// placed in fwPath provides for the device
+func firmwareTargetVersion(fwPath, pciAddr string) (string, error) {
+	ids, err := readPCIIDs(pciAddr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PCI IDs of device %v: %v", pciAddr, err)
+	}
+
+	f, err := utils.OpenNoLinks(nvmupdate64eCfgPath(fwPath))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return eetrackFromNvmupdateCfg(f, ids)
+}
+
+// eetrackFromNvmupdateCfg finds EEPID of device section matching ids in nvmupdate.cfg
+func eetrackFromNvmupdateCfg(cfg io.Reader, ids pciIDs) (string, error) {
+	matches := func(expected, actual string) bool {
+		expected = normalizeHexID(expected)
+		return expected == "" || expected == "*" || expected == actual
+	}
+
+	var section map[string]string
+	scanner := bufio.NewScanner(cfg)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "BEGIN DEVICE":
+			section = map[string]string{}
+		case line == "END DEVICE":
+			if section != nil && section["VENDOR"] != "" && section["EEPID"] != "" &&
+				matches(section["VENDOR"], ids.vendor) && matches(section["DEVICE"], ids.device) &&
+				matches(section["SUBVENDOR"], ids.subVendor) && matches(section["SUBDEVICE"], ids.subDevice) {
+				return "0x" + normalizeHexID(section["EEPID"]), nil
+			}
+			section = nil
+		case section != nil:
+			if key, value, found := strings.Cut(line, ":"); found {
+				section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no NVM image for device %v:%v (subsystem %v:%v) found in package",
+		ids.vendor, ids.device, ids.subVendor, ids.subDevice)
+}
+
+// observedEETrack returns EETrack ID from firmware version reported by ethtool, e.g. "3.00 0x80008271 1.2992.0"
+func observedEETrack(firmwareVersion string) string {
+	for _, field := range strings.Fields(firmwareVersion) {
+		if strings.HasPrefix(strings.ToLower(field), "0x") {
+			return strings.ToLower(field)
+		}
+	}
+	return ""
+}
+
+// ddpTargetVersion returns version of the DDP package (e.g. 1.3.30.0) stored in its metadata segment
+func ddpTargetVersion(ddpPath string) (string, error) {
+	f, err := utils.OpenNoLinks(ddpPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return ddpVersionFromPackage(f)
+}
+
+// defaultDDPVersion returns version of the default DDP package of the OS, i.e. ice.pkg found first in ddpPaths,
+// which are expected in the order searched by the kernel firmware loader. ice.pkg is usually a symlink to
+// the versioned package, so it is resolved before the package is opened
+func defaultDDPVersion(ddpPaths ...string) (string, error) {
+	for _, ddpPath := range ddpPaths {
+		pkgPath, err := filepath.EvalSymlinks(filepath.Join(ddpPath, "ice.pkg"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		return ddpTargetVersion(pkgPath)
+	}
+	return "", fmt.Errorf("default DDP package ice.pkg not found in %v", ddpPaths)
+}
+
+func ddpVersionFromPackage(pkg io.ReaderAt) (string, error) {
+	header := make([]byte, ddpPkgHeaderSize)
+	if _, err := pkg.ReadAt(header, 0); err != nil {
+		return "", fmt.Errorf("failed to read DDP package header: %v", err)
+	}
+	segCount := binary.LittleEndian.Uint32(header[4:8])
+	if segCount == 0 || segCount > ddpMaxSegments {
+		return "", fmt.Errorf("invalid DDP package segment count %v", segCount)
+	}
+
+	offsets := make([]byte, 4*segCount)
+	if _, err := pkg.ReadAt(offsets, ddpPkgHeaderSize); err != nil {
+		return "", fmt.Errorf("failed to read DDP package segment offsets: %v", err)
+	}
+
+	for i := uint32(0); i < segCount; i++ {
+		offset := int64(binary.LittleEndian.Uint32(offsets[4*i:]))
+		// generic segment header followed by package version
+		seg := make([]byte, ddpSegmentHeaderSize+4)
+		if _, err := pkg.ReadAt(seg, offset); err != nil {
+			return "", fmt.Errorf("failed to read DDP package segment: %v", err)
+		}
+		if binary.LittleEndian.Uint32(seg[0:4]) != ddpSegmentTypeMetadata {
+			continue
+		}
+		v := seg[ddpSegmentHeaderSize:]
+		return fmt.Sprintf("%d.%d.%d.%d", v[0], v[1], v[2], v[3]), nil
+	}
+	return "", errors.New("metadata segment not found in DDP package")
+}
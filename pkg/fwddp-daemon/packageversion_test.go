@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testNvmupdateCfg = `CURRENT FAMILY: 1.0.0
+CONFIG VERSION: 1.20.0
+
+;Intel(R) Ethernet Network Adapter E810-C-Q2
+BEGIN DEVICE
+DEVICENAME: Intel(R) Ethernet Network Adapter E810-C-Q2
+VENDOR: 8086
+DEVICE: 1592
+SUBVENDOR: 8086
+SUBDEVICE: 0002
+NVM IMAGE: E810_CQDA2_O_SEC_FW_1p6p1p9_NVM_3p00_PLDMoMCTP_0.11_8000AD7B.bin
+EEPID: 8000AD7B
+REPLACES: 80008271
+RESET TYPE: REBOOT
+END DEVICE
+
+;Intel(R) Ethernet Network Adapter E810-XXV-4
+BEGIN DEVICE
+DEVICENAME: Intel(R) Ethernet Network Adapter E810-XXV-4
+VENDOR: 8086
+DEVICE: 159B
+SUBVENDOR: 8086
+SUBDEVICE: 0003
+NVM IMAGE: E810_XXVDA4_O_SEC_FW_1p6p1p9_NVM_3p00_PLDMoMCTP_0.11_8000AD7C.bin
+EEPID: 8000AD7C
+END DEVICE
+`
+
+// testDDPPackage builds minimal ICE DDP package with ICE segment followed by metadata segment
+func testDDPPackage(version [4]byte) []byte {
+	buf := new(bytes.Buffer)
+	write := func(v interface{}) { Expect(binary.Write(buf, binary.LittleEndian, v)).To(Succeed()) }
+
+	segment := func(segType uint32, payload []byte) []byte {
+		seg := new(bytes.Buffer)
+		Expect(binary.Write(seg, binary.LittleEndian, segType)).To(Succeed())
+		seg.Write([]byte{1, 0, 0, 0})
+		Expect(binary.Write(seg, binary.LittleEndian, uint32(ddpSegmentHeaderSize+len(payload)))).To(Succeed())
+		seg.Write(make([]byte, 32))
+		seg.Write(payload)
+		return seg.Bytes()
+	}
+	iceSeg := segment(0x10, make([]byte, 16))
+	metadataSeg := segment(ddpSegmentTypeMetadata, append(version[:], make([]byte, 36)...))
+
+	write([4]byte{1, 0, 0, 0})
+	write(uint32(2))
+	write(uint32(16))
+	write(uint32(16 + len(iceSeg)))
+	buf.Write(iceSeg)
+	buf.Write(metadataSeg)
+	return buf.Bytes()
+}
+
+var _ = Describe("PackageVersion", func() {
+	var _ = It("will find EETrack ID of matching device in nvmupdate.cfg", func() {
+		eetrack, err := eetrackFromNvmupdateCfg(strings.NewReader(testNvmupdateCfg),
+			pciIDs{vendor: "8086", device: "159b", subVendor: "8086", subDevice: "0003"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(eetrack).To(Equal("0x8000ad7c"))
+	})
+
+	var _ = It("will return error if device is not present in nvmupdate.cfg", func() {
+		_, err := eetrackFromNvmupdateCfg(strings.NewReader(testNvmupdateCfg),
+			pciIDs{vendor: "8086", device: "1593", subVendor: "8086", subDevice: "0002"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	var _ = It("will read target firmware version using PCI IDs from sysfs", func() {
+		tmpDir, err := os.MkdirTemp("", "packageversion")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+
+		pciDevicesPath = filepath.Join(tmpDir, "devices")
+		defer func() { pciDevicesPath = "/sys/bus/pci/devices" }()
+
+		devicePath := filepath.Join(pciDevicesPath, "0000:00:00.1")
+		Expect(os.MkdirAll(devicePath, 0755)).To(Succeed())
+		for name, value := range map[string]string{
+			"vendor": "0x8086\n", "device": "0x1592\n", "subsystem_vendor": "0x8086\n", "subsystem_device": "0x0002\n",
+		} {
+			Expect(os.WriteFile(filepath.Join(devicePath, name), []byte(value), 0644)).To(Succeed())
+		}
+
+		fwPath := filepath.Join(tmpDir, "E810")
+		Expect(os.MkdirAll(fwPath, 0755)).To(Succeed())
+		Expect(os.WriteFile(nvmupdate64eCfgPath(fwPath), []byte(testNvmupdateCfg), 0644)).To(Succeed())
+
+		version, err := firmwareTargetVersion(fwPath, "0000:00:00.1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal("0x8000ad7b"))
+	})
+
+	var _ = It("will extract EETrack ID from ethtool firmware version", func() {
+		Expect(observedEETrack("3.00 0x80008271 1.2992.0")).To(Equal("0x80008271"))
+		Expect(observedEETrack("TestFWVersion")).To(BeEmpty())
+	})
+
+	var _ = It("will read DDP package version from metadata segment", func() {
+		version, err := ddpVersionFromPackage(bytes.NewReader(testDDPPackage([4]byte{1, 3, 30, 0})))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal("1.3.30.0"))
+	})
+
+	var _ = It("will return error for invalid DDP package", func() {
+		_, err := ddpVersionFromPackage(bytes.NewReader([]byte("not a package")))
+		Expect(err).To(HaveOccurred())
+	})
+
+	var _ = It("will read version of default DDP package found first through ice.pkg symlink", func() {
+		tmpDir := GinkgoT().TempDir()
+		updatesPath, intelPath := filepath.Join(tmpDir, "updates"), filepath.Join(tmpDir, "intel")
+		Expect(os.Mkdir(updatesPath, 0755)).To(Succeed())
+		Expect(os.Mkdir(intelPath, 0755)).To(Succeed())
+
+		_, err := defaultDDPVersion(updatesPath, intelPath)
+		Expect(err).To(HaveOccurred())
+
+		pkgPath := filepath.Join(intelPath, "ice-1.3.30.0.pkg")
+		Expect(os.WriteFile(pkgPath, testDDPPackage([4]byte{1, 3, 30, 0}), 0644)).To(Succeed())
+		Expect(os.Symlink(pkgPath, filepath.Join(intelPath, "ice.pkg"))).To(Succeed())
+		Expect(defaultDDPVersion(updatesPath, intelPath)).To(Equal("1.3.30.0"))
+
+		Expect(os.WriteFile(filepath.Join(updatesPath, "ice.pkg"), testDDPPackage([4]byte{1, 3, 35, 0}), 0644)).To(Succeed())
+		Expect(defaultDDPVersion(updatesPath, intelPath)).To(Equal("1.3.35.0"))
+	})
+})
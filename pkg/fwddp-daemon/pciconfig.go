@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+)
+
+const (
+	// PCI Express extended capabilities follow the legacy configuration space, see PCIe Base Specification 7.6
+	pciExtCapStart        = 0x100
+	pciExtConfigSpaceSize = 4096
+	pciExtCapHeaderSize   = 4
+	pciExtCapIDDSN        = 0x0003
+	pciExtCapDSNSize      = 12
+)
+
+var errNoDeviceSerialNumber = errors.New("Device Serial Number capability not found")
+
+// readDeviceSerialNumber returns PCIe Device Serial Number of the device
+var readDeviceSerialNumber = readDSNFromConfigSpace
+
+// readDSNFromConfigSpace walks extended capabilities in the configuration space of the device exposed in sysfs.
+// Extended configuration space is readable only with CAP_SYS_ADMIN
+func readDSNFromConfigSpace(pciAddr string) (uint64, error) {
+	f, err := utils.OpenNoLinks(filepath.Join(pciDevicesPath, pciAddr, "config"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	config, err := io.ReadAll(io.LimitReader(f, pciExtConfigSpaceSize))
+	if err != nil {
+		return 0, err
+	}
+	return findDSN(config)
+}
+
+// findDSN returns Device Serial Number from the extended capability list of the configuration space
+func findDSN(config []byte) (uint64, error) {
+	offset := pciExtCapStart
+	// each capability takes at least its header, more iterations mean a loop in the list
+	for i := 0; i < (pciExtConfigSpaceSize-pciExtCapStart)/pciExtCapHeaderSize; i++ {
+		if offset < pciExtCapStart || offset+pciExtCapHeaderSize > len(config) {
+			break
+		}
+		header := binary.LittleEndian.Uint32(config[offset:])
+		// no extended capabilities, or configuration space not readable
+		if header == 0 || header == 0xffffffff {
+			break
+		}
+		if header&0xffff == pciExtCapIDDSN {
+			if offset+pciExtCapDSNSize > len(config) {
+				break
+			}
+			low := binary.LittleEndian.Uint32(config[offset+4:])
+			high := binary.LittleEndian.Uint32(config[offset+8:])
+			return uint64(high)<<32 | uint64(low), nil
+		}
+		next := int(header>>20) &^ 0x3
+		if next == 0 {
+			break
+		}
+		offset = next
+	}
+	return 0, errNoDeviceSerialNumber
+}
+
+// formatDSN formats Device Serial Number as lspci and devlink do, e.g. b4-96-91-ff-ff-af-6d-68
+func formatDSN(dsn uint64) string {
+	hex := fmt.Sprintf("%016x", dsn)
+	parts := make([]string, 0, 8)
+	for i := 0; i < len(hex); i += 2 {
+		parts = append(parts, hex[i:i+2])
+	}
+	return strings.Join(parts, "-")
+}
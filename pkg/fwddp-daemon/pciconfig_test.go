@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// testConfigSpace returns extended configuration space with AER capability followed by DSN capability
+func testConfigSpace(dsn uint64) []byte {
+	config := make([]byte, pciExtConfigSpaceSize)
+	binary.LittleEndian.PutUint32(config[0x100:], 0x0001|1<<16|0x148<<20)
+	binary.LittleEndian.PutUint32(config[0x148:], pciExtCapIDDSN|1<<16)
+	binary.LittleEndian.PutUint32(config[0x14c:], uint32(dsn))
+	binary.LittleEndian.PutUint32(config[0x150:], uint32(dsn>>32))
+	return config
+}
+
+var _ = Describe("PCI configuration space", func() {
+	var _ = It("will find Device Serial Number in extended capabilities", func() {
+		dsn, err := findDSN(testConfigSpace(0xb49691ffffaf6d68))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dsn).To(Equal(uint64(0xb49691ffffaf6d68)))
+		Expect(formatDSN(dsn)).To(Equal("b4-96-91-ff-ff-af-6d-68"))
+	})
+
+	var _ = It("will return error if capability is missing or config space is not readable", func() {
+		config := testConfigSpace(1)
+		// AER is the last capability
+		binary.LittleEndian.PutUint32(config[0x100:], 0x0001|1<<16)
+		_, err := findDSN(config)
+		Expect(err).To(MatchError(errNoDeviceSerialNumber))
+
+		// only legacy configuration space is readable without CAP_SYS_ADMIN
+		_, err = findDSN(testConfigSpace(1)[:256])
+		Expect(err).To(MatchError(errNoDeviceSerialNumber))
+	})
+
+	var _ = It("will stop on capability list loop", func() {
+		config := testConfigSpace(1)
+		binary.LittleEndian.PutUint32(config[0x100:], 0x0001|1<<16|0x100<<20)
+		_, err := findDSN(config)
+		Expect(err).To(MatchError(errNoDeviceSerialNumber))
+	})
+
+	var _ = It("will read Device Serial Number of the device from sysfs", func() {
+		origDevicesPath := pciDevicesPath
+		defer func() { pciDevicesPath = origDevicesPath }()
+		pciDevicesPath = GinkgoT().TempDir()
+
+		Expect(os.Mkdir(filepath.Join(pciDevicesPath, "0000:18:00.0"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(pciDevicesPath, "0000:18:00.0", "config"),
+			testConfigSpace(0xb49691ffffaf6d68), 0600)).To(Succeed())
+
+		dsn, err := readDSNFromConfigSpace("0000:18:00.0")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dsn).To(Equal(uint64(0xb49691ffffaf6d68)))
+
+		_, err = readDSNFromConfigSpace("0000:18:00.1")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// portConfigurator reads and changes ethtool settings of network interfaces
+type portConfigurator interface {
+	// observe returns current values of settings which are requested in desired
+	observe(ifName string, desired ethernetv1.PortSettings) (ethernetv1.PortSettings, error)
+	// apply changes settings of the interface which differ from ones requested in desired
+	apply(ifName string, desired ethernetv1.PortSettings) error
+	close() error
+}
+
+// newPortConfigurator opens configurator of port settings, ethtool ioctl by default
+var newPortConfigurator = dialEthtool
+
+var errNoNetworkInterface = errors.New("device has no network interface")
+
+// syncPortSettings observes port settings of network interfaces of configured devices and, if apply is true,
+// re-applies them if they differ from requested ones, e.g. after driver reload or node reboot reset them. Settings
+// of devices configured with dry run are only observed. Settings which still differ are reported in the status,
+// together with inventory inv unless it is nil
+func (r *NodeConfigReconciler) syncPortSettings(nc *ethernetv1.EthernetNodeConfig, inv []ethernetv1.Device,
+	apply bool) error {
+	log := r.log.WithName("syncPortSettings")
+
+	var statuses []ethernetv1.PortSettingsStatus
+	var conf portConfigurator
+	for _, config := range nc.Spec.Config {
+		desired := config.DeviceConfig.PortSettings
+		if desired == nil {
+			continue
+		}
+		if conf == nil {
+			var err error
+			if conf, err = newPortConfigurator(); err != nil {
+				return fmt.Errorf("failed to open ethtool: %v", err)
+			}
+			defer conf.close()
+		}
+
+		ifaces, err := portInterfaces(config.PCIAddress)
+		if err == nil && len(ifaces) == 0 {
+			err = errNoNetworkInterface
+		}
+		if err != nil {
+			statuses = append(statuses, ethernetv1.PortSettingsStatus{PCIAddress: config.PCIAddress, Error: err.Error()})
+			continue
+		}
+		deviceStatus := r.deviceStatus(nc, config.PCIAddress)
+		for _, ifName := range ifaces {
+			status := syncInterfacePortSettings(conf, ifName, *desired, apply && !config.DryRun, deviceStatus, log)
+			status.PCIAddress = config.PCIAddress
+			statuses = append(statuses, status)
+		}
+	}
+
+	return r.modifyStatusWithInventory(nc, inv, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		// interfaces which didn't drift keep the time settings were last applied to them
+		for i := range statuses {
+			for _, previous := range nodeStatus.PortSettings {
+				if statuses[i].LastApplied == nil && previous.PCIAddress == statuses[i].PCIAddress &&
+					previous.Interface == statuses[i].Interface {
+					statuses[i].LastApplied = previous.LastApplied
+				}
+			}
+		}
+		nodeStatus.PortSettings = statuses
+	})
+}
+
+// syncPortSettingsAfterUpdate re-applies port settings once devices are updated, as driver reload and node reboot
+// reset them, and drops status of port settings which are no longer configured
+func (r *NodeConfigReconciler) syncPortSettingsAfterUpdate(nc *ethernetv1.EthernetNodeConfig) {
+	if err := r.syncPortSettings(nc, nil, true); err != nil {
+		r.log.Error(err, "failed to sync port settings")
+	}
+}
+
+// syncInterfacePortSettings applies desired settings to the interface if they differ from observed ones
+// and apply is true. Returned status contains settings which still differ
+func syncInterfacePortSettings(conf portConfigurator, ifName string, desired ethernetv1.PortSettings, apply bool,
+	deviceStatus *deviceStatusReporter, log logr.Logger) ethernetv1.PortSettingsStatus {
+	log = log.WithValues("interface", ifName)
+	status := ethernetv1.PortSettingsStatus{Interface: ifName}
+
+	observed, err := conf.observe(ifName, desired)
+	if err != nil {
+		log.Error(err, "Failed to observe port settings")
+		status.Error = err.Error()
+		return status
+	}
+	drift := portSettingsDrift(desired, observed)
+	if len(drift) == 0 || !apply {
+		status.Drift = drift
+		return status
+	}
+
+	log.V(2).Info("Applying port settings", "drift", drift)
+	if err := conf.apply(ifName, desired); err != nil {
+		log.Error(err, "Failed to apply port settings")
+		deviceStatus.event(corev1.EventTypeWarning, EventPortSettingsFailed, "Applying port settings of %v failed: %v",
+			ifName, err)
+		status.Drift, status.Error = drift, err.Error()
+		return status
+	}
+	status.LastApplied = nowPtr()
+	deviceStatus.event(corev1.EventTypeNormal, EventPortSettingsApplied, "Port settings of %v applied: %v",
+		ifName, strings.Join(drift, "; "))
+
+	// drivers may adjust requested values, e.g. round ring sizes, which is reported as drift
+	if observed, err = conf.observe(ifName, desired); err != nil {
+		log.Error(err, "Failed to observe applied port settings")
+		status.Error = err.Error()
+		return status
+	}
+	status.Drift = portSettingsDrift(desired, observed)
+	return status
+}
+
+// portSettingsDrift describes settings requested in desired which differ from observed ones
+func portSettingsDrift(desired, observed ethernetv1.PortSettings) []string {
+	var drift []string
+	compare := func(name string, requested, current interface{}) {
+		if fmt.Sprint(requested) != fmt.Sprint(current) {
+			drift = append(drift, fmt.Sprintf("%v: requested %v, observed %v", name, requested, current))
+		}
+	}
+	compareInt := func(name string, requested, current *int) {
+		if requested == nil {
+			return
+		}
+		if current == nil {
+			compare(name, *requested, "unknown")
+			return
+		}
+		compare(name, *requested, *current)
+	}
+	compareBool := func(name string, requested, current *bool) {
+		if requested == nil {
+			return
+		}
+		if current == nil {
+			compare(name, *requested, "unknown")
+			return
+		}
+		compare(name, *requested, *current)
+	}
+
+	compareInt("rxRingSize", desired.RxRingSize, observed.RxRingSize)
+	compareInt("txRingSize", desired.TxRingSize, observed.TxRingSize)
+	compareInt("combinedChannels", desired.CombinedChannels, observed.CombinedChannels)
+	if desired.Coalescing != nil {
+		current := observed.Coalescing
+		if current == nil {
+			current = &ethernetv1.CoalescingSettings{}
+		}
+		compareBool("coalescing.adaptiveRx", desired.Coalescing.AdaptiveRx, current.AdaptiveRx)
+		compareBool("coalescing.adaptiveTx", desired.Coalescing.AdaptiveTx, current.AdaptiveTx)
+		compareInt("coalescing.rxUsecs", desired.Coalescing.RxUsecs, current.RxUsecs)
+		compareInt("coalescing.txUsecs", desired.Coalescing.TxUsecs, current.TxUsecs)
+	}
+	if desired.FECMode != "" {
+		compare("fecMode", desired.FECMode, observed.FECMode)
+	}
+	compareBool("autoneg", desired.Autoneg, observed.Autoneg)
+	// link speed is unknown while the link is down, it is compared once the link comes up
+	if observed.Speed != nil {
+		compareInt("speed", desired.Speed, observed.Speed)
+	}
+	if desired.Pause != nil {
+		current := observed.Pause
+		if current == nil {
+			current = &ethernetv1.PauseSettings{}
+		}
+		compareBool("pause.autoneg", desired.Pause.Autoneg, current.Autoneg)
+		compareBool("pause.rx", desired.Pause.Rx, current.Rx)
+		compareBool("pause.tx", desired.Pause.Tx, current.Tx)
+	}
+
+	flags := make([]string, 0, len(desired.PrivateFlags))
+	for flag := range desired.PrivateFlags {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	for _, flag := range flags {
+		current, ok := observed.PrivateFlags[flag]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("privateFlags.%v: not supported by the driver", flag))
+			continue
+		}
+		compare("privateFlags."+flag, desired.PrivateFlags[flag], current)
+	}
+	return drift
+}
+
+// portInterfaces returns network interfaces of the device, except port representors of its VFs in switchdev mode
+func portInterfaces(pciAddr string) ([]string, error) {
+	netPath := filepath.Join(pciDevicesPath, pciAddr, "net")
+	entries, err := os.ReadDir(netPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ifaces []string
+	for _, e := range entries {
+		// representors are named by the port they represent, e.g. pf0vf1
+		if strings.Contains(readSysfsString(filepath.Join(netPath, e.Name(), "phys_port_name")), "vf") {
+			continue
+		}
+		ifaces = append(ifaces, e.Name())
+	}
+	return ifaces, nil
+}
@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// fakePortConfigurator keeps settings of interfaces in memory, apply overwrites requested ones
+type fakePortConfigurator struct {
+	settings map[string]*ethernetv1.PortSettings
+	applied  []string
+	applyErr error
+}
+
+func (f *fakePortConfigurator) observe(ifName string, _ ethernetv1.PortSettings) (ethernetv1.PortSettings, error) {
+	s, ok := f.settings[ifName]
+	if !ok {
+		return ethernetv1.PortSettings{}, unix.ENODEV
+	}
+	return *s.DeepCopy(), nil
+}
+
+func (f *fakePortConfigurator) apply(ifName string, desired ethernetv1.PortSettings) error {
+	f.applied = append(f.applied, ifName)
+	if f.applyErr != nil {
+		return f.applyErr
+	}
+	s := f.settings[ifName]
+	s.RxRingSize, s.TxRingSize, s.FECMode = desired.RxRingSize, desired.TxRingSize, desired.FECMode
+	for flag, enabled := range desired.PrivateFlags {
+		s.PrivateFlags[flag] = enabled
+	}
+	return nil
+}
+
+func (f *fakePortConfigurator) close() error {
+	return nil
+}
+
+func intValue(v int) *int {
+	return &v
+}
+
+func boolValue(v bool) *bool {
+	return &v
+}
+
+var _ = Describe("port settings", func() {
+	log := ctrl.Log.WithName("FirmwareDaemon-test")
+
+	desired := ethernetv1.PortSettings{
+		RxRingSize:   intValue(4096),
+		TxRingSize:   intValue(4096),
+		FECMode:      FECModeRS,
+		PrivateFlags: map[string]bool{"fw-lldp-agent": false},
+	}
+
+	var conf *fakePortConfigurator
+	BeforeEach(func() {
+		conf = &fakePortConfigurator{settings: map[string]*ethernetv1.PortSettings{
+			"ens785f0": {
+				RxRingSize:   intValue(2048),
+				TxRingSize:   intValue(4096),
+				FECMode:      FECModeAuto,
+				PrivateFlags: map[string]bool{"fw-lldp-agent": true, "link-down-on-close": false},
+			},
+		}}
+	})
+
+	var _ = It("will describe settings which differ from requested ones", func() {
+		drift := portSettingsDrift(desired, *conf.settings["ens785f0"])
+		Expect(drift).To(Equal([]string{
+			"rxRingSize: requested 4096, observed 2048",
+			"fecMode: requested RS, observed Auto",
+			"privateFlags.fw-lldp-agent: requested false, observed true",
+		}))
+
+		drift = portSettingsDrift(ethernetv1.PortSettings{
+			Coalescing:   &ethernetv1.CoalescingSettings{AdaptiveRx: boolValue(false), RxUsecs: intValue(50)},
+			Autoneg:      boolValue(false),
+			Speed:        intValue(25000),
+			Pause:        &ethernetv1.PauseSettings{Rx: boolValue(true)},
+			PrivateFlags: map[string]bool{"legacy-rx": true},
+		}, ethernetv1.PortSettings{
+			Coalescing: &ethernetv1.CoalescingSettings{AdaptiveRx: boolValue(false), RxUsecs: intValue(50)},
+			Autoneg:    boolValue(false),
+			Speed:      intValue(10000),
+		})
+		Expect(drift).To(Equal([]string{
+			"speed: requested 25000, observed 10000",
+			"pause.rx: requested true, observed unknown",
+			"privateFlags.legacy-rx: not supported by the driver",
+		}))
+
+		Expect(portSettingsDrift(ethernetv1.PortSettings{}, *conf.settings["ens785f0"])).To(BeEmpty())
+
+		// speed of link which is down is unknown
+		Expect(portSettingsDrift(ethernetv1.PortSettings{Autoneg: boolValue(false), Speed: intValue(25000)},
+			ethernetv1.PortSettings{Autoneg: boolValue(false)})).To(BeEmpty())
+	})
+
+	var _ = It("will apply settings which drifted and report remaining drift", func() {
+		status := syncInterfacePortSettings(conf, "ens785f0", desired, true, nil, log)
+		Expect(conf.applied).To(Equal([]string{"ens785f0"}))
+		Expect(status.Interface).To(Equal("ens785f0"))
+		Expect(status.Drift).To(BeEmpty())
+		Expect(status.Error).To(BeEmpty())
+		Expect(status.LastApplied).ToNot(BeNil())
+		Expect(*conf.settings["ens785f0"].RxRingSize).To(Equal(4096))
+
+		// settings in sync are not applied again
+		status = syncInterfacePortSettings(conf, "ens785f0", desired, true, nil, log)
+		Expect(conf.applied).To(HaveLen(1))
+		Expect(status.LastApplied).To(BeNil())
+	})
+
+	var _ = It("will only observe settings if they are not applied", func() {
+		status := syncInterfacePortSettings(conf, "ens785f0", desired, false, nil, log)
+		Expect(conf.applied).To(BeEmpty())
+		Expect(status.Drift).To(HaveLen(3))
+		Expect(status.LastApplied).To(BeNil())
+	})
+
+	var _ = It("will report failure to observe or apply settings", func() {
+		conf.applyErr = unix.EINVAL
+		status := syncInterfacePortSettings(conf, "ens785f0", desired, true, nil, log)
+		Expect(status.Error).To(Equal(unix.EINVAL.Error()))
+		Expect(status.Drift).To(HaveLen(3))
+		Expect(status.LastApplied).To(BeNil())
+
+		status = syncInterfacePortSettings(conf, "ens785f1", desired, true, nil, log)
+		Expect(status.Error).To(Equal(unix.ENODEV.Error()))
+		Expect(conf.applied).To(Equal([]string{"ens785f0"}))
+	})
+
+	var _ = Context("portInterfaces", func() {
+		origDevicesPath := pciDevicesPath
+
+		BeforeEach(func() {
+			pciDevicesPath = GinkgoT().TempDir()
+		})
+
+		AfterEach(func() {
+			pciDevicesPath = origDevicesPath
+		})
+
+		var _ = It("will return interfaces of the device except VF representors", func() {
+			netPath := filepath.Join(pciDevicesPath, "0000:18:00.0", "net")
+			for ifName, portName := range map[string]string{"ens785f0": "p0", "eth0": "pf0vf0", "eth1": "pf0vf1"} {
+				Expect(os.MkdirAll(filepath.Join(netPath, ifName), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(netPath, ifName, "phys_port_name"), []byte(portName+"\n"),
+					0644)).To(Succeed())
+			}
+
+			Expect(portInterfaces("0000:18:00.0")).To(Equal([]string{"ens785f0"}))
+			Expect(portInterfaces("0000:18:00.1")).To(BeEmpty())
+		})
+	})
+
+	var _ = Context("ethtool", func() {
+		var _ = It("will lay out commands as the kernel expects", func() {
+			Expect(unsafe.Sizeof(ethtoolRingParam{})).To(Equal(uintptr(36)))
+			Expect(unsafe.Sizeof(ethtoolChannels{})).To(Equal(uintptr(36)))
+			Expect(unsafe.Sizeof(ethtoolCoalesce{})).To(Equal(uintptr(92)))
+			Expect(unsafe.Offsetof(ethtoolLinkSettings{}.linkModeMasks)).To(Equal(uintptr(48)))
+			Expect(unsafe.Sizeof(ifreqData{})).To(Equal(uintptr(40)))
+		})
+
+		var _ = It("will change requested private flags only", func() {
+			names := []string{"link-down-on-close", "fw-lldp-agent", "legacy-rx"}
+			flags, err := privateFlagsValue(names, 0x5, map[string]bool{"fw-lldp-agent": true, "legacy-rx": false})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(flags).To(Equal(uint32(0x3)))
+
+			_, err = privateFlagsValue(names, 0, map[string]bool{"vf-true-promisc-support": true})
+			Expect(err).To(MatchError(ContainSubstring("not supported")))
+		})
+
+		var _ = It("will parse names of private flags", func() {
+			data := make([]byte, 3*ethtoolStringLen)
+			copy(data, "link-down-on-close")
+			copy(data[ethtoolStringLen:], "fw-lldp-agent")
+			Expect(parseEthtoolStrings(data, 2)).To(Equal([]string{"link-down-on-close", "fw-lldp-agent"}))
+			Expect(parseEthtoolStrings(data[:ethtoolStringLen], 2)).To(Equal([]string{"link-down-on-close"}))
+		})
+
+		var _ = It("will report unknown link speed", func() {
+			Expect(speedPtr(ethtoolSpeedUnknown)).To(BeNil())
+			Expect(speedPtr(25000)).To(Equal(intValue(25000)))
+		})
+
+		var _ = It("will apply groups of settings independently", func() {
+			var applied []string
+			group := func(name string, err error) func() error {
+				return func() error {
+					applied = append(applied, name)
+					return err
+				}
+			}
+			err := applyPortSettingsGroups([]func() error{
+				group("ring sizes", errors.New("failed to set ring sizes: invalid argument")),
+				group("FEC mode", nil),
+				group("link settings", errors.New("link speed can be set only with autoneg disabled")),
+				group("private flags", nil),
+			})
+			Expect(applied).To(Equal([]string{"ring sizes", "FEC mode", "link settings", "private flags"}))
+			Expect(err).To(MatchError("failed to set ring sizes: invalid argument; " +
+				"link speed can be set only with autoneg disabled"))
+
+			Expect(applyPortSettingsGroups([]func() error{group("pause frames", nil)})).To(Succeed())
+		})
+
+		var _ = It("will map FEC modes", func() {
+			Expect(fecModeName(unix.ETHTOOL_FEC_RS)).To(Equal(FECModeRS))
+			Expect(fecModeName(unix.ETHTOOL_FEC_AUTO | unix.ETHTOOL_FEC_RS)).To(Equal(FECModeAuto))
+			Expect(fecModeName(unix.ETHTOOL_FEC_LLRS)).To(Equal("0x20"))
+
+			bit, err := fecModeBit(FECModeBaseR)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bit).To(Equal(uint32(unix.ETHTOOL_FEC_BASER)))
+			_, err = fecModeBit("LLRS")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
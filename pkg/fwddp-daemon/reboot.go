@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	RebootStrategyImmediate = "Immediate"
+	RebootStrategyDeferred  = "Deferred"
+	RebootStrategyExternal  = "External"
+
+	// RebootApprovedAnnotation set on EthernetNodeConfig approves reboot deferred by the daemon
+	RebootApprovedAnnotation = "ethernet.intel.com/reboot-approved"
+)
+
+var bootIDPath = "/proc/sys/kernel/random/boot_id"
+
+// rebootStrategy returns strategy of the node reboot required by updates of queued devices. Strategy which
+// leaves the reboot to the administrator or external orchestrator takes precedence
+func rebootStrategy(nodeConfig *ethernetv1.EthernetNodeConfig, updateQueue deviceUpdateQueue) string {
+	strategy := RebootStrategyImmediate
+	for _, config := range nodeConfig.Spec.Config {
+		if _, queued := updateQueue[config.PCIAddress]; !queued {
+			continue
+		}
+		switch config.RebootStrategy {
+		case RebootStrategyExternal:
+			return RebootStrategyExternal
+		case RebootStrategyDeferred:
+			strategy = RebootStrategyDeferred
+		}
+	}
+	return strategy
+}
+
+func readBootID() (string, error) {
+	bootID, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bootID)), nil
+}
+
+// requestReboot completes staged update according to the reboot strategy. Node is rebooted right away
+// with Immediate strategy, otherwise pending reboot is recorded and the daemon waits until the node is rebooted
+func (r *NodeConfigReconciler) requestReboot(nodeConfig *ethernetv1.EthernetNodeConfig, strategy string) error {
+	if strategy == RebootStrategyImmediate {
+		r.recordReboot(nodeConfig, &ethernetv1.RebootStatus{Strategy: strategy, Pending: true, RequiredSince: nowPtr()})
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdatePostUpdateReboot, "Post-update node reboot")
+		r.nodeEvent(nodeConfig, corev1.EventTypeNormal, EventRebootIssued, "Post-update node reboot issued")
+		if err := r.rebootNode(); err != nil {
+			r.finishReboot(nodeConfig)
+			return err
+		}
+		return nil
+	}
+
+	bootID, err := readBootID()
+	if err != nil {
+		return fmt.Errorf("failed to read boot ID: %v", err)
+	}
+	// approval given before the update was staged is not valid for it
+	if err := r.clearRebootApproval(nodeConfig); err != nil {
+		return err
+	}
+
+	r.recordReboot(nodeConfig, &ethernetv1.RebootStatus{
+		Strategy:      strategy,
+		Pending:       true,
+		RequiredSince: nowPtr(),
+		BootID:        bootID,
+	})
+	r.nodeEvent(nodeConfig, corev1.EventTypeNormal, EventRebootPending, "Update staged, %v node reboot required",
+		strings.ToLower(strategy))
+	if strategy == RebootStrategyDeferred {
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateRebootPending,
+			fmt.Sprintf("Update staged, waiting for %v annotation to reboot the node", RebootApprovedAnnotation))
+	} else {
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, UpdateRebootRequired,
+			"Update staged, waiting for the node to be rebooted externally")
+	}
+	return nil
+}
+
+// isRebooted returns true if the node was rebooted since the pending reboot was recorded
+func isRebooted(nodeConfig *ethernetv1.EthernetNodeConfig) (bool, error) {
+	reboot := nodeConfig.Status.Reboot
+	if reboot == nil || reboot.BootID == "" {
+		return false, nil
+	}
+	bootID, err := readBootID()
+	if err != nil {
+		return false, err
+	}
+	return bootID != reboot.BootID, nil
+}
+
+func isRebootApproved(nodeConfig *ethernetv1.EthernetNodeConfig) bool {
+	approval, ok := nodeConfig.GetAnnotations()[RebootApprovedAnnotation]
+	return ok && approval != "false"
+}
+
+func (r *NodeConfigReconciler) clearRebootApproval(nodeConfig *ethernetv1.EthernetNodeConfig) error {
+	if _, ok := nodeConfig.GetAnnotations()[RebootApprovedAnnotation]; !ok {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		nc := &ethernetv1.EthernetNodeConfig{}
+		if err := r.Get(context.Background(), client.ObjectKeyFromObject(nodeConfig), nc); err != nil {
+			return err
+		}
+		annotations := nc.GetAnnotations()
+		delete(annotations, RebootApprovedAnnotation)
+		nc.SetAnnotations(annotations)
+		return r.Update(context.Background(), nc)
+	})
+}
+
+// recordReboot stores state of the reboot completing the update in status
+func (r *NodeConfigReconciler) recordReboot(nc *ethernetv1.EthernetNodeConfig, reboot *ethernetv1.RebootStatus) {
+	err := r.modifyStatus(nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		nodeStatus.Reboot = reboot
+	})
+	if err != nil {
+		r.log.Error(err, "failed to record node reboot")
+	}
+}
+
+// finishReboot marks the pending reboot as completed
+func (r *NodeConfigReconciler) finishReboot(nc *ethernetv1.EthernetNodeConfig) {
+	if nc.Status.Reboot == nil || !nc.Status.Reboot.Pending {
+		return
+	}
+	reboot := nc.Status.Reboot.DeepCopy()
+	reboot.Pending = false
+	r.recordReboot(nc, reboot)
+}
+
+func nowPtr() *metav1.Time {
+	now := metav1.Now()
+	return &now
+}
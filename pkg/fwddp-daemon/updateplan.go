@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+// planDeviceUpdate prepares artifacts of the device configured with dry run and computes what its update
+// would do, without touching the hardware. Failures are reported in the plan
+func (r *NodeConfigReconciler) planDeviceUpdate(config ethernetv1.DeviceNodeConfig, inv []ethernetv1.Device,
+	flashed []ethernetv1.FlashedImage) ethernetv1.DeviceUpdatePlan {
+	log := r.log.WithName("planDeviceUpdate").WithValues("device", config.PCIAddress)
+
+	plan := ethernetv1.DeviceUpdatePlan{PCIAddress: config.PCIAddress}
+	for _, device := range inv {
+		if device.PCIAddress == config.PCIAddress {
+			plan.CurrentFirmwareVersion = device.Firmware.Version
+			plan.CurrentDDPVersion = device.DDP.Version
+			break
+		}
+	}
+
+	artifacts, err := r.prepareArtifacts(config, inv, flashed, nil)
+	if err != nil {
+		log.Error(err, "Failed to prepare artifacts for update plan")
+		plan.Error = err.Error()
+		return plan
+	}
+	plan.TargetFirmwareVersion = artifacts.targetFWVersion
+	plan.TargetDDPVersion = artifacts.targetDDPVersion
+
+	if err := r.verifyCompatibility(config, inv, artifacts); err != nil {
+		plan.Error = err.Error()
+		return plan
+	}
+
+	if artifacts.fwPath != "" {
+		plan.FirmwareUpdate, err = r.fwUpdater.backend(config.DeviceConfig.FWUpdateBackend).isUpdateAvailable(
+			config.PCIAddress, artifacts.fwPath)
+		if err != nil {
+			log.Error(err, "Failed to run firmware inventory, assuming update is available")
+		}
+	}
+	plan.DDPUpdate = artifacts.ddpPath != ""
+	plan.DDPRevert = artifacts.ddpRevert
+	// firmware and DDP updates are completed by the node reboot, unless DDP is loaded by driver reload
+	plan.RebootRequired = plan.FirmwareUpdate || ((plan.DDPUpdate || plan.DDPRevert) && !artifacts.ddpReload)
+	for _, c := range artifacts.devlinkParams {
+		plan.DevlinkParams = append(plan.DevlinkParams, c.DevlinkParam)
+		// permanent values are stored in the NVM and loaded by the device on reboot
+		if c.CMode == DevlinkParamCModePermanent {
+			plan.RebootRequired = true
+		}
+	}
+
+	return plan
+}
+
+// publishUpdatePlan stores plans of devices configured with dry run in EthernetNodeConfig status.
+// Plan is removed if no device is configured with dry run
+func (r *NodeConfigReconciler) publishUpdatePlan(nc *ethernetv1.EthernetNodeConfig, devices []ethernetv1.DeviceUpdatePlan) {
+	log := r.log.WithName("publishUpdatePlan")
+
+	if len(devices) == 0 && nc.Status.UpdatePlan == nil {
+		return
+	}
+
+	err := r.modifyStatus(nc, func(nodeStatus *ethernetv1.EthernetNodeConfigStatus) {
+		if len(devices) == 0 {
+			nodeStatus.UpdatePlan = nil
+			return
+		}
+		plan := &ethernetv1.NodeUpdatePlan{
+			Devices:            devices,
+			Complete:           true,
+			ObservedGeneration: nc.GetGeneration(),
+		}
+		plan.SetRequirements(nc.Spec.DrainSkip)
+		nodeStatus.UpdatePlan = plan
+	})
+	if err != nil {
+		log.Error(err, "failed to publish update plan")
+	}
+}
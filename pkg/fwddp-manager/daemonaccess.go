@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"context"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const daemonServiceAccountName = "fwddp-daemon"
+
+// grantDaemonAccess creates or updates Role with the rule and binds it to the daemon service account
+func (r *EthernetClusterConfigReconciler) grantDaemonAccess(name string, rule rbacv1.PolicyRule) error {
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+		Rules:      []rbacv1.PolicyRule{rule},
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      daemonServiceAccountName,
+			Namespace: NAMESPACE,
+		}},
+	}
+
+	current := &rbacv1.Role{}
+	if err := r.Get(context.TODO(), client.ObjectKeyFromObject(role), current); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		if err := r.Create(context.TODO(), role); err != nil {
+			return err
+		}
+	} else if !equality.Semantic.DeepEqual(current.Rules, role.Rules) {
+		current.Rules = role.Rules
+		if err := r.Update(context.TODO(), current); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Create(context.TODO(), binding); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// revokeDaemonAccess removes Role and RoleBinding created by grantDaemonAccess
+func (r *EthernetClusterConfigReconciler) revokeDaemonAccess(name string) error {
+	meta := metav1.ObjectMeta{Name: name, Namespace: NAMESPACE}
+	for _, obj := range []client.Object{&rbacv1.RoleBinding{ObjectMeta: meta}, &rbacv1.Role{ObjectMeta: meta}} {
+		if err := r.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+const (
+	// ddpRevertFinalizer keeps EthernetClusterConfig with RevertDDPOnDelete until DDP package
+	// it applied is reverted on all selected devices
+	ddpRevertFinalizer = "ethernet.intel.com/ddp-revert"
+
+	// condition of EthernetNodeConfig set by the daemon
+	nodeUpdateCondition = "Updated"
+	nodeUpdateSucceeded = "Succeeded"
+)
+
+// revertOnDelete returns true if DDP package applied by the config is to be reverted once the config is deleted
+func revertOnDelete(cc *ethernetv1.EthernetClusterConfig) bool {
+	return cc.Spec.RevertDDPOnDelete && !cc.Spec.DryRun && cc.Spec.DeviceConfig.DDPURL != ""
+}
+
+// isRevertingDDP returns true if the config is deleted, but still applied to revert DDP package
+func isRevertingDDP(cc *ethernetv1.EthernetClusterConfig) bool {
+	return cc.DeletionTimestamp != nil && controllerutil.ContainsFinalizer(cc, ddpRevertFinalizer) && revertOnDelete(cc)
+}
+
+// revertDeviceConfig returns configuration which reverts DDP package applied with dc
+func revertDeviceConfig(dc ethernetv1.DeviceConfig) ethernetv1.DeviceConfig {
+	return ethernetv1.DeviceConfig{
+		DDPRevert:              true,
+		DDPApplyStrategy:       dc.DDPApplyStrategy,
+		SkipCompatibilityCheck: dc.SkipCompatibilityCheck,
+	}
+}
+
+// ddpReverts maps name of EthernetClusterConfig reverting DDP package to true if the revert is completed
+// on all devices it selects
+type ddpReverts map[string]bool
+
+// collect checks progress of DDP revert on devices matched to deleted configs on the node. Revert is completed
+// once the daemon successfully processed current spec of EthernetNodeConfig, which requests the revert
+func (d ddpReverts) collect(ncc NodeConfigurationCtx) {
+	nodeConfig, deviceConfigContext := ncc()
+
+	for pciAddress, cc := range deviceConfigContext {
+		if !isRevertingDDP(&cc) {
+			continue
+		}
+		done, ok := d[cc.Name]
+		d[cc.Name] = (done || !ok) && isDDPReverted(&nodeConfig, pciAddress)
+	}
+}
+
+func isDDPReverted(nc *ethernetv1.EthernetNodeConfig, pciAddress string) bool {
+	requested := false
+	for _, config := range nc.Spec.Config {
+		if config.PCIAddress == pciAddress && config.DeviceConfig.DDPRevert {
+			requested = true
+			break
+		}
+	}
+
+	condition := meta.FindStatusCondition(nc.Status.Conditions, nodeUpdateCondition)
+	return requested && condition != nil && condition.ObservedGeneration == nc.Generation &&
+		condition.Status == metav1.ConditionTrue && condition.Reason == nodeUpdateSucceeded
+}
+
+// updateDDPRevertFinalizer adds finalizer to config which requests DDP revert on deletion, and removes it
+// once the revert is completed or no longer requested
+func (r *EthernetClusterConfigReconciler) updateDDPRevertFinalizer(cc *ethernetv1.EthernetClusterConfig,
+	reverts ddpReverts) error {
+	log := r.Log.WithName("updateDDPRevertFinalizer").WithValues("name", cc.Name)
+
+	switch {
+	case !controllerutil.ContainsFinalizer(cc, ddpRevertFinalizer):
+		if cc.DeletionTimestamp != nil || !revertOnDelete(cc) {
+			return nil
+		}
+		controllerutil.AddFinalizer(cc, ddpRevertFinalizer)
+	case isRevertingDDP(cc):
+		if done, ok := reverts[cc.Name]; ok && !done {
+			log.V(2).Info("Waiting for DDP revert to complete")
+			return nil
+		}
+		log.V(2).Info("DDP reverted on all selected devices")
+		controllerutil.RemoveFinalizer(cc, ddpRevertFinalizer)
+	case !revertOnDelete(cc):
+		controllerutil.RemoveFinalizer(cc, ddpRevertFinalizer)
+	default:
+		return nil
+	}
+	return r.Update(context.TODO(), cc)
+}
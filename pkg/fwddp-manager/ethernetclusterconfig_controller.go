@@ -13,10 +13,18 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 )
@@ -37,6 +45,7 @@ var NAMESPACE = os.Getenv("ETHERNET_NAMESPACE")
 //+kubebuilder:rbac:groups=ethernet.intel.com,resources=ethernetclusterconfigs/finalizers;ethernetnodeconfigs/finalizers,verbs=update
 //+kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigs,verbs=create;get
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update
 //+kubebuilder:rbac:groups=apps,resources=daemonsets;deployments;deployments/finalizers,verbs=*
 //+kubebuilder:rbac:groups="",resources=namespaces;serviceaccounts;configmaps,verbs=*
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=*
@@ -70,20 +79,59 @@ func (r *EthernetClusterConfigReconciler) Reconcile(_ context.Context, req ctrl.
 		return ctrl.Result{}, err
 	}
 
+	// configs being deleted are applied only to revert DDP package, see ddpRevertFinalizer
+	activeConfigs := make([]ethernetv1.EthernetClusterConfig, 0, len(clusterConfigs.Items))
+	for i := range clusterConfigs.Items {
+		if clusterConfigs.Items[i].DeletionTimestamp == nil || isRevertingDDP(&clusterConfigs.Items[i]) {
+			activeConfigs = append(activeConfigs, clusterConfigs.Items[i])
+		}
+	}
+
+	// daemons need access to the secrets before they get the configs referencing them
+	if err := r.syncSecretAccess(clusterConfigs.Items); err != nil {
+		log.Error(err, "failed to grant daemons access to secrets")
+	}
+
 	clusterConfigurationMatcher := createClusterConfigMatcher(r.getOrInitializeEthernetNodeConfig, log)
+	plans := make(clusterConfigPlans)
+	reverts := make(ddpReverts)
+	windows := newMaintenanceWindows(activeConfigs, log)
+	var fleet fleetMetrics
 	for _, node := range nodes.Items {
-		configurationContext, err := clusterConfigurationMatcher.match(node, clusterConfigs.Items)
+		configurationContext, err := clusterConfigurationMatcher.match(node, activeConfigs)
 		if err != nil {
 			log.Error(err, "failed to match EthernetClusterConfig(s) to a node", "node", node.Name)
 			continue
 		}
-		if err := r.synchronizeNodeConfigSpec(configurationContext, drainSkip); err != nil {
+		plans.collect(configurationContext)
+		reverts.collect(configurationContext)
+		windows.collect(configurationContext)
+		fleet.collect(configurationContext)
+		if err := r.synchronizeNodeConfigSpec(configurationContext, drainSkip, windows); err != nil {
 			log.Error(err, "failed to create/update NodeConfig", "node", node.Name)
 			continue
 		}
 	}
 
-	return ctrl.Result{}, err
+	fleet.publish()
+
+	if err := r.syncOperationLogAccess(nodes.Items); err != nil {
+		log.Error(err, "failed to grant daemons access to operation logs")
+	}
+
+	for i := range clusterConfigs.Items {
+		if err := r.updateClusterConfigPlan(&clusterConfigs.Items[i], plans); err != nil {
+			log.Error(err, "failed to update EthernetClusterConfig status", "name", clusterConfigs.Items[i].Name)
+		}
+		if err := r.updateMaintenanceStatus(&clusterConfigs.Items[i], windows); err != nil {
+			log.Error(err, "failed to update EthernetClusterConfig status", "name", clusterConfigs.Items[i].Name)
+		}
+		if err := r.updateDDPRevertFinalizer(&clusterConfigs.Items[i], reverts); err != nil {
+			log.Error(err, "failed to update EthernetClusterConfig finalizers", "name", clusterConfigs.Items[i].Name)
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: windows.requeueAfter()}, err
 }
 
 type DeviceConfigContext map[string]ethernetv1.EthernetClusterConfig
@@ -182,7 +230,8 @@ func (r *EthernetClusterConfigReconciler) getOrInitializeEthernetNodeConfig(name
 	return nc, nil
 }
 
-func (r *EthernetClusterConfigReconciler) synchronizeNodeConfigSpec(ncc NodeConfigurationCtx, drainSkip bool) error {
+func (r *EthernetClusterConfigReconciler) synchronizeNodeConfigSpec(ncc NodeConfigurationCtx, drainSkip bool,
+	windows *maintenanceWindows) error {
 	copyWithEmptySpec := func(nc ethernetv1.EthernetNodeConfig) *ethernetv1.EthernetNodeConfig {
 		newNC := nc.DeepCopy()
 		newNC.Spec = ethernetv1.EthernetNodeConfigSpec{}
@@ -192,8 +241,25 @@ func (r *EthernetClusterConfigReconciler) synchronizeNodeConfigSpec(ncc NodeConf
 	currentNodeConfig, deviceConfigContext := ncc()
 	newNodeConfig := copyWithEmptySpec(currentNodeConfig)
 	for pciAddress, cc := range deviceConfigContext {
-		dnc := ethernetv1.DeviceNodeConfig{PCIAddress: pciAddress}
+		dnc := ethernetv1.DeviceNodeConfig{PCIAddress: pciAddress, DryRun: cc.Spec.DryRun}
 		dnc.DeviceConfig = cc.Spec.DeviceConfig
+		if isRevertingDDP(&cc) {
+			dnc.DeviceConfig = revertDeviceConfig(cc.Spec.DeviceConfig)
+		}
+		dnc.MaintenanceWindow = cc.Spec.MaintenanceWindow.DeepCopy()
+		dnc.RebootStrategy = cc.Spec.RebootStrategy
+
+		if !windows.isOpen(&cc) {
+			// current configuration of the device is kept until the maintenance window opens
+			current, found := findDeviceNodeConfig(currentNodeConfig.Spec.Config, pciAddress)
+			if !found || !equality.Semantic.DeepEqual(current, dnc) {
+				windows.deferNode(&cc, currentNodeConfig.Name)
+			}
+			if !found {
+				continue
+			}
+			dnc = current
+		}
 		newNodeConfig.Spec.Config = append(newNodeConfig.Spec.Config, dnc)
 		newNodeConfig.Spec.DrainSkip = newNodeConfig.Spec.DrainSkip || drainSkip
 	}
@@ -204,9 +270,60 @@ func (r *EthernetClusterConfigReconciler) synchronizeNodeConfigSpec(ncc NodeConf
 	return nil
 }
 
+func findDeviceNodeConfig(configs []ethernetv1.DeviceNodeConfig, pciAddress string) (ethernetv1.DeviceNodeConfig, bool) {
+	for _, config := range configs {
+		if config.PCIAddress == pciAddress {
+			return config, true
+		}
+	}
+	return ethernetv1.DeviceNodeConfig{}, false
+}
+
+// mapNodeConfigToRequest maps changes of any EthernetNodeConfig to a single request, as Reconcile handles all
+// EthernetClusterConfigs at once. Changes of several nodes are coalesced into one reconcile
+func mapNodeConfigToRequest(_ client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "ethernetnodeconfigs"}}}
+}
+
+// nodeConfigChangedPredicate passes changes of EthernetNodeConfig which the manager acts upon: spec, update plan
+// and reason or observed generation of the update condition. Progress, inventory and port settings reported
+// by daemons are filtered out
+type nodeConfigChangedPredicate struct {
+	predicate.Funcs
+}
+
+func (nodeConfigChangedPredicate) Update(e event.UpdateEvent) bool {
+	oldConfig, ok := e.ObjectOld.(*ethernetv1.EthernetNodeConfig)
+	if !ok {
+		return false
+	}
+	newConfig, ok := e.ObjectNew.(*ethernetv1.EthernetNodeConfig)
+	if !ok {
+		return false
+	}
+
+	if oldConfig.GetGeneration() != newConfig.GetGeneration() {
+		return true
+	}
+	if !equality.Semantic.DeepEqual(oldConfig.Status.UpdatePlan, newConfig.Status.UpdatePlan) {
+		return true
+	}
+	oldCondition := meta.FindStatusCondition(oldConfig.Status.Conditions, nodeUpdateCondition)
+	newCondition := meta.FindStatusCondition(newConfig.Status.Conditions, nodeUpdateCondition)
+	if oldCondition == nil || newCondition == nil {
+		return oldCondition != newCondition
+	}
+	return oldCondition.Reason != newCondition.Reason ||
+		oldCondition.ObservedGeneration != newCondition.ObservedGeneration
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *EthernetClusterConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&ethernetv1.EthernetClusterConfig{}).
+		// update plans computed by daemons are collected from EthernetNodeConfigs
+		Watches(&source.Kind{Type: &ethernetv1.EthernetNodeConfig{}},
+			handler.EnqueueRequestsFromMapFunc(mapNodeConfigToRequest),
+			builder.WithPredicates(nodeConfigChangedPredicate{})).
 		Complete(r)
 }
@@ -12,11 +12,13 @@ import (
 
 	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 const (
@@ -570,6 +572,201 @@ var _ = Describe("EthernetControllerTest", func() {
 			})
 		})
 
+		When("cc has dry run enabled", func() {
+			It("should propagate dry run to nc and publish node plans in cc status", func() {
+				n1 := createNode("n1")
+
+				createNodeInventory(n1.Name, []ethernetv1.Device{
+					{
+						PCIAddress: "0000:15:00.1",
+						VendorID:   "testvendor",
+						DeviceID:   "testid",
+					},
+				})
+
+				cc := createDeviceConfig("dry-run-config", func(cc *ethernetv1.EthernetClusterConfig) {
+					cc.Spec.DeviceConfig = ethernetv1.DeviceConfig{
+						FWURL: "testfwurl",
+					}
+					cc.Spec.DryRun = true
+				})
+
+				reconcile()
+
+				nc := new(ethernetv1.EthernetNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nc)).ToNot(HaveOccurred())
+				Expect(nc.Spec.Config).To(HaveLen(1))
+				Expect(nc.Spec.Config[0].DryRun).To(BeTrue())
+
+				// nc spec was just updated, daemon did not compute the plan yet
+				reconcile()
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+				Expect(cc.Status.Plan).To(HaveLen(1))
+				Expect(cc.Status.Plan[0].NodeName).To(Equal(n1.Name))
+				Expect(cc.Status.Plan[0].Complete).To(BeFalse())
+				Expect(cc.Status.Plan[0].Devices).To(HaveLen(1))
+				Expect(cc.Status.Plan[0].Devices[0].PCIAddress).To(Equal("0000:15:00.1"))
+
+				nc.Status.UpdatePlan = &ethernetv1.NodeUpdatePlan{
+					Devices: []ethernetv1.DeviceUpdatePlan{
+						{PCIAddress: "0000:15:00.1", FirmwareUpdate: true, RebootRequired: true},
+					},
+					Complete:           true,
+					ObservedGeneration: nc.Generation,
+				}
+				Expect(k8sClient.Status().Update(context.TODO(), nc)).ToNot(HaveOccurred())
+
+				reconcile()
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+				Expect(cc.Status.Plan).To(HaveLen(1))
+				Expect(cc.Status.Plan[0].Complete).To(BeTrue())
+				Expect(cc.Status.Plan[0].Devices[0].FirmwareUpdate).To(BeTrue())
+				Expect(cc.Status.Plan[0].RebootRequired).To(BeTrue())
+				// drain is skipped in test environment
+				Expect(cc.Status.Plan[0].DrainRequired).To(BeFalse())
+			})
+		})
+
+		When("cc requests DDP revert on delete", func() {
+			It("should revert DDP on matching devices before the cc is removed", func() {
+				n1 := createNode("n1")
+
+				createNodeInventory(n1.Name, []ethernetv1.Device{
+					{
+						PCIAddress: "0000:15:00.1",
+						VendorID:   "testvendor",
+						DeviceID:   "testid",
+					},
+				})
+
+				cc := createDeviceConfig("revert-config", func(cc *ethernetv1.EthernetClusterConfig) {
+					cc.Spec.DeviceConfig = ethernetv1.DeviceConfig{
+						DDPURL:           "testddpurl",
+						DDPApplyStrategy: "DriverReload",
+					}
+					cc.Spec.RevertDDPOnDelete = true
+				})
+
+				reconcile()
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+				Expect(cc.Finalizers).To(ContainElement(ddpRevertFinalizer))
+
+				Expect(k8sClient.Delete(context.TODO(), cc)).ToNot(HaveOccurred())
+				reconcile()
+
+				nc := new(ethernetv1.EthernetNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nc)).ToNot(HaveOccurred())
+				Expect(nc.Spec.Config).To(HaveLen(1))
+				Expect(nc.Spec.Config[0].DeviceConfig).To(Equal(ethernetv1.DeviceConfig{
+					DDPRevert:        true,
+					DDPApplyStrategy: "DriverReload",
+				}))
+
+				// revert was not completed by the daemon yet
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+
+				nc.Status.Conditions = []v1.Condition{{
+					Type:               nodeUpdateCondition,
+					Status:             v1.ConditionTrue,
+					Reason:             nodeUpdateSucceeded,
+					Message:            "Updated successfully",
+					ObservedGeneration: nc.Generation,
+					LastTransitionTime: v1.Now(),
+				}}
+				Expect(k8sClient.Status().Update(context.TODO(), nc)).ToNot(HaveOccurred())
+
+				reconcile()
+				err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)
+				Expect(errors.IsNotFound(err)).To(BeTrue())
+
+				reconcile()
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nc)).ToNot(HaveOccurred())
+				Expect(nc.Spec.Config).To(BeEmpty())
+			})
+		})
+
+		When("cc has maintenance window", func() {
+			AfterEach(func() {
+				timeNow = time.Now
+			})
+
+			It("should propagate cc.spec to nc only inside the window", func() {
+				n1 := createNode("n1")
+
+				createNodeInventory(n1.Name, []ethernetv1.Device{
+					{
+						PCIAddress: "0000:15:00.1",
+						VendorID:   "testvendor",
+						DeviceID:   "testid",
+					},
+				})
+
+				cc := createDeviceConfig("window-config", func(cc *ethernetv1.EthernetClusterConfig) {
+					cc.Spec.MaintenanceWindow = &ethernetv1.MaintenanceWindow{
+						Schedule: "0 22 * * *",
+						Duration: v1.Duration{Duration: 4 * time.Hour},
+					}
+				})
+
+				timeNow = func() time.Time { return time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC) }
+				reconciler := EthernetClusterConfigReconciler{k8sClient, log, scheme.Scheme}
+				result, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(10 * time.Hour))
+
+				nc := new(ethernetv1.EthernetNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nc)).ToNot(HaveOccurred())
+				Expect(nc.Spec.Config).To(BeEmpty())
+
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+				Expect(cc.Status.Maintenance).ToNot(BeNil())
+				Expect(cc.Status.Maintenance.DeferredNodes).To(Equal([]string{n1.Name}))
+				Expect(cc.Status.Maintenance.NextWindowStart.Time).To(BeTemporally("==", time.Date(2023, 7, 15, 22, 0, 0, 0, time.UTC)))
+
+				timeNow = func() time.Time { return time.Date(2023, 7, 15, 22, 30, 0, 0, time.UTC) }
+				reconcile()
+
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nc)).ToNot(HaveOccurred())
+				Expect(nc.Spec.Config).To(HaveLen(1))
+				Expect(nc.Spec.Config[0].DeviceConfig).To(Equal(cc.Spec.DeviceConfig))
+				Expect(nc.Spec.Config[0].MaintenanceWindow).To(Equal(cc.Spec.MaintenanceWindow))
+
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+				Expect(cc.Status.Maintenance.DeferredNodes).To(BeEmpty())
+			})
+
+			It("should report window shorter than expected update duration as error", func() {
+				n1 := createNode("n1")
+
+				createNodeInventory(n1.Name, []ethernetv1.Device{
+					{
+						PCIAddress: "0000:15:00.1",
+						VendorID:   "testvendor",
+						DeviceID:   "testid",
+					},
+				})
+
+				cc := createDeviceConfig("short-window-config", func(cc *ethernetv1.EthernetClusterConfig) {
+					cc.Spec.MaintenanceWindow = &ethernetv1.MaintenanceWindow{
+						Schedule: "0 22 * * *",
+						Duration: v1.Duration{Duration: 30 * time.Minute},
+					}
+				})
+
+				timeNow = func() time.Time { return time.Date(2023, 7, 15, 22, 10, 0, 0, time.UTC) }
+				reconcile()
+
+				nc := new(ethernetv1.EthernetNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nc)).ToNot(HaveOccurred())
+				Expect(nc.Spec.Config).To(BeEmpty())
+
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+				Expect(cc.Status.Maintenance).ToNot(BeNil())
+				Expect(cc.Status.Maintenance.Error).To(ContainSubstring("shorter than expected update duration 1h0m0s"))
+				Expect(cc.Status.Maintenance.DeferredNodes).To(Equal([]string{n1.Name}))
+			})
+		})
+
 		When("Manager is not set ", func() {
 			It("will return error", func() {
 				var mgr ctrl.Manager
@@ -583,3 +780,39 @@ var _ = Describe("EthernetControllerTest", func() {
 		})
 	})
 })
+
+var _ = Describe("nodeConfigChangedPredicate", func() {
+	nodeConfig := func(generation int64, reason string, dryRun bool) *ethernetv1.EthernetNodeConfig {
+		nc := &ethernetv1.EthernetNodeConfig{ObjectMeta: v1.ObjectMeta{Name: "node", Generation: generation}}
+		nc.Status.Conditions = []v1.Condition{{Type: nodeUpdateCondition, Reason: reason, ObservedGeneration: generation,
+			Message: "Downloading"}}
+		if dryRun {
+			nc.Status.UpdatePlan = &ethernetv1.NodeUpdatePlan{NodeName: "node", ObservedGeneration: generation}
+		}
+		return nc
+	}
+	changed := func(oldConfig, newConfig *ethernetv1.EthernetNodeConfig) bool {
+		return nodeConfigChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: oldConfig, ObjectNew: newConfig})
+	}
+
+	It("will pass changes of generation, update plan and update condition reason", func() {
+		Expect(changed(nodeConfig(1, "InProgress", false), nodeConfig(2, "InProgress", false))).To(BeTrue())
+		Expect(changed(nodeConfig(1, "InProgress", false), nodeConfig(1, "Succeeded", false))).To(BeTrue())
+		Expect(changed(nodeConfig(1, "DryRun", false), nodeConfig(1, "DryRun", true))).To(BeTrue())
+	})
+
+	It("will filter out progress, inventory and port settings reported by daemon", func() {
+		oldConfig := nodeConfig(1, "InProgress", false)
+		newConfig := nodeConfig(1, "InProgress", false)
+		newConfig.Status.Conditions[0].Message = "Downloading 512/2048 bytes"
+		newConfig.Status.Devices = []ethernetv1.Device{{PCIAddress: "0000:01:00.0"}}
+		newConfig.Status.PortSettings = []ethernetv1.PortSettingsStatus{{PCIAddress: "0000:01:00.0"}}
+		Expect(changed(oldConfig, newConfig)).To(BeFalse())
+	})
+
+	It("will map all node configs to a single request", func() {
+		Expect(mapNodeConfigToRequest(nodeConfig(1, "", false))).To(
+			Equal(mapNodeConfigToRequest(&ethernetv1.EthernetNodeConfig{ObjectMeta: v1.ObjectMeta{Name: "other"}})))
+		Expect(mapNodeConfigToRequest(nodeConfig(1, "", false))).To(HaveLen(1))
+	})
+})
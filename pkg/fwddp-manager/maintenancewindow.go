@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	"github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/pkg/utils"
+)
+
+const (
+	// reason of EthernetNodeConfig update condition set by the daemon if update does not fit maintenance window
+	nodeUpdateDeferred = "Deferred"
+
+	// MaintenanceUpdateDurationEnvVarName sets expected duration of node update, windows shorter than that are
+	// invalid. It is passed to the daemon as MAINTENANCE_UPDATE_DURATION_SECONDS
+	MaintenanceUpdateDurationEnvVarName = "ETHERNET_MAINTENANCE_UPDATE_DURATION_SECONDS"
+	maintenanceUpdateDurationDefault    = int64(3600)
+)
+
+var timeNow = time.Now
+
+// maintenanceWindows holds state of maintenance windows of EthernetClusterConfigs at the time of reconcile
+type maintenanceWindows struct {
+	now    time.Time
+	open   map[string]bool
+	status map[string]*ethernetv1.MaintenanceStatus
+}
+
+// newMaintenanceWindows evaluates maintenance windows of the configs. Window which cannot be parsed, or is shorter
+// than expected duration of the update, is never open
+func newMaintenanceWindows(configs []ethernetv1.EthernetClusterConfig, log logr.Logger) *maintenanceWindows {
+	updateDuration := time.Duration(utils.GetOsVarOrUseDefault(log, MaintenanceUpdateDurationEnvVarName,
+		maintenanceUpdateDurationDefault)) * time.Second
+	mw := &maintenanceWindows{
+		now:    timeNow(),
+		open:   map[string]bool{},
+		status: map[string]*ethernetv1.MaintenanceStatus{},
+	}
+
+	for _, cc := range configs {
+		w := cc.Spec.MaintenanceWindow
+		if w == nil {
+			continue
+		}
+
+		status := &ethernetv1.MaintenanceStatus{}
+		window, err := utils.ParseMaintenanceWindow(w.Schedule, w.Duration.Duration, w.TimeZone)
+		if err == nil {
+			err = window.CheckUpdateFits(updateDuration)
+		}
+		if err != nil {
+			log.Error(err, "invalid maintenance window", "name", cc.Name)
+			status.Error = err.Error()
+		} else {
+			_, mw.open[cc.Name] = window.End(mw.now)
+			next := metav1.NewTime(window.NextStart(mw.now))
+			status.NextWindowStart = &next
+		}
+		mw.status[cc.Name] = status
+	}
+	return mw
+}
+
+// isOpen returns true if changes of the config can be propagated to the nodes. Dry run does not disrupt
+// the nodes, so it is not limited by the window
+func (m *maintenanceWindows) isOpen(cc *ethernetv1.EthernetClusterConfig) bool {
+	return cc.Spec.MaintenanceWindow == nil || cc.Spec.DryRun || m.open[cc.Name]
+}
+
+// deferNode records that changes of the config wait for the maintenance window on the node
+func (m *maintenanceWindows) deferNode(cc *ethernetv1.EthernetClusterConfig, nodeName string) {
+	status, ok := m.status[cc.Name]
+	if !ok {
+		return
+	}
+	for _, n := range status.DeferredNodes {
+		if n == nodeName {
+			return
+		}
+	}
+	status.DeferredNodes = append(status.DeferredNodes, nodeName)
+}
+
+// collect records nodes on which the daemon deferred update of devices matched to configs with maintenance window
+func (m *maintenanceWindows) collect(ncc NodeConfigurationCtx) {
+	nodeConfig, deviceConfigContext := ncc()
+
+	condition := meta.FindStatusCondition(nodeConfig.Status.Conditions, nodeUpdateCondition)
+	if condition == nil || condition.Reason != nodeUpdateDeferred {
+		return
+	}
+	for _, cc := range deviceConfigContext {
+		m.deferNode(&cc, nodeConfig.Name)
+	}
+}
+
+// requeueAfter returns time until the earliest window of configs with deferred changes opens,
+// zero if no changes are deferred
+func (m *maintenanceWindows) requeueAfter() time.Duration {
+	var after time.Duration
+	for _, status := range m.status {
+		if len(status.DeferredNodes) == 0 || status.NextWindowStart == nil {
+			continue
+		}
+		if d := status.NextWindowStart.Sub(m.now); after == 0 || d < after {
+			after = d
+		}
+	}
+	return after
+}
+
+// updateMaintenanceStatus publishes state of the maintenance window in status of the EthernetClusterConfig
+func (r *EthernetClusterConfigReconciler) updateMaintenanceStatus(cc *ethernetv1.EthernetClusterConfig,
+	windows *maintenanceWindows) error {
+	status := windows.status[cc.Name]
+	if status != nil {
+		sort.Strings(status.DeferredNodes)
+	}
+
+	if equality.Semantic.DeepEqual(status, cc.Status.Maintenance) {
+		return nil
+	}
+	cc.Status.Maintenance = status
+	return r.Status().Update(context.TODO(), cc)
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+const metricsNamespace = "intel_ethernet"
+
+var (
+	fleetDeviceInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "fleet_device_info",
+		Help:      "Device reported in EthernetNodeConfig status, with its firmware, DDP and driver versions as labels",
+	}, []string{"node", "pci_address", "device_name", "firmware_version", "ddp_package", "ddp_version", "driver",
+		"driver_version"})
+
+	fleetUpdateStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "fleet_node_update_status",
+		Help:      "1 for the reason of the Updated condition of EthernetNodeConfig of the node",
+	}, []string{"node", "reason"})
+
+	fleetRebootPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "fleet_reboot_pending",
+		Help:      "1 if the node waits for the reboot completing the update, 0 otherwise",
+	}, []string{"node"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(fleetDeviceInfo, fleetUpdateStatus, fleetRebootPending)
+}
+
+// fleetMetrics collects status of EthernetNodeConfigs of all nodes during reconcile
+type fleetMetrics []ethernetv1.EthernetNodeConfig
+
+func (f *fleetMetrics) collect(ncc NodeConfigurationCtx) {
+	nodeConfig, _ := ncc()
+	*f = append(*f, nodeConfig)
+}
+
+// publish replaces series of previous reconcile, so that removed nodes and devices are not reported
+func (f fleetMetrics) publish() {
+	fleetDeviceInfo.Reset()
+	fleetUpdateStatus.Reset()
+	fleetRebootPending.Reset()
+
+	for _, nc := range f {
+		for _, d := range nc.Status.Devices {
+			fleetDeviceInfo.WithLabelValues(nc.Name, d.PCIAddress, d.Name, d.Firmware.Version, d.DDP.PackageName,
+				d.DDP.Version, d.Driver, d.DriverVersion).Set(1)
+		}
+
+		if condition := meta.FindStatusCondition(nc.Status.Conditions, nodeUpdateCondition); condition != nil {
+			fleetUpdateStatus.WithLabelValues(nc.Name, condition.Reason).Set(1)
+		}
+
+		pending := 0.0
+		if nc.Status.Reboot != nil && nc.Status.Reboot.Pending {
+			pending = 1
+		}
+		fleetRebootPending.WithLabelValues(nc.Name).Set(pending)
+	}
+}
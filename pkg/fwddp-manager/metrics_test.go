@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+var _ = Describe("fleetMetrics", func() {
+	nodeConfig := func(name, reason string, pending bool) NodeConfigurationCtx {
+		nc := ethernetv1.EthernetNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		nc.Status.Devices = []ethernetv1.Device{{PCIAddress: "0000:01:00.0", Driver: "ice",
+			Firmware: ethernetv1.FirmwareInfo{Version: "4.20"}}}
+		nc.Status.Conditions = []metav1.Condition{{Type: nodeUpdateCondition, Reason: reason}}
+		nc.Status.Reboot = &ethernetv1.RebootStatus{Pending: pending}
+		return func() (ethernetv1.EthernetNodeConfig, DeviceConfigContext) { return nc, nil }
+	}
+
+	It("will report devices, update status and pending reboots of collected nodes", func() {
+		var fleet fleetMetrics
+		fleet.collect(nodeConfig("node1", "Succeeded", false))
+		fleet.collect(nodeConfig("node2", "RebootPending", true))
+		fleet.publish()
+
+		Expect(testutil.CollectAndCount(fleetDeviceInfo)).To(Equal(2))
+		Expect(testutil.ToFloat64(fleetDeviceInfo.WithLabelValues("node1", "0000:01:00.0", "", "4.20", "", "", "ice",
+			""))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(fleetUpdateStatus.WithLabelValues("node2", "RebootPending"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(fleetRebootPending.WithLabelValues("node1"))).To(Equal(0.0))
+		Expect(testutil.ToFloat64(fleetRebootPending.WithLabelValues("node2"))).To(Equal(1.0))
+
+		fleet = nil
+		fleet.collect(nodeConfig("node1", "InProgress", false))
+		fleet.publish()
+		Expect(testutil.CollectAndCount(fleetDeviceInfo)).To(Equal(1))
+		Expect(testutil.CollectAndCount(fleetUpdateStatus)).To(Equal(1))
+	})
+})
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// OperationLogStorageEnvVarName selects where daemons keep operation logs, it is passed to the daemon
+	// as OPERATION_LOG_STORAGE
+	OperationLogStorageEnvVarName = "ETHERNET_OPERATION_LOG_STORAGE"
+
+	operationLogStorageConfigMap = "ConfigMap"
+	operationLogStorageSecret    = "Secret"
+
+	// operationLogAccessName is the name of Role and RoleBinding granting daemons access to log objects
+	operationLogAccessName = "fwddp-daemon-logs"
+)
+
+// operationLogObjectName returns name of ConfigMap or Secret in which the daemon of the node keeps operation logs
+func operationLogObjectName(nodeName string) string {
+	return "fwddp-logs-" + nodeName
+}
+
+// syncOperationLogAccess grants daemons get and update of the objects with operation logs of the nodes, if logs
+// are kept in ConfigMaps or Secrets, and revokes it otherwise. Access to other objects isn't granted, so
+// the objects are created by the manager, as create can't be restricted to resource names
+func (r *EthernetClusterConfigReconciler) syncOperationLogAccess(nodes []corev1.Node) error {
+	var resource string
+	switch os.Getenv(OperationLogStorageEnvVarName) {
+	case "", operationLogStorageConfigMap:
+		resource = "configmaps"
+	case operationLogStorageSecret:
+		resource = "secrets"
+	default:
+		return r.deleteOperationLogAccess()
+	}
+
+	names := make([]string, 0, len(nodes))
+	for i := range nodes {
+		if err := r.createOperationLogObject(resource, &nodes[i]); err != nil {
+			return err
+		}
+		names = append(names, operationLogObjectName(nodes[i].Name))
+	}
+	if len(names) == 0 {
+		// rule without resource names would grant access to all objects
+		return r.deleteOperationLogAccess()
+	}
+	sort.Strings(names)
+
+	return r.grantDaemonAccess(operationLogAccessName, rbacv1.PolicyRule{
+		APIGroups:     []string{""},
+		Resources:     []string{resource},
+		Verbs:         []string{"get", "update"},
+		ResourceNames: names,
+	})
+}
+
+// createOperationLogObject creates empty log object of the node owned by the node, so it is removed with the node
+func (r *EthernetClusterConfigReconciler) createOperationLogObject(resource string, node *corev1.Node) error {
+	meta := metav1.ObjectMeta{
+		Name:      operationLogObjectName(node.Name),
+		Namespace: NAMESPACE,
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       node.Name,
+			UID:        node.UID,
+		}},
+	}
+
+	var obj client.Object = &corev1.ConfigMap{ObjectMeta: meta}
+	if resource == "secrets" {
+		obj = &corev1.Secret{ObjectMeta: meta, Type: corev1.SecretTypeOpaque}
+	}
+	if err := r.Create(context.TODO(), obj); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *EthernetClusterConfigReconciler) deleteOperationLogAccess() error {
+	return r.revokeDaemonAccess(operationLogAccessName)
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("syncOperationLogAccess", func() {
+	var reconciler *EthernetClusterConfigReconciler
+	nodes := []corev1.Node{
+		{ObjectMeta: v1.ObjectMeta{Name: "n2", UID: "uid-n2"}},
+		{ObjectMeta: v1.ObjectMeta{Name: "n1", UID: "uid-n1"}},
+	}
+	accessKey := client.ObjectKey{Name: operationLogAccessName, Namespace: NAMESPACE}
+
+	// log objects may be left by reconciles of other specs, which create nodes with the same names
+	deleteLogObjects := func() {
+		for _, node := range nodes {
+			meta := v1.ObjectMeta{Name: operationLogObjectName(node.Name), Namespace: NAMESPACE}
+			for _, obj := range []client.Object{&corev1.ConfigMap{ObjectMeta: meta}, &corev1.Secret{ObjectMeta: meta}} {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(context.TODO(), obj))).To(Succeed())
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		reconciler = &EthernetClusterConfigReconciler{k8sClient, ctrl.Log.WithName("test"), scheme.Scheme}
+		deleteLogObjects()
+	})
+
+	AfterEach(func() {
+		Expect(os.Unsetenv(OperationLogStorageEnvVarName)).To(Succeed())
+		Expect(reconciler.deleteOperationLogAccess()).To(Succeed())
+		deleteLogObjects()
+	})
+
+	var _ = It("will create log ConfigMaps of nodes and grant access only to them", func() {
+		Expect(reconciler.syncOperationLogAccess(nodes)).To(Succeed())
+
+		for _, node := range nodes {
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: "fwddp-logs-" + node.Name, Namespace: NAMESPACE},
+				cm)).To(Succeed())
+			Expect(cm.OwnerReferences).To(HaveLen(1))
+			Expect(cm.OwnerReferences[0].Kind).To(Equal("Node"))
+			Expect(cm.OwnerReferences[0].UID).To(Equal(node.UID))
+		}
+
+		role := &rbacv1.Role{}
+		Expect(k8sClient.Get(context.TODO(), accessKey, role)).To(Succeed())
+		Expect(role.Rules).To(Equal([]rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"configmaps"},
+			Verbs:         []string{"get", "update"},
+			ResourceNames: []string{"fwddp-logs-n1", "fwddp-logs-n2"},
+		}}))
+
+		binding := &rbacv1.RoleBinding{}
+		Expect(k8sClient.Get(context.TODO(), accessKey, binding)).To(Succeed())
+		Expect(binding.RoleRef.Name).To(Equal(operationLogAccessName))
+		Expect(binding.Subjects).To(Equal([]rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      "fwddp-daemon",
+			Namespace: NAMESPACE,
+		}}))
+	})
+
+	var _ = It("will grant access to log Secrets of current nodes", func() {
+		Expect(os.Setenv(OperationLogStorageEnvVarName, "Secret")).To(Succeed())
+		Expect(reconciler.syncOperationLogAccess(nodes)).To(Succeed())
+		Expect(reconciler.syncOperationLogAccess(nodes[1:])).To(Succeed())
+
+		Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: "fwddp-logs-n1", Namespace: NAMESPACE},
+			&corev1.Secret{})).To(Succeed())
+		role := &rbacv1.Role{}
+		Expect(k8sClient.Get(context.TODO(), accessKey, role)).To(Succeed())
+		Expect(role.Rules).To(HaveLen(1))
+		Expect(role.Rules[0].Resources).To(Equal([]string{"secrets"}))
+		Expect(role.Rules[0].ResourceNames).To(Equal([]string{"fwddp-logs-n1"}))
+	})
+
+	var _ = It("will revoke access if logs are not kept in ConfigMaps or Secrets", func() {
+		Expect(reconciler.syncOperationLogAccess(nodes)).To(Succeed())
+
+		Expect(os.Setenv(OperationLogStorageEnvVarName, "S3")).To(Succeed())
+		Expect(reconciler.syncOperationLogAccess(nodes)).To(Succeed())
+
+		err := k8sClient.Get(context.TODO(), accessKey, &rbacv1.Role{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		err = k8sClient.Get(context.TODO(), accessKey, &rbacv1.RoleBinding{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+
+	var _ = It("will not grant access without nodes", func() {
+		Expect(reconciler.syncOperationLogAccess(nil)).To(Succeed())
+
+		err := k8sClient.Get(context.TODO(), accessKey, &rbacv1.Role{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+})
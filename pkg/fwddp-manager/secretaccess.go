@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"sort"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// secretAccessName is the name of Role and RoleBinding granting daemons access to pull and signature key secrets
+const secretAccessName = "fwddp-daemon-secrets"
+
+// syncSecretAccess grants daemons get of the pull and signature key secrets referenced by the cluster configs,
+// access to other secrets isn't granted. Role is removed if no secret is referenced
+func (r *EthernetClusterConfigReconciler) syncSecretAccess(configs []ethernetv1.EthernetClusterConfig) error {
+	referenced := map[string]bool{}
+	for i := range configs {
+		for _, name := range []string{configs[i].Spec.DeviceConfig.PullSecret,
+			configs[i].Spec.DeviceConfig.SignatureKeySecret} {
+			if name != "" {
+				referenced[name] = true
+			}
+		}
+	}
+	if len(referenced) == 0 {
+		// rule without resource names would grant access to all secrets
+		return r.revokeDaemonAccess(secretAccessName)
+	}
+
+	names := make([]string, 0, len(referenced))
+	for name := range referenced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return r.grantDaemonAccess(secretAccessName, rbacv1.PolicyRule{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		Verbs:         []string{"get"},
+		ResourceNames: names,
+	})
+}
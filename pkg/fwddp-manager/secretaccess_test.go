@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("syncSecretAccess", func() {
+	var reconciler *EthernetClusterConfigReconciler
+	accessKey := client.ObjectKey{Name: secretAccessName, Namespace: NAMESPACE}
+
+	clusterConfig := func(pullSecret, keySecret string) ethernetv1.EthernetClusterConfig {
+		config := ethernetv1.EthernetClusterConfig{}
+		config.Spec.DeviceConfig.PullSecret = pullSecret
+		config.Spec.DeviceConfig.SignatureKeySecret = keySecret
+		return config
+	}
+
+	BeforeEach(func() {
+		reconciler = &EthernetClusterConfigReconciler{k8sClient, ctrl.Log.WithName("test"), scheme.Scheme}
+	})
+
+	AfterEach(func() {
+		Expect(reconciler.revokeDaemonAccess(secretAccessName)).To(Succeed())
+	})
+
+	var _ = It("will grant access only to referenced secrets", func() {
+		configs := []ethernetv1.EthernetClusterConfig{
+			clusterConfig("registry", "key"),
+			clusterConfig("", "key"),
+			clusterConfig("another-registry", ""),
+		}
+		Expect(reconciler.syncSecretAccess(configs)).To(Succeed())
+
+		role := &rbacv1.Role{}
+		Expect(k8sClient.Get(context.TODO(), accessKey, role)).To(Succeed())
+		Expect(role.Rules).To(Equal([]rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			Verbs:         []string{"get"},
+			ResourceNames: []string{"another-registry", "key", "registry"},
+		}}))
+
+		binding := &rbacv1.RoleBinding{}
+		Expect(k8sClient.Get(context.TODO(), accessKey, binding)).To(Succeed())
+		Expect(binding.RoleRef.Name).To(Equal(secretAccessName))
+		Expect(binding.Subjects).To(Equal([]rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      "fwddp-daemon",
+			Namespace: NAMESPACE,
+		}}))
+	})
+
+	var _ = It("will update access when references change", func() {
+		Expect(reconciler.syncSecretAccess([]ethernetv1.EthernetClusterConfig{clusterConfig("registry", "key")})).
+			To(Succeed())
+		Expect(reconciler.syncSecretAccess([]ethernetv1.EthernetClusterConfig{clusterConfig("", "key")})).
+			To(Succeed())
+
+		role := &rbacv1.Role{}
+		Expect(k8sClient.Get(context.TODO(), accessKey, role)).To(Succeed())
+		Expect(role.Rules).To(HaveLen(1))
+		Expect(role.Rules[0].ResourceNames).To(Equal([]string{"key"}))
+	})
+
+	var _ = It("will revoke access if no secret is referenced", func() {
+		Expect(reconciler.syncSecretAccess([]ethernetv1.EthernetClusterConfig{clusterConfig("registry", "")})).
+			To(Succeed())
+		Expect(reconciler.syncSecretAccess([]ethernetv1.EthernetClusterConfig{clusterConfig("", "")})).To(Succeed())
+
+		err := k8sClient.Get(context.TODO(), accessKey, &rbacv1.Role{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		err = k8sClient.Get(context.TODO(), accessKey, &rbacv1.RoleBinding{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package fwddp_manager
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	ethernetv1 "github.com/intel-collab/applications.orchestration.operators.intel-ethernet-operator/apis/ethernet/v1"
+)
+
+// clusterConfigPlans maps name of EthernetClusterConfig with dry run enabled to plans of the nodes it selects
+type clusterConfigPlans map[string][]ethernetv1.NodeUpdatePlan
+
+// collect adds plans of devices matched to dry run configs on the node. Plans are taken from EthernetNodeConfig
+// status, devices without plan computed for the current spec of EthernetNodeConfig make the node plan incomplete
+func (p clusterConfigPlans) collect(ncc NodeConfigurationCtx) {
+	nodeConfig, deviceConfigContext := ncc()
+
+	var published []ethernetv1.DeviceUpdatePlan
+	if plan := nodeConfig.Status.UpdatePlan; plan != nil && plan.ObservedGeneration == nodeConfig.Generation {
+		published = plan.Devices
+	}
+
+	nodePlans := map[string]*ethernetv1.NodeUpdatePlan{}
+	for pciAddress, cc := range deviceConfigContext {
+		if !cc.Spec.DryRun {
+			continue
+		}
+		nodePlan, ok := nodePlans[cc.Name]
+		if !ok {
+			nodePlan = &ethernetv1.NodeUpdatePlan{NodeName: nodeConfig.Name, Complete: true}
+			nodePlans[cc.Name] = nodePlan
+		}
+
+		devicePlan := ethernetv1.DeviceUpdatePlan{PCIAddress: pciAddress}
+		found := false
+		for _, d := range published {
+			if d.PCIAddress == pciAddress {
+				devicePlan, found = d, true
+				break
+			}
+		}
+		nodePlan.Complete = nodePlan.Complete && found
+		nodePlan.Devices = append(nodePlan.Devices, devicePlan)
+	}
+
+	for name, nodePlan := range nodePlans {
+		sort.Slice(nodePlan.Devices, func(i, j int) bool {
+			return nodePlan.Devices[i].PCIAddress < nodePlan.Devices[j].PCIAddress
+		})
+		nodePlan.SetRequirements(nodeConfig.Spec.DrainSkip)
+		p[name] = append(p[name], *nodePlan)
+	}
+}
+
+// updateClusterConfigPlan publishes collected plans in status of the EthernetClusterConfig,
+// plan of config without dry run is cleared
+func (r *EthernetClusterConfigReconciler) updateClusterConfigPlan(cc *ethernetv1.EthernetClusterConfig,
+	plans clusterConfigPlans) error {
+	var plan []ethernetv1.NodeUpdatePlan
+	if cc.Spec.DryRun {
+		plan = plans[cc.Name]
+		sort.Slice(plan, func(i, j int) bool { return plan[i].NodeName < plan[j].NodeName })
+	}
+
+	if equality.Semantic.DeepEqual(plan, cc.Status.Plan) {
+		return nil
+	}
+	cc.Status.Plan = plan
+	return r.Status().Update(context.TODO(), cc)
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package utils
+
+import (
+	"fmt"
+	"time"
+	// time zones are resolved even if the image does not provide tzdata
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+)
+
+// MaintenanceWindow is a recurring period of time when disruptive maintenance is allowed. Windows start
+// at activations of the cron schedule and last for the given duration
+type MaintenanceWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// ParseMaintenanceWindow parses standard 5-field cron schedule of window starts, evaluated in timeZone
+// (IANA name, UTC if empty)
+func ParseMaintenanceWindow(schedule string, duration time.Duration, timeZone string) (*MaintenanceWindow, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("maintenance window duration must be positive, got %v", duration)
+	}
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance window time zone %q: %v", timeZone, err)
+	}
+
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance window schedule %q: %v", schedule, err)
+	}
+	if s, ok := parsed.(*cron.SpecSchedule); ok {
+		s.Location = location
+	}
+	return &MaintenanceWindow{schedule: parsed, duration: duration}, nil
+}
+
+// End returns end of the window open at now. False is returned if no window is open
+func (w *MaintenanceWindow) End(now time.Time) (time.Time, bool) {
+	// window open at now started after now-duration
+	start := w.schedule.Next(now.Add(-w.duration))
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, false
+	}
+	return start.Add(w.duration), true
+}
+
+// NextStart returns start of the first window opening after now
+func (w *MaintenanceWindow) NextStart(now time.Time) time.Time {
+	return w.schedule.Next(now)
+}
+
+// CheckUpdateFits returns error if update expected to take updateDuration can't complete inside any window
+func (w *MaintenanceWindow) CheckUpdateFits(updateDuration time.Duration) error {
+	if w.duration < updateDuration {
+		return fmt.Errorf("maintenance window duration %v is shorter than expected update duration %v",
+			w.duration, updateDuration)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package utils
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MaintenanceWindow", func() {
+	// every Saturday 22:00 - Sunday 02:00 in Warsaw (UTC+2 in summer)
+	window, err := ParseMaintenanceWindow("0 22 * * 6", 4*time.Hour, "Europe/Warsaw")
+
+	var _ = It("will parse valid window", func() {
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	var _ = It("will return end of window open at given time", func() {
+		end, open := window.End(time.Date(2023, 7, 15, 23, 30, 0, 0, time.UTC))
+		Expect(open).To(BeTrue())
+		Expect(end).To(BeTemporally("==", time.Date(2023, 7, 16, 0, 0, 0, 0, time.UTC)))
+	})
+
+	var _ = It("will report window closed outside of it", func() {
+		_, open := window.End(time.Date(2023, 7, 15, 19, 59, 0, 0, time.UTC))
+		Expect(open).To(BeFalse())
+		_, open = window.End(time.Date(2023, 7, 16, 0, 0, 0, 0, time.UTC))
+		Expect(open).To(BeFalse())
+	})
+
+	var _ = It("will return start of next window", func() {
+		next := window.NextStart(time.Date(2023, 7, 16, 0, 0, 0, 0, time.UTC))
+		Expect(next).To(BeTemporally("==", time.Date(2023, 7, 22, 20, 0, 0, 0, time.UTC)))
+	})
+
+	var _ = It("will use UTC if time zone is not set", func() {
+		w, err := ParseMaintenanceWindow("0 22 * * 6", time.Hour, "")
+		Expect(err).ToNot(HaveOccurred())
+		_, open := w.End(time.Date(2023, 7, 15, 22, 30, 0, 0, time.UTC))
+		Expect(open).To(BeTrue())
+	})
+
+	var _ = It("will fail on invalid window", func() {
+		_, err := ParseMaintenanceWindow("0 22 * *", time.Hour, "")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseMaintenanceWindow("0 22 * * 6", time.Hour, "Mars/Olympus")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseMaintenanceWindow("0 22 * * 6", 0, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	var _ = It("will report update which does not fit the window", func() {
+		w, err := ParseMaintenanceWindow("0 22 * * 6", time.Hour, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.CheckUpdateFits(time.Hour)).To(Succeed())
+		Expect(w.CheckUpdateFits(2 * time.Hour)).To(MatchError(ContainSubstring("shorter than expected update duration")))
+	})
+})
@@ -0,0 +1,481 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	OCIScheme = "oci://"
+
+	ociManifestMediaType      = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType   = "application/vnd.docker.distribution.manifest.v2+json"
+	ociTitleAnnotation        = "org.opencontainers.image.title"
+	manifestSizeLimitInBytes  = 4194304 // 4 MB
+	tokenResponseLimitInBytes = 1048576 // 1 MB
+)
+
+var (
+	ociDigestRegex = regexp.MustCompile(`^(sha256:[a-f0-9]{64}|sha512:[a-f0-9]{128})$`)
+	ociTagRegex    = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	ociRepoRegex   = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+)
+
+// OCIReference is a parsed oci://registry/repository[:tag][@digest] reference
+type OCIReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// RegistryCredentials are used to authenticate to OCI registry
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// OCIPullOptions configures PullOCIArtifact
+type OCIPullOptions struct {
+	// PlainHTTPRegistries are registries (host[:port]) accessed over plain HTTP instead of HTTPS.
+	// Registries on localhost and loopback addresses are always accessed over plain HTTP
+	PlainHTTPRegistries []string
+	// Download configures retries, resume, timeout and progress reporting of the layer download
+	Download DownloadOptions
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+func IsOCIReference(ref string) bool {
+	return strings.HasPrefix(ref, OCIScheme)
+}
+
+// ParseOCIReference parses reference in oci://registry/repository[:tag][@digest] format.
+// If neither tag nor digest is provided, "latest" tag is used
+func ParseOCIReference(ref string) (*OCIReference, error) {
+	if !IsOCIReference(ref) {
+		return nil, fmt.Errorf("invalid OCI reference %s: missing %s scheme", ref, OCIScheme)
+	}
+	name := strings.TrimPrefix(ref, OCIScheme)
+
+	r := &OCIReference{}
+	if n, digest, found := strings.Cut(name, "@"); found {
+		if !ociDigestRegex.MatchString(digest) {
+			return nil, fmt.Errorf("invalid OCI reference %s: invalid digest %s", ref, digest)
+		}
+		name, r.Digest = n, digest
+	}
+
+	registry, repository, found := strings.Cut(name, "/")
+	if !found || registry == "" {
+		return nil, fmt.Errorf("invalid OCI reference %s: missing registry", ref)
+	}
+	if repo, tag, found := strings.Cut(repository, ":"); found {
+		if !ociTagRegex.MatchString(tag) {
+			return nil, fmt.Errorf("invalid OCI reference %s: invalid tag %s", ref, tag)
+		}
+		repository, r.Tag = repo, tag
+	}
+	if !ociRepoRegex.MatchString(repository) {
+		return nil, fmt.Errorf("invalid OCI reference %s: invalid repository %s", ref, repository)
+	}
+	r.Registry, r.Repository = registry, repository
+
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	return r, nil
+}
+
+// Reference returns digest if it is set, otherwise tag
+func (r *OCIReference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// baseURL returns registry API endpoint. Like container runtimes, plain HTTP is used only for local registries
+// and registries explicitly listed in plainHTTPRegistries
+func (r *OCIReference) baseURL(plainHTTPRegistries []string) string {
+	for _, registry := range plainHTTPRegistries {
+		if registry == r.Registry {
+			return "http://" + r.Registry
+		}
+	}
+	host := r.Registry
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" || net.ParseIP(host).IsLoopback() {
+		return "http://" + r.Registry
+	}
+	return "https://" + r.Registry
+}
+
+// RegistryCredentialsFromDockerConfig returns credentials for registry from .dockerconfigjson content
+func RegistryCredentialsFromDockerConfig(dockerConfig []byte, registry string) (*RegistryCredentials, error) {
+	var cfg struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(dockerConfig, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %v", err)
+	}
+
+	for server, auth := range cfg.Auths {
+		if normalizeRegistryHost(server) != registry {
+			continue
+		}
+		if auth.Username != "" || auth.Password != "" {
+			return &RegistryCredentials{Username: auth.Username, Password: auth.Password}, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode auth for registry %s: %v", registry, err)
+		}
+		username, password, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid auth for registry %s", registry)
+		}
+		return &RegistryCredentials{Username: username, Password: password}, nil
+	}
+	return nil, fmt.Errorf("no credentials for registry %s found in docker config", registry)
+}
+
+// normalizeRegistryHost strips scheme and path from docker config server entries, e.g. https://registry:5000/v1/
+func normalizeRegistryHost(server string) string {
+	server = strings.TrimPrefix(server, "https://")
+	server = strings.TrimPrefix(server, "http://")
+	host, _, _ := strings.Cut(server, "/")
+	return host
+}
+
+// PullOCIArtifact pulls single layer artifact referenced by ref into dstDir and returns path of the pulled file.
+// Manifest is verified against the digest from reference (if set) and layer is verified against its descriptor digest
+// and additionally against checksum (if set). File name is taken from layer title annotation or repository name.
+// Interrupted layer download is retried and resumed as configured by opts.Download
+func PullOCIArtifact(dstDir, ref, checksum string, creds *RegistryCredentials, client *http.Client,
+	opts OCIPullOptions) (string, error) {
+	r, err := ParseOCIReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	rc := &registryClient{client: client, creds: creds}
+
+	baseURL := r.baseURL(opts.PlainHTTPRegistries)
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", baseURL, r.Repository, r.Reference())
+	manifestBytes, err := rc.fetch(manifestURL, ociManifestMediaType+", "+dockerManifestMediaType, manifestSizeLimitInBytes)
+	if err != nil {
+		return "", fmt.Errorf("unable to pull manifest of %s err: %v", ref, err)
+	}
+	if r.Digest != "" {
+		match, err := digestMatches(manifestBytes, r.Digest)
+		if err != nil {
+			return "", err
+		}
+		if !match {
+			return "", fmt.Errorf("Digest mismatch in pulled manifest: %s", ref)
+		}
+	}
+
+	layer, err := artifactLayer(manifestBytes)
+	if err != nil {
+		return "", fmt.Errorf("unsupported artifact %s: %v", ref, err)
+	}
+
+	dstPath := filepath.Join(dstDir, artifactFileName(r, layer))
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", baseURL, r.Repository, layer.Digest)
+	if err := rc.download(dstPath, blobURL, layer.Size, opts.Download); err != nil {
+		return "", fmt.Errorf("unable to pull layer of %s err: %v", ref, err)
+	}
+
+	match, err := VerifyChecksum(dstPath, layer.Digest)
+	if err != nil {
+		return "", err
+	}
+	if !match {
+		return "", fmt.Errorf("Digest mismatch in pulled layer: %s", ref)
+	}
+
+	if checksum != "" {
+		match, err := VerifyChecksum(dstPath, checksum)
+		if err != nil {
+			return "", err
+		}
+		if !match {
+			return "", fmt.Errorf("Checksum mismatch in pulled file: %s", ref)
+		}
+	}
+	return dstPath, nil
+}
+
+func artifactLayer(manifestBytes []byte) (*ociDescriptor, error) {
+	var manifest ociManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	if manifest.MediaType != "" && manifest.MediaType != ociManifestMediaType && manifest.MediaType != dockerManifestMediaType {
+		return nil, fmt.Errorf("unsupported manifest media type %s", manifest.MediaType)
+	}
+	if len(manifest.Layers) != 1 {
+		return nil, fmt.Errorf("expected exactly one layer, found %d", len(manifest.Layers))
+	}
+	layer := manifest.Layers[0]
+	if !ociDigestRegex.MatchString(layer.Digest) {
+		return nil, fmt.Errorf("invalid layer digest %s", layer.Digest)
+	}
+	return &layer, nil
+}
+
+func artifactFileName(r *OCIReference, layer *ociDescriptor) string {
+	name := filepath.Base(layer.Annotations[ociTitleAnnotation])
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return path.Base(r.Repository)
+	}
+	return name
+}
+
+func digestMatches(content []byte, digest string) (bool, error) {
+	algorithm, value, err := parseChecksum(digest)
+	if err != nil {
+		return false, err
+	}
+	h := checksumAlgorithms[algorithm]()
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)) == value, nil
+}
+
+// registryClient performs registry requests, authenticating with basic auth or bearer token when challenged
+type registryClient struct {
+	client        *http.Client
+	creds         *RegistryCredentials
+	authorization string
+}
+
+func (c *registryClient) fetch(url, accept string, limit int64) ([]byte, error) {
+	resp, err := c.get(url, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("response exceeds limit %d bytes", limit)
+	}
+	return content, nil
+}
+
+// download downloads blob the same way as DownloadFileWithOptions. Authorization obtained for the manifest
+// is reused, Go HTTP client drops it when registry redirects to blob storage on another host. If the registry
+// challenges the blob request, e.g. because the token expired, it is authorized again as the manifest request
+func (c *registryClient) download(path, url string, size int64, opts DownloadOptions) error {
+	err := downloadFile(path, url, "", c.client, opts, c.header())
+	var statusErr *downloadStatusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusUnauthorized {
+		if err := c.authorize(statusErr.header.Get("WWW-Authenticate")); err != nil {
+			return err
+		}
+		err = downloadFile(path, url, "", c.client, opts, c.header())
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("size mismatch, expected %d bytes, got %d", size, info.Size())
+	}
+	return nil
+}
+
+// header returns headers authorizing requests to the registry
+func (c *registryClient) header() http.Header {
+	header := http.Header{}
+	if c.authorization != "" {
+		header.Set("Authorization", c.authorization)
+	}
+	return header
+}
+
+func (c *registryClient) get(url, accept string) (*http.Response, error) {
+	resp, err := c.do(url, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authorize(challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(url, accept); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New(resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *registryClient) do(url, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+	return c.client.Do(req)
+}
+
+// authorize handles WWW-Authenticate challenge of the registry
+func (c *registryClient) authorize(challenge string) error {
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.creds == nil {
+			return errors.New("registry requires authentication, but no pull secret provided")
+		}
+		c.authorization = "Basic " + basicAuth(c.creds)
+		return nil
+	case "bearer":
+		token, err := c.fetchToken(params)
+		if err != nil {
+			return fmt.Errorf("failed to get registry token: %v", err)
+		}
+		c.authorization = "Bearer " + token
+		return nil
+	default:
+		return fmt.Errorf("unsupported registry authentication challenge %q", challenge)
+	}
+}
+
+func (c *registryClient) fetchToken(params map[string]string) (string, error) {
+	realm, ok := params["realm"]
+	if !ok {
+		return "", errors.New("missing realm in authentication challenge")
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if value, ok := params[key]; ok {
+			query.Set(key, value)
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.creds != nil {
+		req.SetBasicAuth(c.creds.Username, c.creds.Password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, tokenResponseLimitInBytes))
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(content, &token); err != nil {
+		return "", err
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", errors.New("empty token in response")
+}
+
+func basicAuth(creds *RegistryCredentials) string {
+	return base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password))
+}
+
+// parseAuthChallenge parses WWW-Authenticate header, e.g. Bearer realm="https://auth",service="registry",scope="repository:fw:pull"
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			value = value[end+2:]
+		} else {
+			v, _, _ := strings.Cut(value, ",")
+			params[key] = v
+			value = value[len(v):]
+		}
+		rest = strings.TrimLeft(value, ", ")
+	}
+	return scheme, params
+}
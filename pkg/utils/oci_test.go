@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func sha256Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeRegistry serves single layer artifact under fw/e810 repository for any manifest reference
+type fakeRegistry struct {
+	server   *httptest.Server
+	manifest []byte
+	layer    []byte
+	// token required in Authorization header, no authentication if empty
+	token    string
+	username string
+	password string
+	// renewedToken replaces token once the manifest is served, as if the token expired before the blob request
+	renewedToken string
+	// interruptBlob drops connection after the first half of the layer on the first blob request
+	interruptBlob bool
+	blobRanges    []string
+}
+
+func newFakeRegistry(layer []byte, title string) *fakeRegistry {
+	r := &fakeRegistry{layer: layer}
+	descriptor := map[string]interface{}{
+		"mediaType": "application/vnd.oci.image.layer.v1.tar",
+		"digest":    sha256Digest(layer),
+		"size":      len(layer),
+	}
+	if title != "" {
+		descriptor["annotations"] = map[string]string{ociTitleAnnotation: title}
+	}
+	r.manifest, _ = json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestMediaType,
+		"layers":        []interface{}{descriptor},
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		if !ok || username != r.username || password != r.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.URL.Query().Get("scope") != "repository:fw/e810:pull" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"token": %q}`, r.token)
+	})
+	mux.HandleFunc("/v2/fw/e810/", func(w http.ResponseWriter, req *http.Request) {
+		if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:fw/e810:pull"`, r.server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/v2/fw/e810/manifests/"):
+			w.Header().Set("Content-Type", ociManifestMediaType)
+			_, _ = w.Write(r.manifest)
+			if r.renewedToken != "" {
+				r.token = r.renewedToken
+			}
+		case req.URL.Path == "/v2/fw/e810/blobs/"+sha256Digest(layer):
+			r.blobRanges = append(r.blobRanges, req.Header.Get("Range"))
+			if r.interruptBlob && len(r.blobRanges) == 1 {
+				w.Header().Set("Content-Length", fmt.Sprint(len(r.layer)))
+				_, _ = w.Write(r.layer[:len(r.layer)/2])
+				return
+			}
+			http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(r.layer))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	r.server = httptest.NewServer(mux)
+	return r
+}
+
+func (r *fakeRegistry) host() string {
+	return strings.TrimPrefix(r.server.URL, "http://")
+}
+
+var _ = Describe("ParseOCIReference", func() {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	var _ = It("will parse reference with tag and digest", func() {
+		r, err := ParseOCIReference("oci://registry.local:5000/fw/e810:v1@" + digest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*r).To(Equal(OCIReference{Registry: "registry.local:5000", Repository: "fw/e810", Tag: "v1", Digest: digest}))
+		Expect(r.Reference()).To(Equal(digest))
+		Expect(r.baseURL(nil)).To(Equal("https://registry.local:5000"))
+		Expect(r.baseURL([]string{"registry.local"})).To(Equal("https://registry.local:5000"))
+		Expect(r.baseURL([]string{"registry.local:5000"})).To(Equal("http://registry.local:5000"))
+	})
+
+	var _ = It("will use latest tag if neither tag nor digest is set", func() {
+		r, err := ParseOCIReference("oci://localhost:5000/ddp")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.Reference()).To(Equal("latest"))
+		Expect(r.baseURL(nil)).To(Equal("http://localhost:5000"))
+	})
+
+	var _ = It("will return error for invalid references", func() {
+		for _, ref := range []string{
+			"https://registry/fw:v1",
+			"oci://registry",
+			"oci://registry/fw@sha256:abc",
+			"oci://registry/fw:v1@md5:" + strings.Repeat("a", 32),
+			"oci://registry/FW:v1",
+			"oci://registry/fw:-v1",
+		} {
+			_, err := ParseOCIReference(ref)
+			Expect(err).To(HaveOccurred(), ref)
+		}
+	})
+})
+
+var _ = Describe("RegistryCredentialsFromDockerConfig", func() {
+	var _ = It("will return credentials from auth field", func() {
+		auth := base64.StdEncoding.EncodeToString([]byte("user:pass:word"))
+		config := fmt.Sprintf(`{"auths": {"https://registry.local:5000/v1/": {"auth": %q}}}`, auth)
+
+		creds, err := RegistryCredentialsFromDockerConfig([]byte(config), "registry.local:5000")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*creds).To(Equal(RegistryCredentials{Username: "user", Password: "pass:word"}))
+	})
+
+	var _ = It("will return credentials from username and password fields", func() {
+		config := `{"auths": {"registry.local": {"username": "user", "password": "pass"}}}`
+
+		creds, err := RegistryCredentialsFromDockerConfig([]byte(config), "registry.local")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*creds).To(Equal(RegistryCredentials{Username: "user", Password: "pass"}))
+	})
+
+	var _ = It("will return error if registry is not present", func() {
+		config := `{"auths": {"registry.local": {"username": "user", "password": "pass"}}}`
+
+		_, err := RegistryCredentialsFromDockerConfig([]byte(config), "other.registry")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("PullOCIArtifact", func() {
+	var (
+		tmpDir   string
+		registry *fakeRegistry
+		content  = []byte("E810 firmware package")
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "oci")
+		Expect(err).ToNot(HaveOccurred())
+		registry = newFakeRegistry(content, "E810_NVMUpdatePackage.tar.gz")
+	})
+
+	AfterEach(func() {
+		registry.server.Close()
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	var _ = It("will pull artifact by tag", func() {
+		path, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", "", nil, http.DefaultClient,
+			OCIPullOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(tmpDir, "E810_NVMUpdatePackage.tar.gz")))
+
+		pulled, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pulled).To(Equal(content))
+	})
+
+	var _ = It("will pull artifact by digest and verify checksum", func() {
+		ref := "oci://" + registry.host() + "/fw/e810:v1@" + sha256Digest(registry.manifest)
+		_, err := PullOCIArtifact(tmpDir, ref, sha256Digest(content), nil, http.DefaultClient, OCIPullOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	var _ = It("will return error if manifest digest does not match", func() {
+		ref := "oci://" + registry.host() + "/fw/e810:v1@" + sha256Digest([]byte("other manifest"))
+		_, err := PullOCIArtifact(tmpDir, ref, "", nil, http.DefaultClient, OCIPullOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Digest mismatch in pulled manifest"))
+	})
+
+	var _ = It("will return error if layer digest does not match", func() {
+		registry.layer = []byte("E810 firmware packagX")
+		_, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", "", nil, http.DefaultClient,
+			OCIPullOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Digest mismatch in pulled layer"))
+	})
+
+	var _ = It("will return error if checksum does not match", func() {
+		_, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", sha256Digest([]byte("other")), nil,
+			http.DefaultClient, OCIPullOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Checksum mismatch"))
+	})
+
+	var _ = It("will authenticate with bearer token using credentials", func() {
+		registry.token, registry.username, registry.password = "secrettoken", "user", "pass"
+
+		_, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", "",
+			&RegistryCredentials{Username: "user", Password: "pass"}, http.DefaultClient, OCIPullOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	var _ = It("will authenticate again if blob request is challenged", func() {
+		registry.token, registry.username, registry.password = "secrettoken", "user", "pass"
+		registry.renewedToken = "renewedtoken"
+
+		path, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", sha256Digest(content),
+			&RegistryCredentials{Username: "user", Password: "pass"}, http.DefaultClient, OCIPullOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		pulled, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pulled).To(Equal(content))
+	})
+
+	var _ = It("will resume interrupted layer download with the same authorization", func() {
+		registry.token, registry.username, registry.password = "secrettoken", "user", "pass"
+		registry.interruptBlob = true
+
+		var progress [][2]int64
+		opts := OCIPullOptions{Download: DownloadOptions{Retries: 1, InitialBackoff: time.Millisecond,
+			Progress: func(downloaded, total int64) { progress = append(progress, [2]int64{downloaded, total}) }}}
+		path, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", sha256Digest(content),
+			&RegistryCredentials{Username: "user", Password: "pass"}, http.DefaultClient, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(registry.blobRanges).To(Equal([]string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}))
+		Expect(progress[len(progress)-1]).To(Equal([2]int64{int64(len(content)), int64(len(content))}))
+
+		pulled, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pulled).To(Equal(content))
+	})
+
+	var _ = It("will return error if layer download is interrupted and retries are disabled", func() {
+		registry.interruptBlob = true
+
+		_, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", "", nil, http.DefaultClient,
+			OCIPullOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unable to pull layer"))
+		Expect(registry.blobRanges).To(HaveLen(1))
+	})
+
+	var _ = It("will return error if credentials are invalid", func() {
+		registry.token, registry.username, registry.password = "secrettoken", "user", "pass"
+
+		_, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", "",
+			&RegistryCredentials{Username: "user", Password: "wrong"}, http.DefaultClient, OCIPullOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to get registry token"))
+	})
+
+	var _ = It("will use repository name if layer has no title", func() {
+		registry.server.Close()
+		registry = newFakeRegistry(content, "")
+
+		path, err := PullOCIArtifact(tmpDir, "oci://"+registry.host()+"/fw/e810:v1", "", nil, http.DefaultClient,
+			OCIPullOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(tmpDir, "e810")))
+	})
+})
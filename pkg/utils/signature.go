@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+const (
+	signatureFilesizeLimitInBytes = 65536 // 64 kB
+
+	pgpPublicKeyHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+)
+
+// VerifyDetachedSignature verifies detached signature of the file using provided public key.
+// Supported are OpenPGP keys (armored or binary) with corresponding GPG signatures and
+// PEM encoded ECDSA/RSA public keys with cosign-style signatures of file's SHA-256 digest (base64 or raw).
+func VerifyDetachedSignature(path, signaturePath string, publicKey []byte) error {
+	signature, err := readSignature(signaturePath)
+	if err != nil {
+		return err
+	}
+
+	f, err := OpenNoLinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file to verify signature: %v", err)
+	}
+	defer f.Close()
+
+	if isOpenPGPKey(publicKey) {
+		return verifyOpenPGPSignature(f, signature, publicKey)
+	}
+	return verifyPKIXSignature(f, signature, publicKey)
+}
+
+func readSignature(signaturePath string) ([]byte, error) {
+	f, err := OpenNoLinks(signaturePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open signature file: %v", err)
+	}
+	defer f.Close()
+
+	signature, err := io.ReadAll(io.LimitReader(f, signatureFilesizeLimitInBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature file: %v", err)
+	}
+	if len(signature) > signatureFilesizeLimitInBytes {
+		return nil, fmt.Errorf("signature file exceeds limit %d bytes", signatureFilesizeLimitInBytes)
+	}
+	return signature, nil
+}
+
+func isOpenPGPKey(publicKey []byte) bool {
+	if bytes.HasPrefix(bytes.TrimSpace(publicKey), []byte(pgpPublicKeyHeader)) {
+		return true
+	}
+	_, err := openpgp.ReadKeyRing(bytes.NewReader(publicKey))
+	return err == nil
+}
+
+func verifyOpenPGPSignature(content io.Reader, signature, publicKey []byte) error {
+	var keyRing openpgp.EntityList
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(publicKey), []byte(pgpPublicKeyHeader)) {
+		keyRing, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	} else {
+		keyRing, err = openpgp.ReadKeyRing(bytes.NewReader(publicKey))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read OpenPGP public key: %v", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(pgpSignatureHeader)) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyRing, content, bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyRing, content, bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("signature verification failed: %v", err)
+	}
+	return nil
+}
+
+func verifyPKIXSignature(content io.Reader, signature, publicKey []byte) error {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return errors.New("failed to decode PEM public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %v", err)
+	}
+
+	// cosign stores signatures base64 encoded, fall back to raw signature otherwise
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature))); err == nil {
+		signature = decoded
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return fmt.Errorf("failed to calculate sha256 of file: %v", err)
+	}
+	digest := h.Sum(nil)
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, signature) {
+			return errors.New("signature verification failed: invalid ECDSA signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature); err != nil {
+			return fmt.Errorf("signature verification failed: %v", err)
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return nil
+}
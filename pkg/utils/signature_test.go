@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerifyDetachedSignature", func() {
+	var (
+		tmpDir        string
+		filePath      string
+		signaturePath string
+		content       = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "signature")
+		Expect(err).ToNot(HaveOccurred())
+		filePath = filepath.Join(tmpDir, "package.zip")
+		signaturePath = filePath + ".sig"
+		Expect(os.WriteFile(filePath, content, 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	pemPublicKey := func(pub interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		Expect(err).ToNot(HaveOccurred())
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+
+	var _ = It("will verify base64 encoded ECDSA signature", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		digest := sha256.Sum256(content)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(signaturePath, []byte(base64.StdEncoding.EncodeToString(signature)), 0600)).To(Succeed())
+
+		Expect(VerifyDetachedSignature(filePath, signaturePath, pemPublicKey(&key.PublicKey))).To(Succeed())
+	})
+
+	var _ = It("will verify raw RSA signature", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		digest := sha256.Sum256(content)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(signaturePath, signature, 0600)).To(Succeed())
+
+		Expect(VerifyDetachedSignature(filePath, signaturePath, pemPublicKey(&key.PublicKey))).To(Succeed())
+	})
+
+	var _ = It("will return error if file was modified after signing", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		digest := sha256.Sum256(content)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(signaturePath, []byte(base64.StdEncoding.EncodeToString(signature)), 0600)).To(Succeed())
+		Expect(os.WriteFile(filePath, append(content, '!'), 0600)).To(Succeed())
+
+		err = VerifyDetachedSignature(filePath, signaturePath, pemPublicKey(&key.PublicKey))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("signature verification failed"))
+	})
+
+	var _ = It("will verify armored OpenPGP signature", func() {
+		entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		var signature bytes.Buffer
+		Expect(openpgp.ArmoredDetachSign(&signature, entity, bytes.NewReader(content), nil)).To(Succeed())
+		Expect(os.WriteFile(signaturePath, signature.Bytes(), 0600)).To(Succeed())
+
+		var publicKey bytes.Buffer
+		Expect(entity.Serialize(&publicKey)).To(Succeed())
+
+		Expect(VerifyDetachedSignature(filePath, signaturePath, publicKey.Bytes())).To(Succeed())
+	})
+
+	var _ = It("will return error if OpenPGP signature was made with different key", func() {
+		signer, err := openpgp.NewEntity("signer", "", "signer@example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+		other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		var signature bytes.Buffer
+		Expect(openpgp.DetachSign(&signature, signer, bytes.NewReader(content), nil)).To(Succeed())
+		Expect(os.WriteFile(signaturePath, signature.Bytes(), 0600)).To(Succeed())
+
+		var publicKey bytes.Buffer
+		Expect(other.Serialize(&publicKey)).To(Succeed())
+
+		err = VerifyDetachedSignature(filePath, signaturePath, publicKey.Bytes())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("signature verification failed"))
+	})
+
+	var _ = It("will return error if public key is invalid", func() {
+		Expect(os.WriteFile(signaturePath, []byte("c2lnbmF0dXJl"), 0600)).To(Succeed())
+
+		err := VerifyDetachedSignature(filePath, signaturePath, []byte("not a key"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to decode PEM public key"))
+	})
+
+	var _ = It("will return error if signature file does not exist", func() {
+		err := VerifyDetachedSignature(filePath, signaturePath, []byte("not a key"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to open signature file"))
+	})
+})
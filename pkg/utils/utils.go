@@ -9,19 +9,26 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -143,26 +150,65 @@ func (l *LogWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-func verifyChecksum(path, expected string) (bool, error) {
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// parseChecksum splits checksum in <algorithm>:<hex> format. Checksum without algorithm is treated as SHA-1
+func parseChecksum(checksum string) (string, string, error) {
+	algorithm, value, found := strings.Cut(checksum, ":")
+	if !found {
+		return "sha1", checksum, nil
+	}
+
+	algorithm = strings.ToLower(algorithm)
+	if _, ok := checksumAlgorithms[algorithm]; !ok {
+		return "", "", fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+	return algorithm, value, nil
+}
+
+// VerifyChecksum returns true if checksum of the file matches expected one in [<algorithm>:]<hex> format
+func VerifyChecksum(path, expected string) (bool, error) {
 	if expected == "" {
 		return false, nil
 	}
+	algorithm, value, err := parseChecksum(expected)
+	if err != nil {
+		return false, err
+	}
 	f, err := OpenNoLinks(path)
 	if err != nil {
-		return false, errors.New("failed to open file to calculate sha-1")
+		return false, fmt.Errorf("failed to open file to calculate %s", algorithm)
 	}
 	defer f.Close()
-	h := sha1.New()
+	h := checksumAlgorithms[algorithm]()
 	if _, err := io.Copy(h, f); err != nil {
-		return false, errors.New("failed to copy file to calculate sha-1")
+		return false, fmt.Errorf("failed to copy file to calculate %s", algorithm)
 	}
-	if hex.EncodeToString(h.Sum(nil)) != strings.ToLower(expected) {
+	if hex.EncodeToString(h.Sum(nil)) != strings.ToLower(value) {
 		return false, nil
 	}
 
 	return true, nil
 }
 
+// FileChecksum returns SHA-256 checksum of the file in sha256:<hex> format, as accepted by VerifyChecksum
+func FileChecksum(path string) (string, error) {
+	f, err := OpenNoLinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file to calculate sha256")
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to copy file to calculate sha256")
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func NewSecureHttpsClient(cert *x509.Certificate) (*http.Client, error) {
 	certPool, err := x509.SystemCertPool()
 	if err != nil {
@@ -181,31 +227,73 @@ func NewSecureHttpsClient(cert *x509.Certificate) (*http.Client, error) {
 	return &httpsClient, nil
 }
 
+// DownloadOptions configures retries, timeout and progress reporting of DownloadFileWithOptions.
+// Zero value means single attempt without timeout
+type DownloadOptions struct {
+	// Retries is the number of additional attempts after failed one. Interrupted download is resumed
+	// with HTTP Range request if server supports it
+	Retries int
+	// InitialBackoff is the delay before first retry, doubled after each following failure up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout limits total time of the download including retries
+	Timeout time.Duration
+	// Progress is called with number of downloaded bytes and total size (-1 if unknown),
+	// at most once per ProgressInterval and when download completes
+	Progress         DownloadProgressFunc
+	ProgressInterval time.Duration
+}
+
+type DownloadProgressFunc func(downloaded, total int64)
+
 func DownloadFile(path, url, checksum string, client *http.Client) error {
+	return DownloadFileWithOptions(path, url, checksum, client, DownloadOptions{})
+}
+
+func DownloadFileWithOptions(path, url, checksum string, client *http.Client, opts DownloadOptions) error {
+	return downloadFile(path, url, checksum, client, opts, nil)
+}
+
+// downloadFile downloads url into path with retries and resume configured by opts. header is added to every request
+func downloadFile(path, url, checksum string, client *http.Client, opts DownloadOptions, header http.Header) error {
 	f, err := CreateNoLinks(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	r, err := client.Get(url)
-	if err != nil {
-		return fmt.Errorf("unable to download image from: %s err: %s", url, err)
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
-	defer r.Body.Close()
 
-	if r.StatusCode != http.StatusOK {
-		return fmt.Errorf("unable to download image from: %s err: %s",
-			url, r.Status)
-	}
+	w := &progressWriter{file: f, total: -1, opts: opts}
+	backoff := opts.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := downloadAttempt(ctx, w, url, client, header)
+		if err == nil {
+			break
+		}
+		if !retryable || attempt >= opts.Retries {
+			return err
+		}
 
-	_, err = io.Copy(f, r.Body)
-	if err != nil {
-		return err
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("unable to download image from: %s err: %s", url, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if opts.MaxBackoff > 0 && backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
 	}
+	w.report(true)
 
 	if checksum != "" {
-		match, err := verifyChecksum(path, checksum)
+		match, err := VerifyChecksum(path, checksum)
 		if err != nil {
 			return err
 		}
@@ -216,6 +304,137 @@ func DownloadFile(path, url, checksum string, client *http.Client) error {
 	return nil
 }
 
+// downloadAttempt downloads the file or its remaining part if some bytes were already written.
+// Returned flag indicates if failed attempt can be retried
+func downloadAttempt(ctx context.Context, w *progressWriter, url string, client *http.Client,
+	header http.Header) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to download image from: %s err: %s", url, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if w.written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", w.written))
+	}
+
+	r, err := client.Do(req)
+	if err != nil {
+		return isRetryableDownloadError(ctx, err), fmt.Errorf("unable to download image from: %s err: %s", url, err)
+	}
+	defer r.Body.Close()
+
+	switch {
+	case r.StatusCode == http.StatusPartialContent && w.written > 0:
+		start, total, err := parseContentRange(r.Header.Get("Content-Range"))
+		if err != nil || start != w.written {
+			// unexpected range, start from scratch
+			return true, w.reset(fmt.Errorf("unable to resume download from: %s", url))
+		}
+		w.total = total
+	case r.StatusCode == http.StatusOK:
+		// server does not support ranges or this is the first attempt
+		if err := w.reset(nil); err != nil {
+			return false, err
+		}
+		w.total = r.ContentLength
+	default:
+		retryable := r.StatusCode >= http.StatusInternalServerError || r.StatusCode == http.StatusTooManyRequests ||
+			r.StatusCode == http.StatusRequestTimeout
+		if r.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			retryable = true
+			if err := w.reset(nil); err != nil {
+				return false, err
+			}
+		}
+		return retryable, &downloadStatusError{url: url, status: r.Status, code: r.StatusCode, header: r.Header}
+	}
+
+	if _, err := io.Copy(w, r.Body); err != nil {
+		return isRetryableDownloadError(ctx, err), fmt.Errorf("unable to download image from: %s err: %s", url, err)
+	}
+	return false, nil
+}
+
+// downloadStatusError is returned if the server responds to the download with unexpected status
+type downloadStatusError struct {
+	url    string
+	status string
+	code   int
+	header http.Header
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("unable to download image from: %s err: %s", e.url, e.status)
+}
+
+func isRetryableDownloadError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var opErr *net.OpError
+	var urlErr *neturl.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &opErr) ||
+		(errors.As(err, &urlErr) && urlErr.Timeout())
+}
+
+// parseContentRange parses "bytes <start>-<end>/<total>" header, total is -1 if unknown
+func parseContentRange(header string) (int64, int64, error) {
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q: %v", header, err)
+	}
+	if total == "*" {
+		return start, -1, nil
+	}
+	t, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q: %v", header, err)
+	}
+	return start, t, nil
+}
+
+// progressWriter writes downloaded content to file and reports progress
+type progressWriter struct {
+	file       *os.File
+	written    int64
+	total      int64
+	opts       DownloadOptions
+	lastReport time.Time
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.written += int64(n)
+	w.report(false)
+	return n, err
+}
+
+// reset truncates the file, so download starts from scratch. Passed error is returned if truncate succeeds
+func (w *progressWriter) reset(err error) error {
+	if truncErr := w.file.Truncate(0); truncErr != nil {
+		return truncErr
+	}
+	if _, seekErr := w.file.Seek(0, io.SeekStart); seekErr != nil {
+		return seekErr
+	}
+	w.written = 0
+	return err
+}
+
+func (w *progressWriter) report(force bool) {
+	if w.opts.Progress == nil {
+		return
+	}
+	if !force && time.Since(w.lastReport) < w.opts.ProgressInterval {
+		return
+	}
+	w.lastReport = time.Now()
+	w.opts.Progress(w.written, w.total)
+}
+
 func CreateFolder(path string, log logr.Logger) error {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -458,9 +677,17 @@ func CopyFile(src string, dst string) error {
 	return os.WriteFile(dst, data, 0644)
 }
 
+// RunExecWithLog runs the command, logging its output. Output is also written to Stdout and Stderr
+// already set in the command
 func RunExecWithLog(cmd *exec.Cmd, log logr.Logger) error {
-	cmd.Stdout = &LogWriter{Log: log, Stream: "stdout"}
-	cmd.Stderr = &LogWriter{Log: log, Stream: "stderr"}
+	var stdout, stderr io.Writer = &LogWriter{Log: log, Stream: "stdout"}, &LogWriter{Log: log, Stream: "stderr"}
+	if cmd.Stdout != nil {
+		stdout = io.MultiWriter(stdout, cmd.Stdout)
+	}
+	if cmd.Stderr != nil {
+		stderr = io.MultiWriter(stderr, cmd.Stderr)
+	}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
 	return cmd.Run()
 }
 
@@ -517,3 +744,21 @@ func SetOsEnvIfNotSet(key, value string, logger logr.Logger) error {
 	logger.Info("setting ENV var", "key", key, "value", value)
 	return os.Setenv(key, value)
 }
+
+func GetOsVarOrUseDefault(log logr.Logger, varName string, defVal int64) int64 {
+	retValStr := os.Getenv(varName)
+
+	if retValStr == "" {
+		log.Info("env variable not found - using default value", "variable", varName, "default", defVal)
+		return defVal
+	}
+
+	val, err := strconv.ParseInt(retValStr, 10, 64)
+
+	if err != nil {
+		log.Error(err, "failed to parse env variable to int64 - using default value", "variable", varName, "value", retValStr, "default", defVal)
+		return defVal
+	}
+
+	return val
+}
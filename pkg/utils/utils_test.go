@@ -6,10 +6,13 @@ package utils
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
 	"compress/gzip"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
@@ -74,15 +77,15 @@ var _ = Describe("Utils", func() {
 		})
 	})
 
-	var _ = Describe("verifyChecksum", func() {
+	var _ = Describe("VerifyChecksum", func() {
 		var _ = It("will return false and error if it's not able to open file", func() {
-			result, err := verifyChecksum("./invalidfile", "somechecksum")
+			result, err := VerifyChecksum("./invalidfile", "somechecksum")
 			Expect(err).To(HaveOccurred())
 			Expect(result).To(Equal(false))
 		})
 
 		var _ = It("will return false and no error if the expected is empty", func() {
-			result, err := verifyChecksum("./invalidfile", "")
+			result, err := VerifyChecksum("./invalidfile", "")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(result).To(Equal(false))
 		})
@@ -98,7 +101,7 @@ var _ = Describe("Utils", func() {
 			err = tmpfile.Close()
 			Expect(err).ToNot(HaveOccurred())
 
-			result, err := verifyChecksum(tmpfile.Name(), "somechecksum")
+			result, err := VerifyChecksum(tmpfile.Name(), "somechecksum")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(result).To(Equal(false))
 		})
@@ -120,10 +123,51 @@ var _ = Describe("Utils", func() {
 			_, err = io.Copy(h, f)
 			Expect(err).ToNot(HaveOccurred())
 
-			result, err := verifyChecksum(tmpfile.Name(), hex.EncodeToString(h.Sum(nil)))
+			result, err := VerifyChecksum(tmpfile.Name(), hex.EncodeToString(h.Sum(nil)))
 			Expect(err).ToNot(HaveOccurred())
 			Expect(result).To(Equal(true))
 		})
+		var _ = It("will verify checksums prefixed with algorithm", func() {
+			tmpfile, err := os.CreateTemp(".", "testfile")
+			Expect(err).ToNot(HaveOccurred())
+
+			defer os.Remove(tmpfile.Name())
+
+			content := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+			_, err = tmpfile.Write(content)
+			Expect(err).ToNot(HaveOccurred())
+			err = tmpfile.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			sha1Sum := sha1.Sum(content)
+			sha256Sum := sha256.Sum256(content)
+			sha512Sum := sha512.Sum512(content)
+
+			for _, checksum := range []string{
+				"sha1:" + hex.EncodeToString(sha1Sum[:]),
+				"sha256:" + hex.EncodeToString(sha256Sum[:]),
+				"SHA512:" + strings.ToUpper(hex.EncodeToString(sha512Sum[:])),
+			} {
+				result, err := VerifyChecksum(tmpfile.Name(), checksum)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(true), checksum)
+			}
+
+			checksum, err := FileChecksum(tmpfile.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checksum).To(Equal("sha256:" + hex.EncodeToString(sha256Sum[:])))
+
+			result, err := VerifyChecksum(tmpfile.Name(), "sha256:"+hex.EncodeToString(sha1Sum[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(false))
+		})
+
+		var _ = It("will return error if checksum algorithm is not supported", func() {
+			result, err := VerifyChecksum("./invalidfile", "md5:d41d8cd98f00b204e9800998ecf8427e")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported checksum algorithm"))
+			Expect(result).To(Equal(false))
+		})
 	})
 
 	var _ = Describe("CreateFolder", func() {
@@ -302,6 +346,110 @@ var _ = Describe("Utils", func() {
 		})
 	})
 
+	var _ = Describe("DownloadFileWithOptions", func() {
+		content := []byte(strings.Repeat("0123456789", 1000))
+		opts := DownloadOptions{Retries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+		var filePath string
+
+		BeforeEach(func() {
+			tmpDir, err := os.MkdirTemp("", "download")
+			Expect(err).ToNot(HaveOccurred())
+			filePath = filepath.Join(tmpDir, "package.tar.gz")
+			DeferCleanup(os.RemoveAll, tmpDir)
+		})
+
+		var _ = It("will resume interrupted download with range request", func() {
+			var ranges []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				if r.Header.Get("Range") == "" {
+					// announce full length, but drop connection in the middle
+					w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+					_, _ = w.Write(content[:4000])
+					return
+				}
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+			}))
+			defer srv.Close()
+
+			var progress [][2]int64
+			opts := opts
+			opts.Progress = func(downloaded, total int64) { progress = append(progress, [2]int64{downloaded, total}) }
+
+			sum := sha256.Sum256(content)
+			err := DownloadFileWithOptions(filePath, srv.URL, "sha256:"+hex.EncodeToString(sum[:]), http.DefaultClient, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ranges).To(Equal([]string{"", "bytes=4000-"}))
+
+			downloaded, err := os.ReadFile(filePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(downloaded).To(Equal(content))
+			Expect(progress[len(progress)-1]).To(Equal([2]int64{int64(len(content)), int64(len(content))}))
+		})
+
+		var _ = It("will restart download if server does not support ranges", func() {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+				if requests == 1 {
+					_, _ = w.Write(content[:4000])
+					return
+				}
+				_, _ = w.Write(content)
+			}))
+			defer srv.Close()
+
+			Expect(DownloadFileWithOptions(filePath, srv.URL, "", http.DefaultClient, opts)).To(Succeed())
+			downloaded, err := os.ReadFile(filePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(downloaded).To(Equal(content))
+		})
+
+		var _ = It("will return error once retry budget is exhausted", func() {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			err := DownloadFileWithOptions(filePath, srv.URL, "", http.DefaultClient, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("503 Service Unavailable"))
+			Expect(requests).To(Equal(opts.Retries + 1))
+		})
+
+		var _ = It("will not retry if file is not found", func() {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer srv.Close()
+
+			err := DownloadFileWithOptions(filePath, srv.URL, "", http.DefaultClient, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(Equal(1))
+		})
+
+		var _ = It("will return error if download exceeds timeout", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			}))
+			defer srv.Close()
+
+			opts := opts
+			opts.Timeout = 100 * time.Millisecond
+			err := DownloadFileWithOptions(filePath, srv.URL, "", http.DefaultClient, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("context deadline exceeded"))
+		})
+	})
+
 	var _ = Describe("Untar", func() {
 		log := logr.Discard()
 